@@ -1,17 +1,135 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"goparselogs/internal/cli"
+	"goparselogs/internal/fileops"
 	"goparselogs/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+const usage = `Usage: goparselogs [flags] [path ...]
+       goparselogs <command> [flags] [args ...]
+
+Each path is a logs directory, server directory or log file to list in the
+file menu. Without any, the logs directory in the current directory is used.
+
+Commands, for use without the TUI (a file of - reads standard input):
+%s
+Flags:
+`
+
+// errUsage is returned for command lines the flag package has already reported
+var errUsage = errors.New("invalid arguments")
+
+// parseArgs builds the TUI options from the command line. Flags and paths may be mixed.
+func parseArgs(args []string) (ui.Options, error) {
+	var opts ui.Options
+	flags := flag.NewFlagSet("goparselogs", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), usage, cli.Usage())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&opts.Open, "file", "", "open this log file straight away")
+	flags.BoolVar(&opts.CoreProtect, "coreprotect", false, "start with CoreProtect parsing enabled")
+	flags.StringVar(&opts.OutputDir, "output-dir", fileops.DefaultOutputDir, "directory exports are saved in")
+	cacheDir := flags.String("cache-dir", "", "directory log indexes are cached in (default: the user cache directory)")
+	noCache := flags.Bool("no-cache", false, "don't cache log indexes")
+	tz := flags.String("tz", "", "time zone log entries are dated in, e.g. Europe/London or UTC (default: the local time zone)")
+
+	var paths []string
+	for {
+		if err := flags.Parse(args); err == flag.ErrHelp {
+			return opts, err
+		} else if err != nil {
+			return opts, errUsage
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		paths = append(paths, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if *tz != "" {
+		loc, err := cli.LoadLocation(*tz)
+		if err != nil {
+			return opts, err
+		}
+		opts.Location = loc
+	}
+
+	for _, path := range paths {
+		info, err := fileops.StatLogFile(path)
+		if err != nil {
+			return opts, err
+		}
+		if info.IsDir() {
+			opts.Sources.Dirs = append(opts.Sources.Dirs, path)
+		} else {
+			opts.Sources.Files = append(opts.Sources.Files, path)
+		}
+	}
+	if opts.Open != "" {
+		if _, err := fileops.StatLogFile(opts.Open); err != nil {
+			return opts, err
+		}
+		opts.Sources.Files = append(opts.Sources.Files, opts.Open)
+		opts.Open = filepath.ToSlash(opts.Open)
+	}
+	if !*noCache {
+		opts.IndexCache = openIndexCache(*cacheDir)
+	}
+	return opts, nil
+}
+
+// openIndexCache opens the cache of log indexes in dir, or the default
+// directory if empty. Logs are still read without a cache if it can't be opened.
+func openIndexCache(dir string) *fileops.IndexCache {
+	if dir == "" {
+		var err error
+		if dir, err = fileops.DefaultIndexCacheDir(); err != nil {
+			return nil
+		}
+	}
+	cache, err := fileops.OpenIndexCache(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goparselogs: %v\n", err)
+		return nil
+	}
+	return cache
+}
+
 func main() {
-	p := tea.NewProgram(ui.InitialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		env := cli.Env{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, OpenCache: openIndexCache}
+		os.Exit(cli.Run(os.Args[1], os.Args[2:], env))
+	}
+
+	opts, err := parseArgs(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err == errUsage {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goparselogs: %v\n", err)
+		os.Exit(2)
+	}
+
+	p := tea.NewProgram(ui.InitialModel(opts), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if tuiModel, ok := finalModel.(ui.TUIModel); ok {
+		tuiModel.Close()
+	}
+	if err != nil {
 		fmt.Printf("Alas, there's been an error running TUI: %v\n", err)
 		os.Exit(1)
 	}
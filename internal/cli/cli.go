@@ -0,0 +1,246 @@
+// Package cli implements the headless subcommands used from scripts and shell pipelines.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/query"
+)
+
+// Exit codes, following grep
+const (
+	ExitMatch   = 0 // At least one entry matched
+	ExitNoMatch = 1 // Nothing matched
+	ExitError   = 2 // The command failed
+)
+
+// Env holds the streams and settings a command runs with.
+type Env struct {
+	Stdin    io.Reader
+	Stdout   io.Writer
+	Stderr   io.Writer
+	Location *time.Location // Time zone used to date log entries
+
+	// OpenCache opens the cache of log indexes in a directory, or the default
+	// one if empty, returning nil if it can't. Logs aren't cached without it.
+	OpenCache func(dir string) *fileops.IndexCache
+	Cache     *fileops.IndexCache // Cache of log indexes, if any, once opened by openCache
+
+	// Where log indexes are cached, and whether they are at all. They are
+	// set by each command's -cache-dir and -no-cache flags.
+	CacheDir string
+	NoCache  bool
+
+	// Number of the newest actions read from a CoreProtect database, 0 for
+	// all of them. It is set by each command's -limit flag.
+	DatabaseLimit int
+}
+
+// openCache opens the cache of log indexes chosen by the flags, if any
+func (env *Env) openCache() {
+	if env.NoCache || env.OpenCache == nil {
+		env.Cache = nil
+		return
+	}
+	env.Cache = env.OpenCache(env.CacheDir)
+}
+
+// command is a subcommand, returning whether it found anything
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string, env Env) (bool, error)
+}
+
+// Usage lines of the subcommands
+const (
+	grepUsage        = "grep [flags] <query> <file|-> ..."
+	exportUsage      = "export [flags] <file|-> ..."
+	coreProtectUsage = "coreprotect [flags] <file|->"
+)
+
+var commands = map[string]command{
+	"grep": {
+		usage:   grepUsage,
+		summary: "print the entries matching a filter query",
+		run:     runGrep,
+	},
+	"export": {
+		usage:   exportUsage,
+		summary: "write the entries of logs in another format",
+		run:     runExport,
+	},
+	"coreprotect": {
+		usage:   coreProtectUsage,
+		summary: "print the CoreProtect lookup results in a client log or CoreProtect database",
+		run:     runCoreProtect,
+	},
+}
+
+// errUsage is returned for command lines the flag package has already reported
+var errUsage = errors.New("invalid arguments")
+
+// IsCommand reports whether name is a subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// Usage describes the subcommands, for the program's own usage message.
+func Usage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var usage strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&usage, "  goparselogs %s\n    \t%s\n", commands[name].usage, commands[name].summary)
+	}
+	return usage.String()
+}
+
+// Run runs the named subcommand and returns the exit code for the program.
+func Run(name string, args []string, env Env) int {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "goparselogs: unknown command %q\n", name)
+		return ExitError
+	}
+	if env.Location == nil {
+		env.Location = time.Local
+	}
+
+	found, err := cmd.run(args, env)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitMatch
+	}
+	if err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintf(env.Stderr, "goparselogs %s: %v\n", name, err)
+		}
+		return ExitError
+	}
+	if !found {
+		return ExitNoMatch
+	}
+	return ExitMatch
+}
+
+// newFlagSet creates the flag set of a subcommand, reporting errors to stderr.
+// Every subcommand takes -tz, -limit, -cache-dir and -no-cache flags setting
+// the time zone, database limit and index cache in env.
+func newFlagSet(name, usage string, env *Env) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.Stderr)
+	flags.Var(&locationFlag{location: &env.Location}, "tz", "time zone log entries are dated in, e.g. Europe/London or UTC")
+	flags.StringVar(&env.CacheDir, "cache-dir", "", "directory log indexes are cached in (default: the user cache directory)")
+	flags.BoolVar(&env.NoCache, "no-cache", false, "don't cache log indexes")
+	flags.IntVar(&env.DatabaseLimit, "limit", coreprotectparser.DefaultDatabaseLimit, "read only the newest actions matching the filters from a CoreProtect database, 0 for all")
+	flags.Usage = func() {
+		fmt.Fprintf(env.Stderr, "Usage: goparselogs %s\n\nFlags:\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses the flags of a subcommand, which may be mixed with its
+// other arguments, and returns the other arguments
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		} else if err != nil {
+			return nil, errUsage
+		}
+		if flags.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// queryList collects the queries of a repeatable flag
+type queryList []*query.Query
+
+func (q *queryList) String() string {
+	return strings.Join(query.Sources(*q), ", ")
+}
+
+func (q *queryList) Set(value string) error {
+	parsed, err := query.Parse(value)
+	if err != nil {
+		return err
+	}
+	*q = append(*q, parsed)
+	return nil
+}
+
+// locationFlag is a flag holding a time zone name, as accepted by time.LoadLocation
+type locationFlag struct {
+	location **time.Location
+}
+
+func (l *locationFlag) String() string {
+	if l.location == nil || *l.location == nil {
+		return ""
+	}
+	return (*l.location).String()
+}
+
+func (l *locationFlag) Set(value string) error {
+	loc, err := LoadLocation(value)
+	if err != nil {
+		return err
+	}
+	*l.location = loc
+	return nil
+}
+
+// LoadLocation returns the time zone with the given name, such as
+// Europe/London, UTC or Local.
+func LoadLocation(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// formatFlag is a flag holding an export format
+type formatFlag struct {
+	format fileops.ExportFormat
+}
+
+func (f *formatFlag) String() string {
+	return string(f.format)
+}
+
+func (f *formatFlag) Set(value string) error {
+	format, err := fileops.ParseExportFormat(value)
+	if err != nil {
+		return err
+	}
+	f.format = format
+	return nil
+}
+
+// exportFormatNames lists the export formats for flag help
+func exportFormatNames() string {
+	names := make([]string, len(fileops.ExportFormats))
+	for i, format := range fileops.ExportFormats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/internal/fileops"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const standardLog = `[12:00:00] [Server thread/INFO]: Steve joined the game
+[12:00:01] [Server thread/WARN]: Can't keep up!
+	at Example.run(Example.java:1)
+[12:00:02] [Server thread/INFO]: Alex joined the game
+`
+
+const coreProtectLog = `[14:37:37] [Render thread/INFO]: [System] [CHAT] 9.00/h ago §f- §3Th4tGuy1 §fbroke §3stone§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 8.00/h ago §f- §3Alex §fplaced §3dirt§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 7.00/h ago §f- §3Th4tGuy1 §fbroke §3dirt§f.
+`
+
+// The parts of CoreProtect's schema read by coreprotectparser.Database
+const testDatabaseSchema = `
+CREATE TABLE co_user (id INTEGER PRIMARY KEY, time INTEGER, user TEXT, uuid TEXT);
+CREATE TABLE co_world (id INTEGER PRIMARY KEY, world TEXT);
+CREATE TABLE co_material_map (id INTEGER PRIMARY KEY, material TEXT);
+CREATE TABLE co_entity_map (id INTEGER PRIMARY KEY, entity TEXT);
+CREATE TABLE co_block (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, type INTEGER, data INTEGER, meta BLOB, blockdata BLOB, action INTEGER, rolled_back INTEGER);
+CREATE TABLE co_container (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, type INTEGER, data INTEGER, amount INTEGER, metadata BLOB, action INTEGER, rolled_back INTEGER);
+CREATE TABLE co_chat (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, message TEXT);
+CREATE TABLE co_command (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, message TEXT);
+CREATE TABLE co_session (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, action INTEGER);
+
+INSERT INTO co_user VALUES (1, 0, 'Th4tGuy1', ''), (2, 0, 'queercookie', '');
+INSERT INTO co_world VALUES (1, 'world');
+INSERT INTO co_material_map VALUES (1, 'minecraft:stone');
+INSERT INTO co_block VALUES (1714590120, 1, 1, 10, 64, -5, 1, 0, NULL, NULL, 0, 0);
+INSERT INTO co_block VALUES (1714590180, 1, 1, 11, 64, -5, 1, 0, NULL, NULL, 1, 0);
+INSERT INTO co_chat VALUES (1714590240, 2, 1, 0, 64, 0, 'can I see?');
+`
+
+// writeTestFiles writes the logs and database the commands are run on, returning the directory they are in
+func writeTestFiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "latest.log"), []byte(standardLog), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.log"), []byte("[13:00:00] [Server thread/INFO]: Done\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "client.log"), []byte(coreProtectLog), 0o644))
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "database.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(testDatabaseSchema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeTestFiles(t)
+	latest := filepath.Join(dir, "latest.log")
+	other := filepath.Join(dir, "other.log")
+	client := filepath.Join(dir, "client.log")
+	database := filepath.Join(dir, "database.db")
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		stdin   string
+		code    int
+		stdout  string // Expected output, if not empty
+		stderr  string // Text the error output must contain, if not empty
+	}{
+		{
+			name: "grep stdin", command: "grep", args: []string{"joined", "-"}, stdin: standardLog,
+			code:   ExitMatch,
+			stdout: "[12:00:00] [Server thread/INFO]: Steve joined the game\n[12:00:02] [Server thread/INFO]: Alex joined the game\n",
+		},
+		{
+			name: "grep prints stack traces", command: "grep", args: []string{"level:WARN", latest},
+			code:   ExitMatch,
+			stdout: "[12:00:01] [Server thread/WARN]: Can't keep up!\n\tat Example.run(Example.java:1)\n",
+		},
+		{
+			name: "grep prefixes several files", command: "grep", args: []string{"Alex OR Done", latest, other},
+			code:   ExitMatch,
+			stdout: latest + ":[12:00:02] [Server thread/INFO]: Alex joined the game\n" + other + ":[13:00:00] [Server thread/INFO]: Done\n",
+		},
+		{name: "grep no match", command: "grep", args: []string{"creeper", "-"}, stdin: standardLog, code: ExitNoMatch, stdout: ""},
+		{name: "grep flags after arguments", command: "grep", args: []string{"joined", "-", "--tz", "UTC"}, stdin: standardLog, code: ExitMatch},
+		{name: "grep missing file argument", command: "grep", args: []string{"joined"}, code: ExitError, stderr: "Usage: goparselogs grep"},
+		{name: "grep invalid query", command: "grep", args: []string{"(joined", "-"}, code: ExitError, stderr: "invalid query"},
+		{name: "grep missing file", command: "grep", args: []string{"joined", filepath.Join(dir, "missing.log")}, code: ExitError, stderr: "missing.log"},
+		{name: "unknown flag", command: "grep", args: []string{"--nope", "joined", "-"}, code: ExitError, stderr: "flag provided but not defined"},
+		{name: "unknown time zone", command: "grep", args: []string{"--tz", "Nowhere/Land", "joined", "-"}, code: ExitError, stderr: `unknown time zone "Nowhere/Land"`},
+		{name: "help", command: "export", args: []string{"-h"}, code: ExitMatch, stderr: "Usage: goparselogs export"},
+		{name: "unknown command", command: "frobnicate", code: ExitError, stderr: `unknown command "frobnicate"`},
+		{
+			name: "export json", command: "export", args: []string{"--format", "json", "--filter", "Alex", "-"}, stdin: standardLog,
+			code:   ExitMatch,
+			stdout: "[\n  {\"line\":4,\"timestamp\":\"12:00:02\",\"thread\":\"Server thread\",\"level\":\"INFO\",\"message\":\"Alex joined the game\"}\n]\n",
+		},
+		{
+			name: "export filters are ORed", command: "export", args: []string{"--format", "ndjson", "--filter", "Steve", "--filter", "Alex", latest},
+			code: ExitMatch,
+		},
+		{name: "export nothing", command: "export", args: []string{"--filter", "creeper", latest}, code: ExitNoMatch, stdout: ""},
+		{name: "export unknown format", command: "export", args: []string{"--format", "xml", latest}, code: ExitError, stderr: "xml"},
+		{name: "export no files", command: "export", code: ExitError, stderr: "Usage: goparselogs export"},
+		{
+			name: "coreprotect stdin", command: "coreprotect", args: []string{"--filter", "action:place", "-"}, stdin: coreProtectLog,
+			code:   ExitMatch,
+			stdout: "[14:37:37] [Render thread/INFO]: [System] [CHAT] 8.00/h ago §f- §3Alex §fplaced §3dirt§f.\n",
+		},
+		{name: "coreprotect no match", command: "coreprotect", args: []string{"--filter", "action:kill", client}, code: ExitNoMatch, stdout: ""},
+		{
+			name: "coreprotect players", command: "coreprotect", args: []string{"--players", "-"}, stdin: coreProtectLog,
+			code:   ExitMatch,
+			stdout: "Th4tGuy1\t2\tbreak=2\tdirt=1,stone=1\nAlex\t1\tplace=1\tdirt=1\n",
+		},
+		{
+			name: "coreprotect database", command: "coreprotect", args: []string{"--format", "ndjson", "--filter", "user:th4t", database},
+			code: ExitMatch,
+		},
+		{
+			name: "coreprotect database limit", command: "coreprotect", args: []string{"--players", "--limit", "1", database},
+			code:   ExitMatch,
+			stdout: "queercookie\t1\tchat=1\tfirst=2024-05-01T19:04:00\tlast=2024-05-01T19:04:00\n",
+		},
+		{name: "coreprotect two files", command: "coreprotect", args: []string{client, client}, code: ExitError, stderr: "Usage: goparselogs coreprotect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			env := Env{
+				Stdin:    strings.NewReader(tt.stdin),
+				Stdout:   &stdout,
+				Stderr:   &stderr,
+				Location: time.UTC,
+			}
+			code := Run(tt.command, tt.args, env)
+			assert.Equal(t, tt.code, code, "stderr: %s", stderr.String())
+			if tt.stdout != "" || tt.code == ExitNoMatch {
+				assert.Equal(t, tt.stdout, stdout.String())
+			}
+			if tt.stderr != "" {
+				assert.Contains(t, stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRun_DatabaseFilterOutput(t *testing.T) {
+	dir := writeTestFiles(t)
+	var stdout, stderr bytes.Buffer
+	env := Env{Stdin: strings.NewReader(""), Stdout: &stdout, Stderr: &stderr, Location: time.UTC}
+
+	code := Run("coreprotect", []string{"--format", "csv", "--filter", "user:th4t action:break", filepath.Join(dir, "database.db")}, env)
+	assert.Equal(t, ExitMatch, code, stderr.String())
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[1], "Th4tGuy1,break,broke stone")
+	}
+}
+
+func TestRun_IndexCacheFlags(t *testing.T) {
+	dir := writeTestFiles(t)
+	latest := filepath.Join(dir, "latest.log")
+	var opened []string
+	run := func(args ...string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		env := Env{Stdin: strings.NewReader(""), Stdout: &stdout, Stderr: &stderr, Location: time.UTC}
+		env.OpenCache = func(dir string) *fileops.IndexCache {
+			opened = append(opened, dir)
+			cache, err := fileops.OpenIndexCache(dir)
+			require.NoError(t, err)
+			return cache
+		}
+		assert.Equal(t, ExitMatch, Run("grep", append(args, "joined", latest), env), stderr.String())
+	}
+
+	// Indexes are cached where asked, and not at all when asked not to be
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	run("--cache-dir", cacheDir)
+	assert.Equal(t, []string{cacheDir}, opened)
+	files, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	opened = nil
+	run("--no-cache", "--cache-dir", cacheDir)
+	assert.Empty(t, opened)
+}
+
+func TestLocationFlag(t *testing.T) {
+	var loc *time.Location
+	flag := &locationFlag{location: &loc}
+	assert.Equal(t, "", flag.String())
+	assert.NoError(t, flag.Set("Europe/London"))
+	assert.Equal(t, "Europe/London", loc.String())
+	assert.Equal(t, "Europe/London", flag.String())
+	assert.Error(t, flag.Set("Europe/Nowhere"))
+	assert.Equal(t, "Europe/London", loc.String(), "an unknown zone leaves the zone unchanged")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{w: &out, prefix: "a.log:"}
+	for _, part := range []string{"one\ntw", "o\n", "", "three\nfour\n"} {
+		_, err := w.Write([]byte(part))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "a.log:one\na.log:two\na.log:three\na.log:four\n", out.String())
+}
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
+)
+
+// stdinPath is the file argument that reads from standard input
+const stdinPath = "-"
+
+// runGrep prints the entries of each file matching a query, prefixed by the
+// file name when there is more than one file
+func runGrep(args []string, env Env) (bool, error) {
+	flags := newFlagSet("grep", grepUsage, &env)
+	coreProtect := flags.Bool("coreprotect", false, "match CoreProtect lookup results instead of log entries")
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return false, err
+	}
+	env.openCache()
+	if len(rest) < 2 {
+		flags.Usage()
+		return false, errUsage
+	}
+
+	q, err := query.Parse(rest[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid query: %w", err)
+	}
+	queries := []*query.Query{q}
+	files := rest[1:]
+
+	out := bufio.NewWriter(env.Stdout)
+	defer out.Flush()
+
+	found := false
+	for _, file := range files {
+		var w io.Writer = out
+		if len(files) > 1 {
+			w = &prefixWriter{w: out, prefix: file + ":"}
+		}
+		encoder, err := fileops.NewEntryEncoder(w, fileops.FormatText)
+		if err != nil {
+			return found, err
+		}
+		count, err := exportFile(encoder, file, queries, *coreProtect, env)
+		found = found || count > 0
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// runExport writes the entries of each file in the chosen format
+func runExport(args []string, env Env) (bool, error) {
+	flags := newFlagSet("export", exportUsage, &env)
+	format := &formatFlag{format: fileops.FormatText}
+	flags.Var(format, "format", "output format: "+exportFormatNames())
+	var filters queryList
+	flags.Var(&filters, "filter", "only export entries matching this query (repeatable, matched with OR)")
+	coreProtect := flags.Bool("coreprotect", false, "export CoreProtect lookup results instead of log entries")
+	files, err := parseFlags(flags, args)
+	if err != nil {
+		return false, err
+	}
+	env.openCache()
+	if len(files) == 0 {
+		flags.Usage()
+		return false, errUsage
+	}
+
+	return exportFiles(files, format.format, filters, *coreProtect, env)
+}
+
+// runCoreProtect prints the CoreProtect lookup results in a client log or
+// database, or a summary of each player's activity
+func runCoreProtect(args []string, env Env) (bool, error) {
+	flags := newFlagSet("coreprotect", coreProtectUsage, &env)
+	format := &formatFlag{format: fileops.FormatText}
+	flags.Var(format, "format", "output format: "+exportFormatNames())
+	var filters queryList
+	flags.Var(&filters, "filter", "only print entries matching this query (repeatable, matched with OR)")
+	players := flags.Bool("players", false, "print a summary of each player's activity instead of the entries")
+	files, err := parseFlags(flags, args)
+	if err != nil {
+		return false, err
+	}
+	if len(files) != 1 {
+		flags.Usage()
+		return false, errUsage
+	}
+
+	if !*players {
+		return exportFiles(files, format.format, filters, true, env)
+	}
+
+	cpLog, err := readCoreProtect(files[0], filters, env)
+	if err != nil {
+		return false, err
+	}
+	out := bufio.NewWriter(env.Stdout)
+	defer out.Flush()
+	for _, summary := range coreprotectparser.Summarize(cpLog.Entries) {
+		if err := writePlayerSummary(out, summary); err != nil {
+			return true, err
+		}
+	}
+	return len(cpLog.Entries) > 0, nil
+}
+
+// exportFiles writes the entries of the files matching any of the queries with one encoder
+func exportFiles(files []string, format fileops.ExportFormat, queries []*query.Query, coreProtect bool, env Env) (bool, error) {
+	out := bufio.NewWriter(env.Stdout)
+	defer out.Flush()
+
+	encoder, err := fileops.NewEntryEncoder(out, format)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, file := range files {
+		count, err := exportFile(encoder, file, queries, coreProtect, env)
+		found = found || count > 0
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, encoder.Close()
+}
+
+// exportFile writes the entries of a file matching any of the queries, returning how many there were
+func exportFile(encoder fileops.EntryEncoder, file string, queries []*query.Query, coreProtect bool, env Env) (int, error) {
+	name := file
+	if file == stdinPath {
+		name = ""
+	}
+
+	count := 0
+	if coreProtect {
+		cpLog, err := readCoreProtect(file, queries, env)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range cpLog.Entries {
+			if err := encoder.WriteCoreProtect(name, entry); err != nil {
+				return count, err
+			}
+			count++
+		}
+		return count, nil
+	}
+
+	err := eachStandardEntry(file, queries, env, func(entry logparser.LogEntry) error {
+		count++
+		return encoder.WriteStandard(name, entry)
+	})
+	return count, err
+}
+
+// eachStandardEntry calls fn with each entry of a standard log matching any of the queries
+func eachStandardEntry(file string, queries []*query.Query, env Env, fn func(logparser.LogEntry) error) error {
+	filter := standardFilter(queries)
+	if file != stdinPath {
+		// The index works out the dates of entries across midnight before they are read
+		index := fileops.NewLogIndex(file, filter, env.Location)
+		index.SetCache(env.Cache, query.AnyRequiredTerms(queries, logparser.TextFields...))
+		defer index.Close()
+		index.Build()
+		if _, err := index.Done(); err != nil {
+			return err
+		}
+		return index.Each(func(_ int, entry logparser.LogEntry) error {
+			return fn(entry)
+		})
+	}
+
+	parser, err := logparser.NewParser()
+	if err != nil {
+		return err
+	}
+	parser.SetLocation(env.Location)
+	scanner := parser.NewEntryScanner(env.Stdin)
+	for scanner.Scan() {
+		entry := scanner.Entry()
+		if filter != nil && !filter(entry) {
+			continue
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// readCoreProtect reads the CoreProtect entries matching any of the queries
+// from a client log, standard input or a CoreProtect database, of which only
+// the newest env.DatabaseLimit are read
+func readCoreProtect(file string, queries []*query.Query, env Env) (*coreprotectparser.ParsedLog, error) {
+	filter := coreProtectFilter(queries)
+	switch {
+	case file == stdinPath:
+		parser, err := logparser.NewParser()
+		if err != nil {
+			return nil, err
+		}
+		parser.SetLocation(env.Location)
+		return coreprotectparser.ParseReader(env.Stdin, parser, filter)
+	case fileops.IsCoreProtectDatabase(file):
+		database, err := coreprotectparser.OpenDatabase(file)
+		if err != nil {
+			return nil, err
+		}
+		defer database.Close()
+		q := coreprotectparser.NewDatabaseQuery(queries, time.Now())
+		q.Limit = env.DatabaseLimit
+		return database.Lookup(q, filter)
+	default:
+		return fileops.ReadCoreProtectLog(file, filter, env.Location)
+	}
+}
+
+// standardFilter returns a log entry filter matching any of the queries
+func standardFilter(queries []*query.Query) logparser.Filter {
+	if len(queries) == 0 {
+		return nil
+	}
+	return func(entry logparser.LogEntry) bool {
+		return query.MatchAny(queries, entry)
+	}
+}
+
+// coreProtectFilter returns a CoreProtect entry filter matching any of the queries
+func coreProtectFilter(queries []*query.Query) coreprotectparser.Filter {
+	if len(queries) == 0 {
+		return nil
+	}
+	return func(entry coreprotectparser.CoreProtectLogEntry) bool {
+		return query.MatchAny(queries, entry)
+	}
+}
+
+// writePlayerSummary writes a player's activity as a line of text
+func writePlayerSummary(w io.Writer, summary coreprotectparser.PlayerSummary) error {
+	var actions []string
+	for _, action := range sortedActions(summary.Actions) {
+		actions = append(actions, fmt.Sprintf("%s=%d", action, summary.Actions[action]))
+	}
+	var materials []string
+	for _, count := range summary.TopMaterials(5) {
+		materials = append(materials, fmt.Sprintf("%s=%d", count.Name, count.Count))
+	}
+
+	line := fmt.Sprintf("%s\t%d\t%s", summary.Username, summary.Entries, strings.Join(actions, ","))
+	if !summary.FirstSeen.IsZero() {
+		line += fmt.Sprintf("\tfirst=%s\tlast=%s",
+			summary.FirstSeen.Format("2006-01-02T15:04:05"), summary.LastSeen.Format("2006-01-02T15:04:05"))
+	}
+	if len(materials) > 0 {
+		line += "\t" + strings.Join(materials, ",")
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// sortedActions returns the actions in the order they are declared
+func sortedActions(counts map[coreprotectparser.Action]int) []coreprotectparser.Action {
+	var actions []coreprotectparser.Action
+	for action := coreprotectparser.ActionChat; action <= coreprotectparser.ActionLogout; action++ {
+		if counts[action] > 0 {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// prefixWriter writes a prefix at the start of every line
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	midway bool // True if the last write did not end a line
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	written := 0
+	for len(data) > 0 {
+		if !p.midway {
+			if _, err := io.WriteString(p.w, p.prefix); err != nil {
+				return written, err
+			}
+		}
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		n, err := p.w.Write(line)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p.midway = line[len(line)-1] != '\n'
+		data = data[len(line):]
+	}
+	return written, nil
+}
@@ -0,0 +1,452 @@
+package fileops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveSeparator separates an archive's path from the path of a file
+// inside it, as in backup.zip!/logs/2024-05-01-1.log.gz
+const ArchiveSeparator = "!/"
+
+// decompressor wraps a reader of compressed data with one of the data it holds
+type decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressors handles each compression format by its extension
+var decompressors = map[string]decompressor{
+	".gz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	".bz2": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	},
+	// Go has no xz or zstd decoder of its own, so the usual tools are used
+	".xz":  commandDecompressor("xz"),
+	".zst": commandDecompressor("zstd"),
+}
+
+// tarExtensions maps the extensions of tar archives to that of their compression, if any
+var tarExtensions = map[string]string{
+	".tar":     "",
+	".tar.gz":  ".gz",
+	".tgz":     ".gz",
+	".tar.bz2": ".bz2",
+	".tbz2":    ".bz2",
+	".tar.xz":  ".xz",
+	".txz":     ".xz",
+	".tar.zst": ".zst",
+	".tzst":    ".zst",
+}
+
+// SplitArchivePath splits a path to a file inside an archive into the path
+// of the archive and that of the file within it. It returns false for other paths.
+func SplitArchivePath(filePath string) (archive, member string, ok bool) {
+	return strings.Cut(filePath, ArchiveSeparator)
+}
+
+// diskPath returns the file on disk holding a log: the log itself or the archive it's in
+func diskPath(filePath string) string {
+	if archive, _, ok := SplitArchivePath(filePath); ok {
+		return archive
+	}
+	return filePath
+}
+
+// isArchive reports whether the file is an archive whose logs are listed as files of their own
+func isArchive(filePath string) bool {
+	_, _, ok := archiveKind(filePath)
+	return ok
+}
+
+// archiveKind returns whether the file is a zip or a tar archive, along with
+// the compression of a tar archive
+func archiveKind(filePath string) (zipped bool, compression string, ok bool) {
+	name := strings.ToLower(filePath)
+	if strings.HasSuffix(name, ".zip") {
+		return true, "", true
+	}
+	for ext, compression := range tarExtensions {
+		if strings.HasSuffix(name, ext) {
+			return false, compression, true
+		}
+	}
+	return false, "", false
+}
+
+// trimCompression removes the extensions of the compression formats a file was compressed with
+func trimCompression(name string) string {
+	for {
+		ext := strings.ToLower(path.Ext(name))
+		if _, ok := decompressors[ext]; !ok {
+			return name
+		}
+		name = name[:len(name)-len(ext)]
+	}
+}
+
+// isLogName reports whether a file is a log, possibly compressed
+func isLogName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(trimCompression(name)), ".log")
+}
+
+// LogBaseName returns the name of a log without its directories, the archive
+// it's in, or its extensions, e.g. 2024-05-01-1 for backup.zip!/logs/2024-05-01-1.log.gz
+func LogBaseName(filePath string) string {
+	if _, member, ok := SplitArchivePath(filePath); ok {
+		filePath = member
+	}
+	name := path.Base(strings.ReplaceAll(filePath, "\\", "/"))
+	name = trimCompression(name)
+	if strings.HasSuffix(strings.ToLower(name), ".log") {
+		name = name[:len(name)-len(".log")]
+	}
+	return name
+}
+
+// layeredReader reads through a stack of readers, closing all of them when closed
+type layeredReader struct {
+	io.Reader
+	closers []io.Closer // In the order they were opened
+}
+
+func (l *layeredReader) Close() error {
+	var firstErr error
+	for i := len(l.closers) - 1; i >= 0; i-- {
+		if err := l.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// decompress wraps r with a decompressor for each compression extension at
+// the end of name, so that nested formats such as .log.gz.bz2 are undone in turn
+func decompress(r io.Reader, name string, closers ...io.Closer) (io.ReadCloser, error) {
+	layered := &layeredReader{Reader: r, closers: closers}
+	for {
+		ext := strings.ToLower(path.Ext(name))
+		decompressor, ok := decompressors[ext]
+		if !ok {
+			return layered, nil
+		}
+		reader, err := decompressor(layered.Reader)
+		if err != nil {
+			layered.Close()
+			return nil, fmt.Errorf("failed to decompress %s: %w", name, err)
+		}
+		layered.Reader = reader
+		layered.closers = append(layered.closers, reader)
+		name = name[:len(name)-len(ext)]
+	}
+}
+
+// openArchiveMember opens a file inside an archive, without decompressing the file itself
+func openArchiveMember(archive, member string) (io.ReadCloser, error) {
+	zipped, compression, ok := archiveKind(archive)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a supported archive", archive)
+	}
+
+	if zipped {
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range reader.File {
+			if file.Name == member {
+				contents, err := file.Open()
+				if err != nil {
+					reader.Close()
+					return nil, err
+				}
+				return &layeredReader{Reader: contents, closers: []io.Closer{reader, contents}}, nil
+			}
+		}
+		reader.Close()
+		return nil, fmt.Errorf("%s%s%s: %w", archive, ArchiveSeparator, member, fs.ErrNotExist)
+	}
+
+	tarball, err := openTar(archive, compression)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			tarball.Close()
+			return nil, fmt.Errorf("%s%s%s: %w", archive, ArchiveSeparator, member, fs.ErrNotExist)
+		}
+		if err != nil {
+			tarball.Close()
+			return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		if header.Name == member && header.Typeflag == tar.TypeReg {
+			return tarball, nil
+		}
+	}
+}
+
+// readArchiveMembers calls read with the contents of each file in an archive
+// named in members, in the order they are stored, reading through the archive
+// only once. The contents are those of the file itself, still compressed if it is.
+func readArchiveMembers(archive string, members map[string]bool, read func(member string, r io.Reader)) error {
+	zipped, compression, ok := archiveKind(archive)
+	if !ok {
+		return fmt.Errorf("%s is not a supported archive", archive)
+	}
+
+	if zipped {
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			if !members[file.Name] {
+				continue
+			}
+			contents, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("failed to read %s%s%s: %w", archive, ArchiveSeparator, file.Name, err)
+			}
+			read(file.Name, contents)
+			contents.Close()
+		}
+		return nil
+	}
+
+	tarball, err := openTar(archive, compression)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", archive, err)
+	}
+	defer tarball.Close()
+	remaining := len(members)
+	for remaining > 0 {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		if members[header.Name] && header.Typeflag == tar.TypeReg {
+			read(header.Name, tarball)
+			remaining--
+		}
+	}
+	return nil
+}
+
+// tarReader reads the files of a tar archive, closing the archive when closed
+type tarReader struct {
+	*tar.Reader
+	io.Closer
+}
+
+// openTar opens a tar archive compressed with the given compression extension, if any
+func openTar(archive, compression string) (*tarReader, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := decompress(file, compression, file)
+	if err != nil {
+		return nil, err
+	}
+	return &tarReader{Reader: tar.NewReader(reader), Closer: reader}, nil
+}
+
+// archiveListing caches the logs listed in an archive until it changes
+type archiveListing struct {
+	size    int64
+	modTime time.Time
+	files   []string
+}
+
+var (
+	archiveListingsMu sync.Mutex
+	archiveListings   = map[string]archiveListing{}
+)
+
+// ListArchive returns the paths of the logs in a zip or tar archive, as
+// archive!/path/in/archive. Listings are cached until the archive changes,
+// since reading a compressed tar archive means decompressing all of it.
+func ListArchive(archive string) ([]string, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return nil, err
+	}
+	archiveListingsMu.Lock()
+	cached, ok := archiveListings[archive]
+	archiveListingsMu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.files, nil
+	}
+
+	var members []string
+	zipped, compression, ok := archiveKind(archive)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%s is not a supported archive", archive)
+	case zipped:
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		for _, file := range reader.File {
+			if !file.FileInfo().IsDir() {
+				members = append(members, file.Name)
+			}
+		}
+		reader.Close()
+	default:
+		tarball, err := openTar(archive, compression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		for {
+			header, err := tarball.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				tarball.Close()
+				return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+			}
+			if header.Typeflag == tar.TypeReg {
+				members = append(members, header.Name)
+			}
+		}
+		tarball.Close()
+	}
+
+	var files []string
+	for _, member := range members {
+		if isLogName(member) && !strings.Contains(member, ArchiveSeparator) {
+			files = append(files, archive+ArchiveSeparator+member)
+		}
+	}
+
+	archiveListingsMu.Lock()
+	archiveListings[archive] = archiveListing{size: info.Size(), modTime: info.ModTime(), files: files}
+	archiveListingsMu.Unlock()
+	return files, nil
+}
+
+// StatLogFile returns information about a log, which for a log inside an
+// archive describes the file within the archive.
+func StatLogFile(filePath string) (os.FileInfo, error) {
+	archive, member, ok := SplitArchivePath(filePath)
+	if !ok {
+		return os.Stat(filePath)
+	}
+
+	zipped, compression, ok := archiveKind(archive)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a supported archive", archive)
+	}
+	if zipped {
+		reader, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			if file.Name == member {
+				return file.FileInfo(), nil
+			}
+		}
+		return nil, fmt.Errorf("%s: %w", filePath, fs.ErrNotExist)
+	}
+
+	tarball, err := openTar(archive, compression)
+	if err != nil {
+		return nil, err
+	}
+	defer tarball.Close()
+	for {
+		header, err := tarball.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s: %w", filePath, fs.ErrNotExist)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive %s: %w", archive, err)
+		}
+		if header.Name == member {
+			return header.FileInfo(), nil
+		}
+	}
+}
+
+// commandReader reads the output of a decompression command
+type commandReader struct {
+	io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	waited bool
+}
+
+// commandDecompressor returns a decompressor running the named tool, which
+// must accept -dc to decompress standard input to standard output
+func commandDecompressor(name string) decompressor {
+	return func(r io.Reader) (io.ReadCloser, error) {
+		tool, err := exec.LookPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("the %s command is needed to read this format: %w", name, err)
+		}
+		reader := &commandReader{cmd: exec.Command(tool, "-dc")}
+		reader.cmd.Stdin = r
+		reader.cmd.Stderr = &reader.stderr
+		if reader.ReadCloser, err = reader.cmd.StdoutPipe(); err != nil {
+			return nil, err
+		}
+		if err := reader.cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", name, err)
+		}
+		return reader, nil
+	}
+}
+
+func (c *commandReader) Read(p []byte) (int, error) {
+	if c.waited {
+		return 0, io.EOF // Waiting closed the pipe
+	}
+	n, err := c.ReadCloser.Read(p)
+	if err == io.EOF {
+		// Corrupt input only shows in the command's exit status
+		c.waited = true
+		if waitErr := c.cmd.Wait(); waitErr != nil {
+			return n, fmt.Errorf("%s: %w: %s", c.cmd.Path, waitErr, strings.TrimSpace(c.stderr.String()))
+		}
+	}
+	return n, err
+}
+
+func (c *commandReader) Close() error {
+	if c.waited {
+		return nil
+	}
+	c.waited = true
+	// Closed before the end, so the rest of the output isn't wanted
+	c.cmd.Process.Kill()
+	if err := c.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package fileops
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// archiveFile is a file to put in a test archive
+type archiveFile struct {
+	name     string
+	contents string
+}
+
+// testArchiveFiles are the files put in each test archive: two logs, one of
+// them gzipped, and a file that isn't a log
+func testArchiveFiles(t *testing.T) []archiveFile {
+	t.Helper()
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	_, err := writer.Write([]byte(indexTestLog))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return []archiveFile{
+		{name: "logs/2024-05-01-1.log.gz", contents: gzipped.String()},
+		{name: "logs/latest.log", contents: "[13:00:00] [Server thread/INFO]: Done\n"},
+		{name: "server.properties", contents: "motd=A Minecraft Server\n"},
+	}
+}
+
+func writeTestZip(t *testing.T, path string, files []archiveFile) {
+	t.Helper()
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	writer := zip.NewWriter(file)
+	_, err = writer.Create("logs/")
+	require.NoError(t, err)
+	for _, f := range files {
+		w, err := writer.Create(f.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(f.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+}
+
+func writeTestTarGz(t *testing.T, path string, files []archiveFile) {
+	t.Helper()
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	compressed := gzip.NewWriter(file)
+	writer := tar.NewWriter(compressed)
+	require.NoError(t, writer.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for _, f := range files {
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(f.contents))}))
+		_, err := writer.Write([]byte(f.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, compressed.Close())
+	require.NoError(t, file.Close())
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	for name, write := range map[string]func(*testing.T, string, []archiveFile){
+		"backup.zip":    writeTestZip,
+		"backup.tar.gz": writeTestTarGz,
+	} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), name)
+			files := testArchiveFiles(t)
+			write(t, archive, files)
+			assert.True(t, isArchive(archive))
+
+			// Only the logs are listed
+			logs, err := ListArchive(archive)
+			require.NoError(t, err)
+			gzipped := archive + ArchiveSeparator + "logs/2024-05-01-1.log.gz"
+			latest := archive + ArchiveSeparator + "logs/latest.log"
+			assert.Equal(t, []string{gzipped, latest}, logs)
+			assert.Equal(t, "2024-05-01-1", LogBaseName(gzipped))
+
+			// Logs are read back decompressed
+			reader, err := OpenLogFile(gzipped)
+			require.NoError(t, err)
+			contents, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			assert.Equal(t, indexTestLog, string(contents))
+
+			info, err := StatLogFile(latest)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(files[1].contents)), info.Size())
+
+			// An index of a log in an archive reads its entries like any other
+			ix := buildTestIndex(t, gzipped, nil)
+			assert.Equal(t, 4, ix.Len())
+			entries, err := ix.ReadEntries(1, 2)
+			require.NoError(t, err)
+			assert.Equal(t, "Can't keep up!", entries[0].Message)
+			assert.Len(t, entries[0].Continuation, 2)
+
+			// Missing logs aren't found
+			_, err = OpenLogFile(archive + ArchiveSeparator + "logs/missing.log")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			_, err = StatLogFile(archive + ArchiveSeparator + "logs/missing.log")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		})
+	}
+}
+
+func TestDetectLogFormats_Archive(t *testing.T) {
+	for name, write := range map[string]func(*testing.T, string, []archiveFile){
+		"formats.zip":    writeTestZip,
+		"formats.tar.gz": writeTestTarGz,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, name)
+			files := append(testArchiveFiles(t), archiveFile{name: "logs/notes.log", contents: "not a log line\n"})
+			write(t, archive, files)
+			plain := writeTestLog(t, dir, "latest.log", indexTestLog)
+			logs, err := ListArchive(archive)
+			require.NoError(t, err)
+			require.Len(t, logs, 3)
+
+			vanilla := logparser.VanillaFormat.Name()
+			expected := map[string]string{logs[0]: vanilla, logs[1]: vanilla, plain: vanilla}
+			assert.Equal(t, expected, DetectLogFormats(append(logs, plain)))
+
+			// Formats are remembered until the archive changes, without reading it again
+			info, err := os.Stat(archive)
+			require.NoError(t, err)
+			files[1].contents = "not a log line\n"
+			write(t, archive, files)
+			require.NoError(t, os.Chtimes(archive, info.ModTime(), info.ModTime()))
+			assert.Equal(t, expected, DetectLogFormats(append(logs, plain)), "cached")
+			later := info.ModTime().Add(time.Second)
+			require.NoError(t, os.Chtimes(archive, later, later))
+			delete(expected, logs[1])
+			assert.Equal(t, expected, DetectLogFormats(append(logs, plain)), "changed")
+		})
+	}
+}
+
+func TestListArchive_RereadWhenChanged(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "backup.zip")
+	files := testArchiveFiles(t)
+	writeTestZip(t, archive, files[1:])
+	logs, err := ListArchive(archive)
+	require.NoError(t, err)
+	assert.Len(t, logs, 1)
+
+	writeTestZip(t, archive, files)
+	logs, err = ListArchive(archive)
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+}
+
+func TestListArchive_NotAnArchive(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	_, err := ListArchive(path)
+	assert.Error(t, err)
+
+	// Corrupt archives fail to be listed
+	broken := writeTestLog(t, t.TempDir(), "broken.zip", "not a zip")
+	_, err = ListArchive(broken)
+	assert.Error(t, err)
+}
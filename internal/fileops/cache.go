@@ -0,0 +1,448 @@
+package fileops
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"goparselogs/pkg/events"
+	"goparselogs/pkg/logparser"
+)
+
+// indexCacheVersion is bumped whenever parsing or the layout of cached
+// indexes changes, so that indexes built by older versions are rebuilt
+const indexCacheVersion = 2
+
+// DefaultIndexCacheSize is the most disk space the cache takes up unless another size is configured.
+const DefaultIndexCacheSize = 2 << 30
+
+// Limits on the words cached for a log. Beyond them the words aren't cached,
+// so that the cache doesn't grow with the log; filtering the log then reads
+// every entry, as it would without a cache.
+const (
+	maxCachedWords    = 200_000   // Distinct words
+	maxCachedPostings = 4_000_000 // Entries listed under all the words together
+)
+
+// staleTempAge is the age at which a temporary file in the cache is taken to
+// have been left behind by a run that didn't finish
+const staleTempAge = 24 * time.Hour
+
+// IndexCache keeps the indexes of logs on disk, so that reopening or searching
+// a log that has been indexed before doesn't need it to be decompressed and
+// parsed again. An index is used only while the log's path, size and
+// modification time, the time zone and the parser version are unchanged.
+// Once the cache is larger than MaxSize, the indexes of the logs used least
+// recently are removed.
+type IndexCache struct {
+	Dir     string
+	MaxSize int64 // Most bytes the cache takes up, no limit if 0
+
+	mu    sync.Mutex
+	inUse map[string]int // Number of open indexes reading each log's cached files, by file name
+}
+
+// DefaultIndexCacheDir returns the directory indexes are cached in unless another is configured.
+func DefaultIndexCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goparselogs", "index"), nil
+}
+
+// OpenIndexCache returns a cache of indexes in dir, creating it if needed.
+func OpenIndexCache(dir string) (*IndexCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index cache directory: %w", err)
+	}
+	return &IndexCache{Dir: dir, MaxSize: DefaultIndexCacheSize}, nil
+}
+
+// cacheKey identifies the version of a log an index was built from
+type cacheKey struct {
+	Path     string
+	Size     int64
+	ModTime  int64
+	Location string
+	Version  int
+}
+
+func newCacheKey(path string, info os.FileInfo, loc *time.Location) cacheKey {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return cacheKey{
+		Path:     path,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Location: loc.String(),
+		Version:  indexCacheVersion,
+	}
+}
+
+// cachedIndex holds the position of every entry of a log, whatever the filter
+type cachedIndex struct {
+	Key        cacheKey
+	Format     string
+	Offsets    []int64
+	Lines      []int32
+	Times      []int64
+	Events     []events.Event // Events of every entry, with EntryIndex into Offsets
+	ReadOffset int64          // Number of bytes of the (decompressed) log indexed
+	HasWords   bool           // Whether the words of the entries are cached too
+
+	count     int32             // Number of entries recorded
+	filtered  bool              // Whether positions and events are recorded here, rather than taken from an index of every entry
+	words     *wordIndex        // Words of the entries recorded, nil once there are too many to cache
+	extractor *events.Extractor // Extracts events from every entry while the index is being built, if filtered
+}
+
+// wordIndex lists the entries containing each word of a log. It is cached in
+// a file of its own, which is only read when filtering the log.
+type wordIndex struct {
+	Key   cacheKey
+	Words map[string][]int32 // Entries containing each lower case word of their text, in order
+
+	postings int // Number of entries listed under all the words together
+}
+
+// newCachedIndex starts recording an index. The index being built by a
+// filtered LogIndex only holds some of the entries, so the position and events
+// of every entry are recorded separately; otherwise they are taken from the
+// LogIndex when the index is stored.
+func newCachedIndex(key cacheKey, filtered bool) *cachedIndex {
+	ci := &cachedIndex{
+		Key:      key,
+		filtered: filtered,
+		words:    &wordIndex{Key: key, Words: map[string][]int32{}},
+	}
+	if filtered {
+		ci.extractor = events.NewExtractor()
+	}
+	return ci
+}
+
+// add records an entry scanned from the log
+func (ci *cachedIndex) add(entry logparser.LogEntry) {
+	position := ci.count
+	ci.count++
+	if ci.filtered {
+		ci.Offsets = append(ci.Offsets, entry.Offset)
+		ci.Lines = append(ci.Lines, int32(entry.LineNumber))
+		ci.Times = append(ci.Times, unixNanos(entry.Time))
+		if event, ok := ci.extractor.Add(entry, int(position)); ok {
+			event.Entry.Continuation = nil
+			ci.Events = append(ci.Events, event)
+		}
+	}
+	if w := ci.words; w != nil {
+		for _, word := range entryWords(entry) {
+			w.Words[word] = append(w.Words[word], position)
+			w.postings++
+		}
+		if len(w.Words) > maxCachedWords || w.postings > maxCachedPostings {
+			ci.words = nil
+		}
+	}
+}
+
+// entryWords returns the distinct lower case words of an entry's text
+func entryWords(entry logparser.LogEntry) []string {
+	var words []string
+	for _, value := range entry.Text() {
+		words = append(words, textWords(value)...)
+	}
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+// textWords splits text into lower case runs of letters and digits
+func textWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// candidates returns the positions of the entries that could match a filter
+// made of alternatives, each requiring all of its terms, in order. It returns
+// false if any entry could match. A term can only be found in an entry if
+// each of its words is part of one of the entry's words.
+func (wi *wordIndex) candidates(alternatives [][]string) ([]int32, bool) {
+	if len(alternatives) == 0 {
+		return nil, false
+	}
+	var union []int32
+	for _, terms := range alternatives {
+		var words []string
+		for _, term := range terms {
+			words = append(words, textWords(term)...)
+		}
+		if len(words) == 0 {
+			return nil, false
+		}
+
+		var matched []int32
+		for i, word := range words {
+			var containing []int32
+			for indexed, positions := range wi.Words {
+				if strings.Contains(indexed, word) {
+					containing = append(containing, positions...)
+				}
+			}
+			slices.Sort(containing)
+			containing = slices.Compact(containing)
+			if i == 0 {
+				matched = containing
+			} else {
+				matched = intersect(matched, containing)
+			}
+		}
+		union = append(union, matched...)
+	}
+	slices.Sort(union)
+	return slices.Compact(union), true
+}
+
+// intersect returns the positions in both sorted lists
+func intersect(a, b []int32) []int32 {
+	var both []int32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			both = append(both, a[i])
+			i++
+			j++
+		}
+	}
+	return both
+}
+
+// cacheName returns the name the cached files of a log start with
+func cacheName(logPath string) string {
+	if abs, err := filepath.Abs(logPath); err == nil {
+		logPath = abs
+	}
+	sum := sha256.Sum256([]byte(logPath))
+	return hex.EncodeToString(sum[:16])
+}
+
+// files returns the paths of the cached index of a log, its words and its decompressed copy
+func (c *IndexCache) files(logPath string) (indexPath, wordsPath, dataPath string) {
+	name := filepath.Join(c.Dir, cacheName(logPath))
+	return name + ".idx", name + ".words", name + ".log"
+}
+
+// acquire marks the cached files of a log as being read by an index, so that
+// they aren't removed to make room in the cache until released
+func (c *IndexCache) acquire(logPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inUse == nil {
+		c.inUse = map[string]int{}
+	}
+	c.inUse[cacheName(logPath)]++
+}
+
+// release undoes acquire once the index reading a log is closed
+func (c *IndexCache) release(logPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := cacheName(logPath)
+	if c.inUse[name]--; c.inUse[name] <= 0 {
+		delete(c.inUse, name)
+	}
+}
+
+// load returns the cached index of a log if it was built from the same
+// version of it, along with the file its offsets refer to
+func (c *IndexCache) load(logPath string, key cacheKey) (*cachedIndex, string, bool) {
+	indexPath, _, dataPath := c.files(logPath)
+	file, err := os.Open(indexPath)
+	if err != nil {
+		return nil, "", false
+	}
+	defer file.Close()
+
+	var ci cachedIndex
+	if err := gob.NewDecoder(file).Decode(&ci); err != nil || ci.Key != key {
+		return nil, "", false
+	}
+	if _, ok := logparser.FormatByName(ci.Format); !ok {
+		return nil, "", false
+	}
+	if !isCompressed(logPath) {
+		dataPath = logPath
+	} else if info, err := os.Stat(dataPath); err != nil || info.Size() != ci.ReadOffset {
+		return nil, "", false
+	}
+	// The modification time of the index records when the log was last used
+	now := time.Now()
+	os.Chtimes(indexPath, now, now)
+	return &ci, dataPath, true
+}
+
+// loadWords returns the cached words of a log whose index was loaded with the given key
+func (c *IndexCache) loadWords(logPath string, key cacheKey) (*wordIndex, bool) {
+	_, wordsPath, _ := c.files(logPath)
+	file, err := os.Open(wordsPath)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var wi wordIndex
+	if err := gob.NewDecoder(file).Decode(&wi); err != nil || wi.Key != key {
+		return nil, false
+	}
+	return &wi, true
+}
+
+// store saves the index of a log, replacing any index of an older version of
+// it, and then makes room for it in the cache
+func (c *IndexCache) store(logPath string, ci *cachedIndex) error {
+	indexPath, wordsPath, _ := c.files(logPath)
+	defer c.evict(cacheName(logPath))
+
+	ci.HasWords = ci.words != nil
+	if ci.HasWords {
+		err := writeAtomically(wordsPath, func(file *os.File) error {
+			return gob.NewEncoder(file).Encode(ci.words)
+		})
+		if err != nil {
+			return err
+		}
+	} else if err := os.Remove(wordsPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return writeAtomically(indexPath, func(file *os.File) error {
+		return gob.NewEncoder(file).Encode(ci)
+	})
+}
+
+// cacheEntry is the cached files of one log
+type cacheEntry struct {
+	paths []string
+	size  int64
+	used  time.Time // When the log was last indexed or opened, zero if it has no index
+}
+
+// evict removes the cached files of the logs used least recently until the
+// cache fits in MaxSize, sparing the log named keep and those being read.
+// Temporary files left behind by runs that didn't finish are removed too.
+func (c *IndexCache) evict(keep string) {
+	if c.MaxSize <= 0 {
+		return
+	}
+	files, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return
+	}
+
+	entries := map[string]*cacheEntry{}
+	var total int64
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(c.Dir, file.Name())
+		name, ext, ok := strings.Cut(file.Name(), ".")
+		if !ok || strings.HasSuffix(ext, "tmp") || strings.HasSuffix(ext, "link") {
+			// Still being written, unless it was abandoned
+			if time.Since(info.ModTime()) > staleTempAge {
+				os.Remove(path)
+			}
+			continue
+		}
+		entry := entries[name]
+		if entry == nil {
+			entry = &cacheEntry{}
+			entries[name] = entry
+		}
+		entry.paths = append(entry.paths, path)
+		entry.size += info.Size()
+		if ext == "idx" {
+			entry.used = info.ModTime()
+		}
+		total += info.Size()
+	}
+	if total <= c.MaxSize {
+		return
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return entries[names[i]].used.Before(entries[names[j]].used)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, name := range names {
+		if total <= c.MaxSize {
+			break
+		}
+		if name == keep || c.inUse[name] > 0 {
+			continue
+		}
+		for _, path := range entries[name].paths {
+			os.Remove(path)
+		}
+		total -= entries[name].size
+	}
+}
+
+// createData creates a temporary file in the cache to decompress a log into
+func (c *IndexCache) createData() (*os.File, error) {
+	return os.CreateTemp(c.Dir, "data-*.tmp")
+}
+
+// linkData links a decompressed copy of a log into the cache, leaving the copy
+// itself in place for the index reading it
+func (c *IndexCache) linkData(logPath, tempPath string) error {
+	_, _, dataPath := c.files(logPath)
+	staging := tempPath + ".link"
+	if err := os.Link(tempPath, staging); err != nil {
+		return fmt.Errorf("failed to cache decompressed log: %w", err)
+	}
+	if err := os.Rename(staging, dataPath); err != nil {
+		os.Remove(staging)
+		return fmt.Errorf("failed to cache decompressed log: %w", err)
+	}
+	return nil
+}
+
+// writeAtomically writes a file through a temporary file, so that readers
+// never see it half written
+func writeAtomically(path string, write func(*os.File) error) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err := write(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
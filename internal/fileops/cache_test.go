@@ -0,0 +1,174 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestCache(t *testing.T) *IndexCache {
+	t.Helper()
+	cache, err := OpenIndexCache(t.TempDir())
+	require.NoError(t, err)
+	return cache
+}
+
+func buildCachedIndex(t *testing.T, cache *IndexCache, path string, filter logparser.Filter, terms [][]string) *LogIndex {
+	t.Helper()
+	ix := NewLogIndex(path, filter, time.UTC)
+	ix.SetCache(cache, terms)
+	ix.Build()
+	t.Cleanup(func() { ix.Close() })
+	done, err := ix.Done()
+	require.True(t, done)
+	require.NoError(t, err)
+	return ix
+}
+
+// testCacheKey returns the key a log would be cached under as it is now
+func testCacheKey(t *testing.T, path string, loc *time.Location) cacheKey {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return newCacheKey(path, info, loc)
+}
+
+func TestIndexCache_InvalidatedWhenLogChanges(t *testing.T) {
+	tests := map[string]func(t *testing.T, path string) cacheKey{
+		"size": func(t *testing.T, path string) cacheKey {
+			appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Alex joined the game\n")
+			return testCacheKey(t, path, time.UTC)
+		},
+		"modification time": func(t *testing.T, path string) cacheKey {
+			later := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(path, later, later))
+			return testCacheKey(t, path, time.UTC)
+		},
+		"time zone": func(t *testing.T, path string) cacheKey {
+			return testCacheKey(t, path, time.FixedZone("UTC+2", 2*60*60))
+		},
+		"parser version": func(t *testing.T, path string) cacheKey {
+			key := testCacheKey(t, path, time.UTC)
+			key.Version++
+			return key
+		},
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			cache := openTestCache(t)
+			path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+			buildCachedIndex(t, cache, path, nil, nil)
+
+			cached, dataPath, ok := cache.load(path, testCacheKey(t, path, time.UTC))
+			require.True(t, ok)
+			assert.Equal(t, path, dataPath)
+			assert.Len(t, cached.Offsets, 4)
+
+			_, _, ok = cache.load(path, change(t, path))
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestIndexCache_FilterFromCachedWords(t *testing.T) {
+	cache := openTestCache(t)
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	buildCachedIndex(t, cache, path, nil, nil)
+	_, wordsPath, _ := cache.files(path)
+	assert.FileExists(t, wordsPath)
+
+	filter := func(entry logparser.LogEntry) bool {
+		return strings.Contains(strings.ToLower(entry.Message), "joined")
+	}
+	ix := buildCachedIndex(t, cache, path, filter, [][]string{{"joined"}})
+	assert.Equal(t, 1, ix.Len())
+	entries, err := ix.ReadEntries(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Steve joined the game", entries[0].Message)
+	assert.Equal(t, 5, entries[0].LineNumber)
+}
+
+func TestIndexCache_SkipsWordsOfLargeLogs(t *testing.T) {
+	cache := openTestCache(t)
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	buildCachedIndex(t, cache, path, nil, nil)
+	_, wordsPath, _ := cache.files(path)
+	require.FileExists(t, wordsPath)
+
+	// Too many distinct words to be worth caching
+	ci := newCachedIndex(testCacheKey(t, path, time.UTC), false)
+	for i := 0; i <= maxCachedWords; i++ {
+		ci.add(logparser.LogEntry{Message: fmt.Sprintf("word%d", i)})
+	}
+	assert.Nil(t, ci.words)
+	assert.Equal(t, int32(maxCachedWords+1), ci.count, "the entries are still recorded")
+
+	// Storing an index without words removes those of an older one
+	cached, _, ok := cache.load(path, ci.Key)
+	require.True(t, ok)
+	require.True(t, cached.HasWords)
+	require.NoError(t, cache.store(path, cached))
+	assert.NoFileExists(t, wordsPath)
+	cached, _, ok = cache.load(path, ci.Key)
+	require.True(t, ok)
+	assert.False(t, cached.HasWords)
+}
+
+func TestIndexCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := openTestCache(t)
+	dir := t.TempDir()
+	var indexPaths []string
+	for _, name := range []string{"recent.log", "old.log", "open.log"} {
+		path := writeTestLog(t, dir, name, indexTestLog)
+		ix := NewLogIndex(path, nil, time.UTC)
+		ix.SetCache(cache, nil)
+		ix.Build()
+		if name != "open.log" {
+			require.NoError(t, ix.Close())
+		} else {
+			t.Cleanup(func() { ix.Close() })
+		}
+		indexPath, _, _ := cache.files(path)
+		indexPaths = append(indexPaths, indexPath)
+	}
+	now := time.Now()
+	require.NoError(t, os.Chtimes(indexPaths[1], now, now.Add(-time.Hour)))
+	require.NoError(t, os.Chtimes(indexPaths[2], now, now.Add(-2*time.Hour)))
+
+	// Temporary files are left alone until they have been abandoned
+	abandoned := filepath.Join(cache.Dir, "data-1.tmp")
+	writing := filepath.Join(cache.Dir, "data-2.tmp")
+	require.NoError(t, os.WriteFile(abandoned, nil, 0o644))
+	require.NoError(t, os.WriteFile(writing, nil, 0o644))
+	require.NoError(t, os.Chtimes(abandoned, now, now.Add(-2*staleTempAge)))
+
+	var total int64
+	files, err := os.ReadDir(cache.Dir)
+	require.NoError(t, err)
+	for _, file := range files {
+		info, err := file.Info()
+		require.NoError(t, err)
+		total += info.Size()
+	}
+
+	// The oldest log is still open, so the next oldest makes room
+	cache.MaxSize = total - 1
+	cache.evict("")
+	assert.FileExists(t, indexPaths[0])
+	assert.NoFileExists(t, indexPaths[1])
+	assert.FileExists(t, indexPaths[2])
+	assert.NoFileExists(t, abandoned)
+	assert.FileExists(t, writing)
+
+	// Nothing is removed while the cache fits
+	cache.evict("")
+	assert.FileExists(t, indexPaths[0])
+}
@@ -0,0 +1,139 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultOutputDir is the directory exports are saved in unless another is configured.
+const DefaultOutputDir = "output"
+
+// GzipExtension marks exports that are compressed with gzip.
+const GzipExtension = ".gz"
+
+// ErrUnsafeFilename is returned for export filenames that would be saved outside the output directory.
+var ErrUnsafeFilename = errors.New("filename must be a relative path inside the output directory")
+
+// ResolveOutputPath returns the path an export named filename is saved to in
+// outputDir. Absolute names and names climbing out of outputDir with ".." are
+// rejected, so an export can never overwrite files elsewhere.
+func ResolveOutputPath(outputDir, filename string) (string, error) {
+	if outputDir == "" {
+		outputDir = DefaultOutputDir
+	}
+	filename = strings.TrimSpace(filename)
+	if filename == "" {
+		return "", fmt.Errorf("filename cannot be empty")
+	}
+	name := filepath.FromSlash(filename)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%q: %w", filename, ErrUnsafeFilename)
+	}
+	if strings.HasSuffix(filename, "/") || filepath.Base(name) == "." {
+		return "", fmt.Errorf("%q is a directory, not a filename", filename)
+	}
+	return filepath.Join(outputDir, filepath.Clean(name)), nil
+}
+
+// FilenameVars are the values substituted into filename templates.
+type FilenameVars struct {
+	File    string    // Path of the log being exported
+	Filters []string  // Source text of the active filter queries
+	Now     time.Time // Time of the export
+}
+
+// unsafeFilenameChars matches runs of characters that are left out of template values
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ExpandFilenameTemplate replaces the placeholders {file}, {date}, {time} and
+// {filter} in a filename. Substituted values never contain path separators.
+func ExpandFilenameTemplate(template string, vars FilenameVars) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	now := vars.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	file := LogBaseName(vars.File)
+	if !isLogName(vars.File) {
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+	if vars.File == "" || file == "." {
+		file = "logs"
+	}
+	filter := "all"
+	if len(vars.Filters) > 0 {
+		filter = strings.Join(vars.Filters, "-")
+	}
+
+	replacer := strings.NewReplacer(
+		"{file}", filenameSafe(file),
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{filter}", filenameSafe(filter),
+	)
+	return replacer.Replace(template)
+}
+
+// filenameSafe replaces characters that could change the meaning of a path, trimming the result
+func filenameSafe(value string) string {
+	value = strings.Trim(unsafeFilenameChars.ReplaceAllString(value, "_"), "_.")
+	if len(value) > 64 {
+		value = strings.TrimRight(value[:64], "_.")
+	}
+	if value == "" {
+		return "_"
+	}
+	return value
+}
+
+// CompleteDirectory completes the last path element of a partially typed
+// export filename to a subdirectory of outputDir. A single match is completed
+// with a trailing slash; several are completed to their longest common prefix.
+// It returns the matching directory names and whether partial changed.
+func CompleteDirectory(outputDir, partial string) (string, []string, bool) {
+	if outputDir == "" {
+		outputDir = DefaultOutputDir
+	}
+	dir, prefix := "", partial
+	if i := strings.LastIndex(partial, "/"); i >= 0 {
+		dir, prefix = partial[:i+1], partial[i+1:]
+	}
+	if dir != "" && !filepath.IsLocal(filepath.FromSlash(dir)) {
+		return partial, nil, false
+	}
+
+	entries, err := os.ReadDir(filepath.Join(outputDir, filepath.FromSlash(dir)))
+	if err != nil {
+		return partial, nil, false
+	}
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return partial, nil, false
+	case 1:
+		return dir + matches[0] + "/", matches, true
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	return dir + common, matches, len(common) > len(prefix)
+}
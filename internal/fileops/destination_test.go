@@ -0,0 +1,118 @@
+package fileops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOutputPath(t *testing.T) {
+	tests := []struct {
+		filename string
+		expected string // Empty if the name is rejected
+	}{
+		{"export.txt", filepath.Join("out", "export.txt")},
+		{"  export.txt  ", filepath.Join("out", "export.txt")},
+		{"2024/may/export.json", filepath.Join("out", "2024", "may", "export.json")},
+		{"a/../export.txt", filepath.Join("out", "export.txt")},
+		{"", ""},
+		{"../export.txt", ""},
+		{"a/../../export.txt", ""},
+		{"/etc/passwd", ""},
+		{"reports/", ""},
+		{".", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			path, err := ResolveOutputPath("out", tt.filename)
+			if tt.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+
+	_, err := ResolveOutputPath("out", "../../etc/cron.d/job")
+	assert.ErrorIs(t, err, ErrUnsafeFilename)
+
+	path, err := ResolveOutputPath("", "export.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(DefaultOutputDir, "export.txt"), path)
+}
+
+func TestExpandFilenameTemplate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 13, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		template string
+		vars     FilenameVars
+		expected string
+	}{
+		{"no placeholders", "export.txt", FilenameVars{File: "logs/latest.log"}, "export.txt"},
+		{"all placeholders", "{file}_{date}_{time}_{filter}.txt",
+			FilenameVars{File: "logs/2024-05-01-1.log.gz", Filters: []string{"level:ERROR", "user:Steve"}, Now: now},
+			"2024-05-01-1_2024-05-01_130405_level_ERROR-user_Steve.txt"},
+		{"no filters", "{file}-{filter}.csv", FilenameVars{File: "latest.log", Now: now}, "latest-all.csv"},
+		{"no file", "{file}.json", FilenameVars{Now: now}, "logs.json"},
+		{"database", "{file}.json", FilenameVars{File: "plugins/CoreProtect/database.db", Now: now}, "database.json"},
+		{"archive member", "{file}.txt", FilenameVars{File: "backup.zip!/logs/2024-05-01-1.log.gz", Now: now}, "2024-05-01-1.txt"},
+		{"filters can't climb out", "{filter}.txt", FilenameVars{Filters: []string{"../../etc/passwd"}, Now: now}, "etc_passwd.txt"},
+		{"directories in the template are kept", "{date}/{file}.txt", FilenameVars{File: "latest.log", Now: now}, "2024-05-01/latest.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExpandFilenameTemplate(tt.template, tt.vars))
+		})
+	}
+}
+
+func TestFilenameSafe(t *testing.T) {
+	assert.Equal(t, "latest", filenameSafe("latest"))
+	assert.Equal(t, "level_ERROR_AND_Steve", filenameSafe(`level:ERROR AND "Steve"`))
+	assert.Equal(t, "a_b_c", filenameSafe(`a/b\c`))
+	assert.Equal(t, "etc", filenameSafe("../.."+string(filepath.Separator)+"etc"))
+	assert.Equal(t, "_", filenameSafe(""))
+	assert.Equal(t, "_", filenameSafe("../"))
+	assert.Equal(t, strings.Repeat("a", 64), filenameSafe(strings.Repeat("a", 100)))
+	assert.Equal(t, strings.Repeat("a", 63), filenameSafe(strings.Repeat("a", 63)+"/b"))
+}
+
+func TestCompleteDirectory(t *testing.T) {
+	outputDir := t.TempDir()
+	for _, dir := range []string{"reports", "reviews", "archive/2024", "archive/2023"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(outputDir, filepath.FromSlash(dir)), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "readme.txt"), nil, 0o644))
+
+	tests := []struct {
+		partial   string
+		completed string
+		matches   []string
+		changed   bool
+	}{
+		{"arc", "archive/", []string{"archive"}, true},
+		{"re", "re", []string{"reports", "reviews"}, false},
+		{"rep", "reports/", []string{"reports"}, true},
+		{"archive/", "archive/202", []string{"2023", "2024"}, true},
+		{"archive/2024", "archive/2024/", []string{"2024"}, true},
+		{"readme", "readme", nil, false},
+		{"nothing", "nothing", nil, false},
+		{"../", "../", nil, false},
+		{"missing/", "missing/", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.partial, func(t *testing.T) {
+			completed, matches, changed := CompleteDirectory(outputDir, tt.partial)
+			assert.Equal(t, tt.completed, completed)
+			assert.Equal(t, tt.matches, matches)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package fileops
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"goparselogs/pkg/logparser"
+)
+
+// detectedFormat caches the detected format of a file along with its modification time
+type detectedFormat struct {
+	modTime time.Time
+	name    string
+}
+
+var (
+	formatCacheMu sync.Mutex
+	formatCache   = map[string]detectedFormat{}
+)
+
+// DetectLogFormat returns the name of the log format detected from the first lines
+// of the file, or an empty string if no known format matches. Results are cached
+// until the file's modification time changes.
+func DetectLogFormat(filePath string) (string, error) {
+	// A log in an archive changes with the archive
+	info, err := os.Stat(diskPath(filePath))
+	if err != nil {
+		return "", err
+	}
+
+	if name, ok := cachedFormat(filePath, info.ModTime()); ok {
+		return name, nil
+	}
+
+	reader, err := OpenLogFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	name := detectReaderFormat(reader)
+	cacheFormat(filePath, info.ModTime(), name)
+	return name, nil
+}
+
+// detectReaderFormat returns the name of the log format detected from the first lines of r, if any
+func detectReaderFormat(r io.Reader) string {
+	if format, ok := logparser.DetectReaderFormat(r); ok {
+		return format.Name()
+	}
+	return ""
+}
+
+// cachedFormat returns the format detected for a file when it was last modified at modTime
+func cachedFormat(filePath string, modTime time.Time) (string, bool) {
+	formatCacheMu.Lock()
+	defer formatCacheMu.Unlock()
+	cached, ok := formatCache[filePath]
+	if !ok || !cached.modTime.Equal(modTime) {
+		return "", false
+	}
+	return cached.name, true
+}
+
+// cacheFormat remembers the format detected for a file last modified at modTime
+func cacheFormat(filePath string, modTime time.Time, name string) {
+	formatCacheMu.Lock()
+	formatCache[filePath] = detectedFormat{modTime: modTime, name: name}
+	formatCacheMu.Unlock()
+}
+
+// CoreProtectDatabaseFormat is the format name given to CoreProtect databases
+const CoreProtectDatabaseFormat = "CoreProtect DB"
+
+// DetectLogFormats detects the format of each file, skipping files that cannot
+// be read. Each archive holding some of the files is read through only once.
+func DetectLogFormats(filePaths []string) map[string]string {
+	formats := make(map[string]string, len(filePaths))
+	archived := map[string][]string{}
+	for _, path := range filePaths {
+		if IsCoreProtectDatabase(path) {
+			formats[path] = CoreProtectDatabaseFormat
+			continue
+		}
+		if archive, _, ok := SplitArchivePath(path); ok {
+			archived[archive] = append(archived[archive], path)
+			continue
+		}
+		if name, err := DetectLogFormat(path); err == nil && name != "" {
+			formats[path] = name
+		}
+	}
+	for archive, paths := range archived {
+		detectArchiveFormats(archive, paths, formats)
+	}
+	return formats
+}
+
+// detectArchiveFormats adds the formats of logs in an archive to formats,
+// reading the archive once for all those not already cached
+func detectArchiveFormats(archive string, filePaths []string, formats map[string]string) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return
+	}
+	members := map[string]bool{}
+	for _, path := range filePaths {
+		if name, ok := cachedFormat(path, info.ModTime()); ok {
+			if name != "" {
+				formats[path] = name
+			}
+			continue
+		}
+		_, member, _ := SplitArchivePath(path)
+		members[member] = true
+	}
+	if len(members) == 0 {
+		return
+	}
+
+	readArchiveMembers(archive, members, func(member string, r io.Reader) {
+		reader, err := decompress(r, member)
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+		path := archive + ArchiveSeparator + member
+		name := detectReaderFormat(reader)
+		cacheFormat(path, info.ModTime(), name)
+		if name != "" {
+			formats[path] = name
+		}
+	})
+}
@@ -0,0 +1,363 @@
+package fileops
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/logparser"
+)
+
+// ExportFormat is a file format log entries can be exported in.
+type ExportFormat string
+
+const (
+	FormatText   ExportFormat = "text"   // Lines as they appeared in the original log
+	FormatJSON   ExportFormat = "json"   // A JSON array of objects holding every parsed field
+	FormatNDJSON ExportFormat = "ndjson" // One JSON object per line, for streaming into other tools
+	FormatCSV    ExportFormat = "csv"    // A header row and one row per entry, for spreadsheets
+	FormatHTML   ExportFormat = "html"   // A self-contained page with colour coding and a search box
+)
+
+// ExportFormats lists the supported export formats.
+var ExportFormats = []ExportFormat{FormatText, FormatJSON, FormatNDJSON, FormatCSV, FormatHTML}
+
+// exportExtensions maps file extensions to the format they imply
+var exportExtensions = map[string]ExportFormat{
+	".log":    FormatText,
+	".txt":    FormatText,
+	".json":   FormatJSON,
+	".ndjson": FormatNDJSON,
+	".jsonl":  FormatNDJSON,
+	".csv":    FormatCSV,
+	".html":   FormatHTML,
+	".htm":    FormatHTML,
+}
+
+// Extension returns the file extension usually given to exports in the format.
+func (f ExportFormat) Extension() string {
+	if f == FormatText {
+		return ".log"
+	}
+	return "." + string(f)
+}
+
+// FormatForFilename returns the export format implied by a filename's
+// extension, looking past a gzip extension.
+func FormatForFilename(filename string) (ExportFormat, bool) {
+	filename = strings.ToLower(filename)
+	filename = strings.TrimSuffix(filename, GzipExtension)
+	format, ok := exportExtensions[filepath.Ext(filename)]
+	return format, ok
+}
+
+// ParseExportFormat returns the export format with the given name.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, format := range ExportFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q", name)
+}
+
+// EntryEncoder writes log entries one at a time in an export format. Close
+// must be called after the last entry to finish the output.
+type EntryEncoder interface {
+	// WriteStandard writes a standard log entry read from the named file.
+	WriteStandard(file string, entry logparser.LogEntry) error
+	// WriteCoreProtect writes a CoreProtect entry read from the named file.
+	WriteCoreProtect(file string, entry coreprotectparser.CoreProtectLogEntry) error
+	Close() error
+}
+
+// NewEntryEncoder returns an encoder writing entries to w in the given format.
+func NewEntryEncoder(w io.Writer, format ExportFormat) (EntryEncoder, error) {
+	switch format {
+	case FormatText:
+		return &textEncoder{w: w}, nil
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{w: w}, nil
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case FormatHTML:
+		return &htmlEncoder{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// textEncoder writes entries as they appeared in the log
+type textEncoder struct {
+	w io.Writer
+}
+
+func (e *textEncoder) WriteStandard(_ string, entry logparser.LogEntry) error {
+	return writeStandardEntry(e.w, entry)
+}
+
+func (e *textEncoder) WriteCoreProtect(_ string, entry coreprotectparser.CoreProtectLogEntry) error {
+	line := entry.RawLine
+	if at := entry.EventTimeString(); at != "" {
+		line = fmt.Sprintf("[%s] %s", at, line)
+	}
+	if _, err := fmt.Fprintln(e.w, line); err != nil {
+		return err
+	}
+	if entry.LocationLine != "" {
+		if _, err := fmt.Fprintln(e.w, entry.LocationLine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *textEncoder) Close() error {
+	return nil
+}
+
+// standardRecord holds the fields of a standard log entry for structured exports
+type standardRecord struct {
+	File         string     `json:"file,omitempty"`
+	Line         int        `json:"line,omitempty"`
+	Time         *time.Time `json:"time,omitempty"`
+	Timestamp    string     `json:"timestamp"`
+	Date         string     `json:"date,omitempty"`
+	Thread       string     `json:"thread,omitempty"`
+	Level        string     `json:"level"`
+	Logger       string     `json:"logger,omitempty"`
+	Message      string     `json:"message"`
+	Continuation []string   `json:"continuation,omitempty"`
+}
+
+func newStandardRecord(file string, entry logparser.LogEntry) standardRecord {
+	return standardRecord{
+		File:         file,
+		Line:         entry.LineNumber,
+		Time:         optionalTime(entry.Time),
+		Timestamp:    entry.Timestamp,
+		Date:         entry.Date,
+		Thread:       entry.Thread,
+		Level:        entry.Level,
+		Logger:       entry.Logger,
+		Message:      entry.Message,
+		Continuation: entry.Continuation,
+	}
+}
+
+// coreProtectRecord holds the fields of a CoreProtect entry for structured exports
+type coreProtectRecord struct {
+	File           string          `json:"file,omitempty"`
+	User           string          `json:"user"`
+	Action         string          `json:"action"`
+	Message        string          `json:"message"`
+	Target         string          `json:"target,omitempty"`
+	Amount         int             `json:"amount,omitempty"`
+	Location       *locationRecord `json:"location,omitempty"`
+	Ago            string          `json:"ago"`
+	HoursAgo       float64         `json:"hoursAgo"`
+	EventTime      *time.Time      `json:"eventTime,omitempty"`
+	EventTimeError float64         `json:"eventTimeErrorSeconds,omitempty"`
+	LoggedAt       *time.Time      `json:"loggedAt,omitempty"`
+	Session        int             `json:"session"`
+	Page           int             `json:"page,omitempty"`
+	RawLine        string          `json:"rawLine"`
+}
+
+func newCoreProtectRecord(file string, entry coreprotectparser.CoreProtectLogEntry) coreProtectRecord {
+	return coreProtectRecord{
+		File:           file,
+		User:           entry.Username,
+		Action:         entry.Action.String(),
+		Message:        entry.Message,
+		Target:         entry.Target,
+		Amount:         entry.Amount,
+		Location:       newLocationRecord(entry.Location),
+		Ago:            entry.AgoString(),
+		HoursAgo:       entry.HoursAgo,
+		EventTime:      optionalTime(entry.EventTime),
+		EventTimeError: entry.EventTimeError.Seconds(),
+		LoggedAt:       optionalTime(entry.LoggedAt),
+		Session:        entry.Session,
+		Page:           entry.Page,
+		RawLine:        entry.RawLine,
+	}
+}
+
+// locationRecord holds the block position of a CoreProtect entry for structured exports
+type locationRecord struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Z     int    `json:"z"`
+	World string `json:"world"`
+}
+
+func newLocationRecord(location *coreprotectparser.Location) *locationRecord {
+	if location == nil {
+		return nil
+	}
+	return &locationRecord{X: location.X, Y: location.Y, Z: location.Z, World: location.World}
+}
+
+// optionalTime returns nil for an unknown time so that it is left out of exports
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// jsonEncoder writes entries as a JSON array, one object per line
+type jsonEncoder struct {
+	w       io.Writer
+	written bool
+}
+
+func (e *jsonEncoder) WriteStandard(file string, entry logparser.LogEntry) error {
+	return e.write(newStandardRecord(file, entry))
+}
+
+func (e *jsonEncoder) WriteCoreProtect(file string, entry coreprotectparser.CoreProtectLogEntry) error {
+	return e.write(newCoreProtectRecord(file, entry))
+}
+
+func (e *jsonEncoder) write(record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if !e.written {
+		separator = "[\n"
+		e.written = true
+	}
+	_, err = fmt.Fprintf(e.w, "%s  %s", separator, data)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	if !e.written {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// ndjsonEncoder writes entries as one JSON object per line
+type ndjsonEncoder struct {
+	w io.Writer
+}
+
+func (e *ndjsonEncoder) WriteStandard(file string, entry logparser.LogEntry) error {
+	return e.write(newStandardRecord(file, entry))
+}
+
+func (e *ndjsonEncoder) WriteCoreProtect(file string, entry coreprotectparser.CoreProtectLogEntry) error {
+	return e.write(newCoreProtectRecord(file, entry))
+}
+
+func (e *ndjsonEncoder) write(record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, "%s\n", data)
+	return err
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+// Columns of CSV exports
+var (
+	standardCSVHeader    = []string{"file", "line", "time", "timestamp", "date", "thread", "level", "logger", "message", "continuation"}
+	coreProtectCSVHeader = []string{"file", "user", "action", "message", "target", "amount", "x", "y", "z", "world",
+		"ago", "hours_ago", "event_time", "event_time_error_seconds", "logged_at", "session", "page", "raw_line"}
+)
+
+// csvEncoder writes entries as CSV rows below a header row
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvEncoder) WriteStandard(file string, entry logparser.LogEntry) error {
+	record := newStandardRecord(file, entry)
+	return e.write(standardCSVHeader, []string{
+		record.File,
+		strconv.Itoa(record.Line),
+		formatOptionalTime(record.Time),
+		record.Timestamp,
+		record.Date,
+		record.Thread,
+		record.Level,
+		record.Logger,
+		record.Message,
+		strings.Join(record.Continuation, "\n"),
+	})
+}
+
+func (e *csvEncoder) WriteCoreProtect(file string, entry coreprotectparser.CoreProtectLogEntry) error {
+	record := newCoreProtectRecord(file, entry)
+	var x, y, z, world string
+	if record.Location != nil {
+		x, y, z = strconv.Itoa(record.Location.X), strconv.Itoa(record.Location.Y), strconv.Itoa(record.Location.Z)
+		world = record.Location.World
+	}
+	var amount, page string
+	if record.Amount > 0 {
+		amount = strconv.Itoa(record.Amount)
+	}
+	if record.Page > 0 {
+		page = strconv.Itoa(record.Page)
+	}
+	return e.write(coreProtectCSVHeader, []string{
+		record.File,
+		record.User,
+		record.Action,
+		record.Message,
+		record.Target,
+		amount,
+		x, y, z, world,
+		record.Ago,
+		strconv.FormatFloat(record.HoursAgo, 'f', -1, 64),
+		formatOptionalTime(record.EventTime),
+		strconv.FormatFloat(record.EventTimeError, 'f', -1, 64),
+		formatOptionalTime(record.LoggedAt),
+		strconv.Itoa(record.Session),
+		page,
+		record.RawLine,
+	})
+}
+
+func (e *csvEncoder) write(header, row []string) error {
+	if !e.headerWritten {
+		if err := e.w.Write(header); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// formatOptionalTime formats a time for text based exports, or returns an empty string if it is unknown
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,176 @@
+package fileops
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/logparser"
+)
+
+// htmlHead starts the page, leaving the table header to be filled in. Styles
+// and script are inline so the file can be shared on its own.
+const htmlHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GoParseLogs export</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 1.5em; color: #212121; }
+#search { width: 100%%; max-width: 40em; padding: 0.4em; font-size: 1em; margin-bottom: 0.5em; }
+#count { color: #757575; margin-left: 0.5em; }
+table { border-collapse: collapse; width: 100%%; font-size: 0.9em; }
+th, td { text-align: left; padding: 0.2em 0.6em; vertical-align: top; border-bottom: 1px solid #eeeeee; }
+th { position: sticky; top: 0; background: #fafafa; }
+td.message { font-family: ui-monospace, monospace; white-space: pre-wrap; word-break: break-word; }
+tr.level-error td, tr.level-fatal td, tr.level-severe td { color: #c62828; }
+tr.level-warn td, tr.level-warning td { color: #ef6c00; }
+tr.level-debug td, tr.level-trace td { color: #757575; }
+tr.action-command td { color: #9e7c00; }
+tr.action-sign td { color: #8d6e63; }
+tr.action-place td { color: #2e7d32; }
+tr.action-break td { color: #c62828; }
+tr.action-click td { color: #1565c0; }
+tr.action-kill td { color: #ad1457; }
+tr.action-add td { color: #00897b; }
+tr.action-remove td { color: #ef6c00; }
+tr.action-drop td { color: #e65100; }
+tr.action-pickup td { color: #558b2f; }
+tr.action-login td { color: #00838f; }
+tr.action-logout td { color: #757575; }
+</style>
+</head>
+<body>
+<input id="search" type="search" placeholder="Search entries..." autofocus><span id="count"></span>
+<table>
+<thead><tr>%s</tr></thead>
+<tbody>
+`
+
+// htmlFoot ends the page, with the script behind the search box
+const htmlFoot = `</tbody>
+</table>
+<script>
+const search = document.getElementById("search");
+const count = document.getElementById("count");
+const rows = Array.from(document.querySelectorAll("tbody tr"));
+function update() {
+  const terms = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+  let shown = 0;
+  for (const row of rows) {
+    const text = row.textContent.toLowerCase();
+    const match = terms.every(term => text.includes(term));
+    row.style.display = match ? "" : "none";
+    if (match) shown++;
+  }
+  count.textContent = shown + " of " + rows.length + " entries";
+}
+search.addEventListener("input", update);
+update();
+</script>
+</body>
+</html>
+`
+
+// Columns of HTML exports
+var (
+	standardHTMLHeader    = []string{"File", "Line", "Time", "Level", "Thread", "Logger", "Message"}
+	coreProtectHTMLHeader = []string{"File", "Time", "Ago", "User", "Action", "Target", "Amount", "Location", "Message"}
+)
+
+// htmlEncoder writes entries as the rows of a table in a self-contained HTML page
+type htmlEncoder struct {
+	w       io.Writer
+	started bool
+}
+
+func (e *htmlEncoder) WriteStandard(file string, entry logparser.LogEntry) error {
+	if err := e.start(standardHTMLHeader); err != nil {
+		return err
+	}
+	record := newStandardRecord(file, entry)
+	message := record.Message
+	if len(record.Continuation) > 0 {
+		message += "\n" + strings.Join(record.Continuation, "\n")
+	}
+	when := record.Timestamp
+	if record.Time != nil {
+		when = record.Time.Format("2006-01-02 15:04:05")
+	}
+	return e.writeRow("level-"+strings.ToLower(record.Level), []string{
+		record.File,
+		fmt.Sprint(record.Line),
+		when,
+		record.Level,
+		record.Thread,
+		record.Logger,
+		message,
+	})
+}
+
+func (e *htmlEncoder) WriteCoreProtect(file string, entry coreprotectparser.CoreProtectLogEntry) error {
+	if err := e.start(coreProtectHTMLHeader); err != nil {
+		return err
+	}
+	record := newCoreProtectRecord(file, entry)
+	var amount, location string
+	if record.Amount > 0 {
+		amount = fmt.Sprint(record.Amount)
+	}
+	if entry.Location != nil {
+		location = entry.Location.String()
+	}
+	return e.writeRow("action-"+record.Action, []string{
+		record.File,
+		entry.EventTimeString(),
+		record.Ago,
+		record.User,
+		record.Action,
+		record.Target,
+		amount,
+		location,
+		record.Message,
+	})
+}
+
+// start writes the start of the page and the table header before the first row
+func (e *htmlEncoder) start(header []string) error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	var cells strings.Builder
+	for _, name := range header {
+		cells.WriteString("<th>" + html.EscapeString(name) + "</th>")
+	}
+	_, err := fmt.Fprintf(e.w, htmlHead, cells.String())
+	return err
+}
+
+// writeRow writes a table row, the last cell of which is the message
+func (e *htmlEncoder) writeRow(class string, cells []string) error {
+	var row strings.Builder
+	row.WriteString(`<tr class="` + html.EscapeString(class) + `">`)
+	for i, cell := range cells {
+		if i == len(cells)-1 {
+			row.WriteString(`<td class="message">`)
+		} else {
+			row.WriteString("<td>")
+		}
+		row.WriteString(html.EscapeString(cell) + "</td>")
+	}
+	row.WriteString("</tr>\n")
+	_, err := io.WriteString(e.w, row.String())
+	return err
+}
+
+func (e *htmlEncoder) Close() error {
+	if err := e.start(standardHTMLHeader); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, htmlFoot)
+	return err
+}
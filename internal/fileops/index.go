@@ -0,0 +1,741 @@
+package fileops
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goparselogs/pkg/events"
+	"goparselogs/pkg/logparser"
+)
+
+// indexBatchSize is the number of entries indexed before they are published to readers
+const indexBatchSize = 1000
+
+// seekGap is the distance to the next wanted entry beyond which the log is
+// seeked to rather than read through
+const seekGap = 64 * 1024
+
+// ErrLogRotated is returned by Refresh when the log file has been replaced or
+// truncated, as happens to latest.log when the server restarts.
+var ErrLogRotated = errors.New("log file was rotated")
+
+// errIndexCancelled stops reading a log once its index has been closed
+var errIndexCancelled = errors.New("index cancelled")
+
+// EntryIndex is an index of log entries that is built in the background and
+// read a window at a time. It is implemented by LogIndex for a single log and
+// Timeline for several logs merged together.
+type EntryIndex interface {
+	// Len returns the number of entries indexed so far.
+	Len() int
+	// Done reports whether the index has finished building, and any error that stopped it.
+	Done() (bool, error)
+	// Events returns the events extracted from the entries indexed so far.
+	Events() []events.Event
+	// ReadEntries parses the entries with indices in [start, end).
+	ReadEntries(start, end int) ([]logparser.LogEntry, error)
+	// Each calls fn for every indexed entry in order.
+	Each(fn func(i int, entry logparser.LogEntry) error) error
+	// Close stops building the index and releases its resources.
+	Close() error
+}
+
+var _ EntryIndex = (*LogIndex)(nil)
+
+// LogIndex is a byte-offset index of the entries of a log file that match a
+// filter. It is built in the background so that only a window of entries
+// around the cursor ever needs to be parsed into memory. Compressed logs are
+// decompressed once into a temporary file so that entries can be read by offset.
+type LogIndex struct {
+	Path string // The log file being indexed
+
+	filter   logparser.Filter
+	location *time.Location
+	cache    *IndexCache
+	terms    [][]string // Terms required by the filter, see SetCache
+
+	mu       sync.RWMutex
+	offsets  []int64 // Byte offset of each matching entry's header line
+	lines    []int32 // Line number of each matching entry's header line
+	times    []int64 // Unix nanoseconds of each matching entry, 0 if unknown
+	events   []events.Event
+	format   logparser.Format
+	seekPath string // File the offsets refer to
+	tempPath string // Decompressed copy of a compressed log, removed on Close
+	done     bool
+	err      error
+
+	cancelled atomic.Bool
+
+	// State used to pick up entries appended to the log, owned by the builder
+	// and then by Refresh
+	refreshMu   sync.Mutex
+	extractor   *events.Extractor
+	count       int         // Matching entries scanned so far, including unpublished ones
+	info        os.FileInfo // The file as it was when indexing started
+	readOffset  int64       // Number of bytes of the file read so far
+	tailOffset  int64       // Offset of the last entry scanned, which may still gain continuation lines
+	tailLine    int
+	tailTime    time.Time
+	tailIndexed bool         // Whether the last entry scanned matched the filter
+	recording   *cachedIndex // Every entry scanned while building, to be cached
+
+	// The log as Refresh first found it, kept open where the platform allows
+	// so that what was written to it before it was replaced can still be read.
+	// Set by Refresh, and read under mu once rotated.
+	followed *os.File
+	rotated  bool // Whether the log's path names another file now, entries then being read from followed
+}
+
+// indexBatch holds scanned entries waiting to be published to readers
+type indexBatch struct {
+	offsets []int64
+	lines   []int32
+	times   []int64
+	events  []events.Event
+}
+
+// NewLogIndex creates an index of the entries in filePath accepted by the filter.
+// Call Build (usually in a goroutine) to populate it and Close to release it.
+func NewLogIndex(filePath string, filter logparser.Filter, loc *time.Location) *LogIndex {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &LogIndex{
+		Path:      filePath,
+		filter:    filter,
+		location:  loc,
+		extractor: events.NewExtractor(),
+		tailLine:  1,
+	}
+}
+
+// SetCache makes the index reuse the index cached for the log, or cache the
+// one it builds. terms are the alternative sets of terms, all of one of which
+// an entry must contain to match the filter, as returned by
+// query.AnyRequiredTerms, so that the cached words can rule entries out
+// without reading them. Nil terms mean every entry must be read. It must be called before Build.
+func (ix *LogIndex) SetCache(cache *IndexCache, terms [][]string) {
+	ix.cache = cache
+	ix.terms = terms
+	if cache != nil {
+		cache.acquire(ix.Path)
+	}
+}
+
+// Start builds the index in a new goroutine.
+func (ix *LogIndex) Start() {
+	go ix.Build()
+}
+
+// Build reads the whole log, recording the position of every matching entry
+// and extracting events. Entries are published in batches so readers can use
+// the index while it is still being built.
+func (ix *LogIndex) Build() {
+	err := ix.build()
+
+	ix.mu.Lock()
+	ix.done = true
+	ix.err = err
+	ix.mu.Unlock()
+
+	if ix.cancelled.Load() {
+		ix.removeTemp()
+	}
+}
+
+func (ix *LogIndex) build() error {
+	parser, err := NewParserForFile(ix.Path, ix.location)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(diskPath(ix.Path))
+	if err != nil {
+		return fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+	}
+	ix.info = info
+
+	if ix.cache != nil {
+		key := newCacheKey(ix.Path, info, ix.location)
+		if cached, dataPath, ok := ix.cache.load(ix.Path, key); ok {
+			return ix.buildFromCache(cached, dataPath)
+		}
+		// Without a filter, the index's own entries and events are those of every entry
+		ix.recording = newCachedIndex(key, ix.filter != nil)
+		defer func() { ix.recording = nil }()
+	}
+
+	reader, err := OpenLogFile(ix.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+	}
+	defer reader.Close()
+
+	var source io.Reader = reader
+	var spool *bufio.Writer
+	seekPath := ix.Path
+	if isCompressed(ix.Path) {
+		// Keep a decompressed copy so entries can be read back by offset
+		var tempFile *os.File
+		if ix.cache != nil {
+			// Created next to the cache so it can be linked into it once indexed
+			tempFile, err = ix.cache.createData()
+		} else {
+			tempFile, err = os.CreateTemp("", "goparselogs-*.log")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer tempFile.Close()
+		ix.mu.Lock()
+		ix.tempPath = tempFile.Name()
+		ix.mu.Unlock()
+
+		spool = bufio.NewWriter(tempFile)
+		source = io.TeeReader(reader, spool)
+		seekPath = tempFile.Name()
+	}
+
+	ix.mu.Lock()
+	ix.seekPath = seekPath
+	ix.mu.Unlock()
+
+	scanner := parser.NewEntryScanner(source)
+	err = ix.scan(scanner, indexBatchSize, func(batch *indexBatch) error {
+		if spool != nil {
+			// Entries must be readable from the spooled copy before they are published
+			if err := spool.Flush(); err != nil {
+				return fmt.Errorf("failed to write temporary file: %w", err)
+			}
+		}
+		ix.mu.Lock()
+		if ix.format == nil {
+			ix.format = scanner.Format()
+		}
+		ix.append(batch)
+		ix.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Midnight rollovers are only known at the end when dating back from the file's mtime
+	if days := scanner.RolloverCorrection(); days > 0 {
+		ix.mu.Lock()
+		for i, nanos := range ix.times {
+			if nanos != 0 {
+				ix.times[i] = logparser.ShiftDays(time.Unix(0, nanos).In(ix.location), -days).UnixNano()
+			}
+		}
+		for i := range ix.events {
+			ix.events[i].Entry.Time = logparser.ShiftDays(ix.events[i].Entry.Time, -days)
+		}
+		ix.mu.Unlock()
+		ix.tailTime = logparser.ShiftDays(ix.tailTime, -days)
+
+		if record := ix.recording; record != nil {
+			for i, nanos := range record.Times {
+				if nanos != 0 {
+					record.Times[i] = logparser.ShiftDays(time.Unix(0, nanos).In(ix.location), -days).UnixNano()
+				}
+			}
+			for i := range record.Events {
+				record.Events[i].Entry.Time = logparser.ShiftDays(record.Events[i].Entry.Time, -days)
+			}
+		}
+	}
+
+	if ix.recording != nil && !ix.cancelled.Load() {
+		ix.storeCache(scanner.Format())
+	}
+	return nil
+}
+
+// storeCache saves the index recorded while building to the cache. Caching is
+// only an optimisation, so the index is simply not cached if it fails.
+func (ix *LogIndex) storeCache(format logparser.Format) {
+	if format == nil {
+		return
+	}
+	record := ix.recording
+	record.Format = format.Name()
+	record.ReadOffset = ix.readOffset
+
+	ix.mu.RLock()
+	tempPath := ix.tempPath
+	if !record.filtered {
+		record.Offsets, record.Lines, record.Times = ix.offsets, ix.lines, ix.times
+		record.Events = ix.events
+	}
+	ix.mu.RUnlock()
+	if tempPath != "" {
+		// Keep the decompressed copy so the log needn't be decompressed again
+		if err := ix.cache.linkData(ix.Path, tempPath); err != nil {
+			return
+		}
+	}
+	ix.cache.store(ix.Path, record)
+}
+
+// buildFromCache fills the index from a cached index of the whole log, whose
+// offsets refer to dataPath. Only entries containing the filter's terms are
+// read to check them against the filter, and none are read without a filter.
+func (ix *LogIndex) buildFromCache(cached *cachedIndex, dataPath string) error {
+	format, _ := logparser.FormatByName(cached.Format)
+	ix.mu.Lock()
+	ix.format = format
+	ix.seekPath = dataPath
+	ix.mu.Unlock()
+
+	var candidates []int32
+	ok := false
+	if ix.filter != nil && len(ix.terms) > 0 && cached.HasWords {
+		if words, found := ix.cache.loadWords(ix.Path, cached.Key); found {
+			candidates, ok = words.candidates(ix.terms)
+		}
+	}
+	if !ok {
+		candidates = make([]int32, len(cached.Offsets))
+		for i := range candidates {
+			candidates[i] = int32(i)
+		}
+	}
+
+	positions := make([]int32, len(cached.Offsets)) // Position of each cached entry in the index, -1 if excluded
+	for i := range positions {
+		positions[i] = -1
+	}
+	batch := &indexBatch{}
+	add := func(p int32) {
+		positions[p] = int32(ix.count)
+		batch.offsets = append(batch.offsets, cached.Offsets[p])
+		batch.lines = append(batch.lines, cached.Lines[p])
+		batch.times = append(batch.times, cached.Times[p])
+		ix.count++
+		if len(batch.offsets) >= indexBatchSize {
+			ix.mu.Lock()
+			ix.append(batch)
+			ix.mu.Unlock()
+			batch = &indexBatch{}
+		}
+	}
+
+	if ix.filter == nil && !ok {
+		for _, p := range candidates {
+			add(p)
+		}
+	} else {
+		file, err := os.Open(dataPath)
+		if err != nil {
+			return fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+		}
+		defer file.Close()
+
+		offsets := make([]int64, len(candidates))
+		lines := make([]int32, len(candidates))
+		for j, p := range candidates {
+			offsets[j], lines[j] = cached.Offsets[p], cached.Lines[p]
+		}
+		err = ix.readIndexed(file, format, offsets, lines, func(j int, entry logparser.LogEntry) error {
+			if ix.cancelled.Load() {
+				return errIndexCancelled
+			}
+			p := candidates[j]
+			if cached.Times[p] != 0 {
+				entry.Time = time.Unix(0, cached.Times[p]).In(ix.location)
+			}
+			entry.Source = ix.Path
+			if ix.filter == nil || ix.filter(entry) {
+				add(p)
+			}
+			return nil
+		})
+		if errors.Is(err, errIndexCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, event := range cached.Events {
+		if position := positions[event.EntryIndex]; position >= 0 {
+			event.EntryIndex = int(position)
+			batch.events = append(batch.events, event)
+		}
+	}
+	ix.mu.Lock()
+	ix.append(batch)
+	ix.mu.Unlock()
+
+	// Follow the log from the end of the cached index
+	ix.readOffset = cached.ReadOffset
+	if last := len(cached.Offsets) - 1; last >= 0 {
+		ix.tailOffset, ix.tailLine = cached.Offsets[last], int(cached.Lines[last])
+		if cached.Times[last] != 0 {
+			ix.tailTime = time.Unix(0, cached.Times[last]).In(ix.location)
+		}
+		ix.tailIndexed = positions[last] >= 0
+	}
+	return nil
+}
+
+// scan indexes the entries read by the scanner, calling publish whenever
+// batchSize entries are waiting, or only once at the end if batchSize is 0
+func (ix *LogIndex) scan(scanner *logparser.EntryScanner, batchSize int, publish func(*indexBatch) error) error {
+	batch := &indexBatch{}
+	for scanner.Scan() {
+		if ix.cancelled.Load() {
+			return nil
+		}
+		entry := scanner.Entry()
+		entry.Source = ix.Path
+		if ix.recording != nil {
+			ix.recording.add(entry)
+		}
+		ix.tailOffset, ix.tailLine, ix.tailTime = entry.Offset, entry.LineNumber, entry.Time
+		ix.tailIndexed = ix.filter == nil || ix.filter(entry)
+		if !ix.tailIndexed {
+			continue
+		}
+
+		batch.offsets = append(batch.offsets, entry.Offset)
+		batch.lines = append(batch.lines, int32(entry.LineNumber))
+		batch.times = append(batch.times, unixNanos(entry.Time))
+		if event, ok := ix.extractor.Add(entry, ix.count); ok {
+			// Continuation lines are read back from the log when needed
+			event.Entry.Continuation = nil
+			batch.events = append(batch.events, event)
+		}
+		ix.count++
+
+		if batchSize > 0 && len(batch.offsets) >= batchSize {
+			if err := publish(batch); err != nil {
+				return err
+			}
+			batch = &indexBatch{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to parse log content from %s: %w", ix.Path, err)
+	}
+	ix.readOffset = scanner.Offset()
+	return publish(batch)
+}
+
+// append adds a batch of scanned entries to the index. The caller must hold mu.
+func (ix *LogIndex) append(batch *indexBatch) {
+	ix.offsets = append(ix.offsets, batch.offsets...)
+	ix.lines = append(ix.lines, batch.lines...)
+	ix.times = append(ix.times, batch.times...)
+	ix.events = append(ix.events, batch.events...)
+}
+
+// Refresh indexes entries appended to the log since it was last read, so that
+// a log still being written can be followed. It returns the index of the first
+// entry that was added or changed, which is Len() if there was nothing new.
+// The last entry is re-read each time since more continuation lines may have
+// been appended to it. ErrLogRotated is returned if the file has been replaced,
+// in which case a new index must be built. Whatever was written to the old
+// file before it was replaced is indexed first where the platform lets it be
+// read after being moved. Compressed logs never change.
+func (ix *LogIndex) Refresh() (int, error) {
+	ix.refreshMu.Lock()
+	defer ix.refreshMu.Unlock()
+
+	ix.mu.RLock()
+	done, buildErr, count, rotated := ix.done, ix.err, len(ix.offsets), ix.rotated
+	ix.mu.RUnlock()
+	if !done || buildErr != nil || ix.cancelled.Load() || isCompressed(ix.Path) {
+		return count, nil
+	}
+	if rotated {
+		return count, ErrLogRotated
+	}
+
+	file := ix.followed
+	if file == nil {
+		var err error
+		file, err = os.Open(ix.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return count, nil // Being rotated, the new file will appear shortly
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+		}
+		if keepFollowedOpen {
+			ix.followed = file
+		} else {
+			defer file.Close()
+		}
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return count, fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+	}
+	if !os.SameFile(info, ix.info) || info.Size() < ix.readOffset {
+		ix.closeFollowed()
+		return count, ErrLogRotated
+	}
+
+	// Once another file takes the log's place, the rest of this one is read before starting over
+	if current, err := os.Stat(ix.Path); err == nil && !os.SameFile(current, info) {
+		rotated = true
+	}
+	from := count
+	if info.Size() > ix.readOffset {
+		if from, err = ix.readAppended(file); err != nil {
+			return from, err
+		}
+	}
+	if rotated {
+		if ix.followed != nil {
+			// Entries are read from the file they were found in from now on
+			ix.mu.Lock()
+			ix.rotated = true
+			ix.mu.Unlock()
+		}
+		return from, ErrLogRotated
+	}
+	return from, nil
+}
+
+// closeFollowed closes the log kept open by Refresh, if any
+func (ix *LogIndex) closeFollowed() {
+	if ix.followed == nil {
+		return
+	}
+	ix.mu.Lock()
+	ix.followed.Close()
+	ix.followed = nil
+	ix.rotated = false
+	ix.mu.Unlock()
+}
+
+// readAppended indexes the entries of file from the last entry scanned on,
+// returning the index of the first entry added or changed
+func (ix *LogIndex) readAppended(file *os.File) (int, error) {
+	ix.mu.RLock()
+	count, format := len(ix.offsets), ix.format
+	ix.mu.RUnlock()
+	if _, err := file.Seek(ix.tailOffset, io.SeekStart); err != nil {
+		return count, fmt.Errorf("failed to seek in log file %s: %w", ix.Path, err)
+	}
+
+	parser, err := logparser.NewParserWithFormat(format)
+	if err != nil {
+		return count, err
+	}
+	parser.SetLocation(ix.location)
+	if !ix.tailTime.IsZero() {
+		// Continue dating entries from the day of the entry being re-read
+		parser.SetStartDate(ix.tailTime)
+	}
+	scanner := parser.NewEntryScanner(file)
+	scanner.SetPosition(ix.tailOffset, ix.tailLine)
+
+	from := count
+	if ix.tailIndexed && count > 0 {
+		from = count - 1
+	}
+	ix.count = from
+
+	err = ix.scan(scanner, 0, func(batch *indexBatch) error {
+		ix.mu.Lock()
+		defer ix.mu.Unlock()
+		// Replace the previously last entry with its re-read version
+		ix.offsets = ix.offsets[:from]
+		ix.lines = ix.lines[:from]
+		ix.times = ix.times[:from]
+		for len(ix.events) > 0 && ix.events[len(ix.events)-1].EntryIndex >= from {
+			ix.events = ix.events[:len(ix.events)-1]
+		}
+		ix.append(batch)
+		return nil
+	})
+	if err != nil {
+		return from, err
+	}
+	return from, nil
+}
+
+// Len returns the number of matching entries indexed so far.
+func (ix *LogIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.offsets)
+}
+
+// Done reports whether the index has finished building, and any error that stopped it.
+func (ix *LogIndex) Done() (bool, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.done, ix.err
+}
+
+// Events returns the events extracted from the entries indexed so far. The
+// events' entries do not include continuation lines.
+func (ix *LogIndex) Events() []events.Event {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return append([]events.Event(nil), ix.events...)
+}
+
+// ReadEntries parses the matching entries with indices in [start, end).
+func (ix *LogIndex) ReadEntries(start, end int) ([]logparser.LogEntry, error) {
+	var entries []logparser.LogEntry
+	err := ix.scanRange(start, end, func(_ int, entry logparser.LogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// Each calls fn for every indexed entry in order, reading the log sequentially
+// so that only one entry is held in memory at a time.
+func (ix *LogIndex) Each(fn func(i int, entry logparser.LogEntry) error) error {
+	return ix.scanRange(0, ix.Len(), fn)
+}
+
+// scanRange parses the log from the offset of entry start, calling fn for each
+// indexed entry until entry end is reached.
+func (ix *LogIndex) scanRange(start, end int, fn func(i int, entry logparser.LogEntry) error) error {
+	ix.mu.RLock()
+	if start < 0 {
+		start = 0
+	}
+	if end > len(ix.offsets) {
+		end = len(ix.offsets)
+	}
+	if start >= end {
+		ix.mu.RUnlock()
+		return nil
+	}
+	offsets := ix.offsets[start:end:end]
+	lines := ix.lines[start:end:end]
+	// Times are corrected in place once building finishes, so take a copy
+	times := append([]int64(nil), ix.times[start:end]...)
+	format := ix.format
+	seekPath := ix.seekPath
+	var file io.ReadSeeker
+	if ix.rotated {
+		// The path names another file now, so the one indexed is read through the handle kept to it
+		file = io.NewSectionReader(ix.followed, 0, math.MaxInt64)
+	}
+	ix.mu.RUnlock()
+
+	if file == nil {
+		opened, err := os.Open(seekPath)
+		if err != nil {
+			return fmt.Errorf("failed to read log file %s: %w", ix.Path, err)
+		}
+		defer opened.Close()
+		file = opened
+	}
+
+	return ix.readIndexed(file, format, offsets, lines, func(j int, entry logparser.LogEntry) error {
+		if times[j] != 0 {
+			entry.Time = time.Unix(0, times[j]).In(ix.location)
+		}
+		entry.Source = ix.Path
+		return fn(start+j, entry)
+	})
+}
+
+// readIndexed parses the entries at the given offsets in order, calling fn
+// with the position of each in offsets. Entries close together are read
+// through, and the file is seeked to entries further apart.
+func (ix *LogIndex) readIndexed(file io.ReadSeeker, format logparser.Format, offsets []int64, lines []int32, fn func(j int, entry logparser.LogEntry) error) error {
+	parser, err := logparser.NewParserWithFormat(format)
+	if err != nil {
+		return err
+	}
+
+	var scanner *logparser.EntryScanner
+	var last int64
+	for j, offset := range offsets {
+		if scanner == nil || offset-last > seekGap {
+			if _, err := file.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to seek in log file %s: %w", ix.Path, err)
+			}
+			scanner = parser.NewEntryScanner(file)
+			scanner.SetPosition(offset, int(lines[j]))
+		}
+
+		for {
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return fmt.Errorf("failed to parse log content from %s: %w", ix.Path, err)
+				}
+				return nil
+			}
+			entry := scanner.Entry()
+			last = entry.Offset
+			if entry.Offset < offset {
+				continue // Entry excluded by the filter
+			}
+			if entry.Offset > offset {
+				return fmt.Errorf("log file %s changed since it was indexed", ix.Path)
+			}
+			if err := fn(j, entry); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Close stops building the index and removes any temporary files.
+func (ix *LogIndex) Close() error {
+	if !ix.cancelled.Swap(true) && ix.cache != nil {
+		ix.cache.release(ix.Path)
+	}
+	ix.refreshMu.Lock()
+	ix.closeFollowed()
+	ix.refreshMu.Unlock()
+	ix.mu.RLock()
+	done := ix.done
+	ix.mu.RUnlock()
+	if done {
+		return ix.removeTemp()
+	}
+	// The builder removes the temporary file itself once it notices the cancellation
+	return nil
+}
+
+func (ix *LogIndex) removeTemp() error {
+	ix.mu.Lock()
+	tempPath := ix.tempPath
+	ix.tempPath = ""
+	ix.mu.Unlock()
+	if tempPath == "" {
+		return nil
+	}
+	return os.Remove(tempPath)
+}
+
+// isCompressed reports whether the file must be decompressed or extracted to be read
+func isCompressed(filePath string) bool {
+	if _, _, ok := SplitArchivePath(filePath); ok {
+		return true
+	}
+	return trimCompression(filePath) != filePath
+}
+
+func unixNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
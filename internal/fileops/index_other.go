@@ -0,0 +1,7 @@
+//go:build !windows
+
+package fileops
+
+// keepFollowedOpen is whether Refresh keeps the log open between calls, so
+// that what was written to it before it was rotated can still be read
+const keepFollowedOpen = true
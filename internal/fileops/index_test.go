@@ -0,0 +1,227 @@
+package fileops
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const indexTestLog = "[12:00:00] [Server thread/INFO]: Starting minecraft server\n" +
+	"[12:00:01] [Server thread/WARN]: Can't keep up!\n" +
+	"java.lang.Exception: slow\n" +
+	"\tat Example.run(Example.java:1)\n" +
+	"[12:00:02] [Server thread/INFO]: Steve joined the game\n" +
+	"[12:00:03] [Server thread/INFO]: Done\n"
+
+// writeTestLog writes a log file into dir and returns its path
+func writeTestLog(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// appendTestLog appends to a log file as a server writing to it would
+func appendTestLog(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	_, err = file.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+}
+
+func buildTestIndex(t *testing.T, path string, filter logparser.Filter) *LogIndex {
+	t.Helper()
+	ix := NewLogIndex(path, filter, time.UTC)
+	ix.Build()
+	t.Cleanup(func() { ix.Close() })
+	done, err := ix.Done()
+	assert.True(t, done)
+	assert.NoError(t, err)
+	return ix
+}
+
+func TestLogIndex_BuildRecordsOffsets(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, nil)
+
+	assert.Equal(t, 4, ix.Len())
+	assert.Equal(t, []int64{
+		0,
+		int64(strings.Index(indexTestLog, "[12:00:01]")),
+		int64(strings.Index(indexTestLog, "[12:00:02]")),
+		int64(strings.Index(indexTestLog, "[12:00:03]")),
+	}, ix.offsets)
+	assert.Equal(t, []int32{1, 2, 5, 6}, ix.lines)
+
+	// A window in the middle is read back with its stack trace
+	entries, err := ix.ReadEntries(1, 3)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "Can't keep up!", entries[0].Message)
+	assert.Equal(t, []string{"java.lang.Exception: slow", "\tat Example.run(Example.java:1)"}, entries[0].Continuation)
+	assert.Equal(t, 2, entries[0].LineNumber)
+	assert.Equal(t, path, entries[0].Source)
+	assert.Equal(t, "Steve joined the game", entries[1].Message)
+
+	// Windows are clamped to the entries indexed
+	entries, err = ix.ReadEntries(3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Done", entries[0].Message)
+	entries, err = ix.ReadEntries(5, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestLogIndex_BuildWithFilter(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, func(entry logparser.LogEntry) bool {
+		return entry.Level == "INFO"
+	})
+
+	assert.Equal(t, 3, ix.Len())
+	entries, err := ix.ReadEntries(0, ix.Len())
+	assert.NoError(t, err)
+	var messages []string
+	for _, entry := range entries {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"Starting minecraft server", "Steve joined the game", "Done"}, messages)
+	assert.Equal(t, 5, entries[1].LineNumber)
+}
+
+func TestLogIndex_ReadEntriesSeeksPastGaps(t *testing.T) {
+	// Entries far apart are seeked to rather than read through
+	var log strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&log, "[12:00:00] [Server thread/INFO]: Entry %d %s\n", i, strings.Repeat("x", 1000))
+	}
+	path := writeTestLog(t, t.TempDir(), "latest.log", log.String())
+	ix := buildTestIndex(t, path, func(entry logparser.LogEntry) bool {
+		return strings.HasPrefix(entry.Message, "Entry 1 ") || strings.HasPrefix(entry.Message, "Entry 2 ") ||
+			strings.HasPrefix(entry.Message, "Entry 150 ")
+	})
+
+	entries, err := ix.ReadEntries(0, ix.Len())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.True(t, strings.HasPrefix(entries[0].Message, "Entry 1 "))
+	assert.True(t, strings.HasPrefix(entries[1].Message, "Entry 2 "))
+	assert.True(t, strings.HasPrefix(entries[2].Message, "Entry 150 "))
+	assert.Equal(t, 151, entries[2].LineNumber)
+}
+
+func TestLogIndex_RefreshPicksUpAppendedEntries(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, nil)
+
+	// Nothing new
+	from, err := ix.Refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, from)
+
+	// More continuation lines for the last entry, then a new entry
+	appendTestLog(t, path, "\tat Example.done(Example.java:2)\n[12:00:04] [Server thread/INFO]: Alex joined the game\n")
+	from, err = ix.Refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, from, "the last entry is re-read")
+	assert.Equal(t, 5, ix.Len())
+
+	entries, err := ix.ReadEntries(3, 5)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"\tat Example.done(Example.java:2)"}, entries[0].Continuation)
+	assert.Equal(t, "Alex joined the game", entries[1].Message)
+	assert.Equal(t, 8, entries[1].LineNumber)
+}
+
+func TestLogIndex_RefreshWithFilter(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, func(entry logparser.LogEntry) bool {
+		return strings.Contains(entry.Message, "joined")
+	})
+	assert.Equal(t, 1, ix.Len())
+
+	// The last entry scanned wasn't indexed, so nothing is replaced
+	appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Alex joined the game\n[12:00:05] [Server thread/INFO]: Saving\n")
+	from, err := ix.Refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, 2, ix.Len())
+
+	entries, err := ix.ReadEntries(0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alex joined the game", entries[1].Message)
+}
+
+func TestLogIndex_RefreshDetectsTruncation(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, nil)
+
+	assert.NoError(t, os.WriteFile(path, []byte("[13:00:00] [Server thread/INFO]: Restarted\n"), 0o644))
+	_, err := ix.Refresh()
+	assert.ErrorIs(t, err, ErrLogRotated)
+}
+
+func TestLogIndex_RefreshDetectsRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestLog(t, dir, "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, nil)
+	from, err := ix.Refresh()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, from)
+
+	// Entries written just before the log is moved aside are still read
+	appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Stopping server\n")
+	rotated := filepath.Join(dir, "2024-05-01-1.log")
+	assert.NoError(t, os.Rename(path, rotated))
+	appendTestLog(t, rotated, "[12:00:05] [Server thread/INFO]: Saving worlds\n")
+
+	// While the log is being rotated there is no new file to start over with
+	from, err = ix.Refresh()
+	assert.NoError(t, err)
+	if keepFollowedOpen {
+		assert.Equal(t, 3, from)
+		assert.Equal(t, 6, ix.Len())
+	}
+
+	// A new file, even a longer one, is a new log, once the old one has been read to the end
+	appendTestLog(t, rotated, "[12:00:06] [Server thread/INFO]: Closing server\n")
+	writeTestLog(t, dir, "latest.log", indexTestLog+indexTestLog)
+	from, err = ix.Refresh()
+	assert.ErrorIs(t, err, ErrLogRotated)
+	if keepFollowedOpen {
+		assert.Equal(t, 5, from)
+		assert.Equal(t, 7, ix.Len())
+		entries, err := ix.ReadEntries(4, 7)
+		assert.NoError(t, err)
+		assert.Equal(t, "Stopping server", entries[0].Message)
+		assert.Equal(t, "Closing server", entries[2].Message)
+	}
+	_, err = ix.Refresh()
+	assert.ErrorIs(t, err, ErrLogRotated, "still replaced")
+}
+
+func TestLogIndex_Each(t *testing.T) {
+	path := writeTestLog(t, t.TempDir(), "latest.log", indexTestLog)
+	ix := buildTestIndex(t, path, nil)
+
+	var indices []int
+	var lines []int
+	assert.NoError(t, ix.Each(func(i int, entry logparser.LogEntry) error {
+		indices = append(indices, i)
+		lines = append(lines, entry.LineNumber)
+		return nil
+	}))
+	assert.Equal(t, []int{0, 1, 2, 3}, indices)
+	assert.Equal(t, []int{1, 2, 5, 6}, lines)
+}
@@ -0,0 +1,5 @@
+package fileops
+
+// keepFollowedOpen is false since a file open on Windows can't be renamed,
+// which would stop the server rotating the log being followed
+const keepFollowedOpen = false
@@ -0,0 +1,50 @@
+package fileops
+
+import (
+	"fmt"
+	"time"
+
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/logparser"
+)
+
+// NewParserForFile creates a log parser whose entries are dated using the file:
+// the date in a rotated filename (e.g. 2024-05-01-3.log.gz) for the first entry,
+// or otherwise the file's modification time for the last entry.
+func NewParserForFile(filePath string, loc *time.Location) (*logparser.Parser, error) {
+	parser, err := logparser.NewParser()
+	if err != nil {
+		return nil, err
+	}
+	parser.SetLocation(loc)
+
+	if date, ok := logparser.DateFromFilename(filePath, parser.Location()); ok {
+		parser.SetStartDate(date)
+	} else if info, err := StatLogFile(filePath); err == nil {
+		parser.SetEndDate(info.ModTime())
+	}
+
+	return parser, nil
+}
+
+// ReadCoreProtectLog parses the CoreProtect lookup results in a client log,
+// keeping the entries accepted by filter if it is not nil
+func ReadCoreProtectLog(filePath string, filter coreprotectparser.Filter, loc *time.Location) (*coreprotectparser.ParsedLog, error) {
+	// The log file's date is needed to work out when each action happened
+	parser, err := NewParserForFile(filePath, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := OpenLogFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CoreProtect log file %s: %w", filePath, err)
+	}
+	defer reader.Close()
+
+	cpLog, err := coreprotectparser.ParseReader(reader, parser, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CoreProtect log file %s: %w", filePath, err)
+	}
+	return cpLog, nil
+}
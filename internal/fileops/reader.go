@@ -1,31 +1,36 @@
 package fileops
 
 import (
-	"compress/gzip"
 	"io"
 	"os"
-	"strings"
 )
 
-// ReadFileContent reads the content of a file, automatically handling gzip compression if needed
-func ReadFileContent(filePath string) (string, error) {
+// OpenLogFile opens a log file for reading, decompressing it as its
+// extensions say. Logs inside archives are read through their virtual path,
+// such as backup.zip!/logs/2024-05-01-1.log.gz.
+func OpenLogFile(filePath string) (io.ReadCloser, error) {
+	if archive, member, ok := SplitArchivePath(filePath); ok {
+		contents, err := openArchiveMember(archive, member)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(contents, member, contents)
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer file.Close()
-
-	var reader io.Reader = file
+	return decompress(file, filePath, file)
+}
 
-	// If file ends with .gz, use gzip reader
-	if strings.HasSuffix(strings.ToLower(filePath), ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			return "", err
-		}
-		defer gzReader.Close()
-		reader = gzReader
+// ReadFileContent reads the content of a log file, decompressing it if needed
+func ReadFileContent(filePath string) (string, error) {
+	reader, err := OpenLogFile(filePath)
+	if err != nil {
+		return "", err
 	}
+	defer reader.Close()
 
 	content, err := io.ReadAll(reader)
 	if err != nil {
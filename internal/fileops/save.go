@@ -1,66 +1,207 @@
 package fileops
 
 import (
+	"bufio"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"goparselogs/pkg/coreprotectparser"
 	"goparselogs/pkg/logparser"
 )
 
-// SaveStandardLogsToFile writes the provided standard log entries to a file.
-func SaveStandardLogsToFile(entries []logparser.LogEntry, filename string) error {
+// ExportOptions describe the file an export is written to.
+type ExportOptions struct {
+	OutputDir string       // Directory Path must stay within, DefaultOutputDir if empty
+	Path      string       // File to write, from ResolveOutputPath; compressed with gzip if it ends in GzipExtension
+	Format    ExportFormat // Format of the entries
+	Overwrite bool         // Replace the file if it exists, rather than failing with an error wrapping fs.ErrExist
+}
+
+// SaveStandardLogsToFile writes the provided standard log entries to a file in the given format.
+func SaveStandardLogsToFile(entries []logparser.LogEntry, opts ExportOptions) error {
 	if len(entries) == 0 {
 		return fmt.Errorf("no entries to save")
 	}
 
-	var contentBuilder strings.Builder
-	for _, entry := range entries {
-		contentBuilder.WriteString(fmt.Sprintf("[%s] [%s/%s]: %s\n", entry.Timestamp, entry.Thread, entry.Level, entry.Message))
-	}
-
-	// Create the output directory if it doesn't exist
-	outputDir := "output"
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		err = os.Mkdir(outputDir, 0755)
-		if err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+	return writeOutputFile(opts, func(encoder EntryEncoder) error {
+		for _, entry := range entries {
+			if err := encoder.WriteStandard("", entry); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+// SaveIndexedLogsToFile writes every entry of the index to a file in the given
+// format, reading the log sequentially so that large logs are never held in memory.
+func SaveIndexedLogsToFile(index EntryIndex, opts ExportOptions) error {
+	if index == nil || index.Len() == 0 {
+		return fmt.Errorf("no entries to save")
 	}
 
-	filePath := fmt.Sprintf("%s/%s", outputDir, filename)
-	err := os.WriteFile(filePath, []byte(contentBuilder.String()), 0644)
+	return writeOutputFile(opts, func(encoder EntryEncoder) error {
+		return index.Each(func(_ int, entry logparser.LogEntry) error {
+			return encoder.WriteStandard(entry.Source, entry)
+		})
+	})
+}
+
+// writeStandardEntry writes an entry as it appeared in the original log
+func writeStandardEntry(w io.Writer, entry logparser.LogEntry) error {
+	var err error
+	if entry.RawLine != "" {
+		_, err = fmt.Fprintln(w, entry.RawLine)
+	} else {
+		_, err = fmt.Fprintf(w, "[%s] [%s/%s]: %s\n", entry.Timestamp, entry.Thread, entry.Level, entry.Message)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+		return err
+	}
+	// Write attached stack traces and other continuation lines back out verbatim
+	for _, line := range entry.Continuation {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
 	}
-
 	return nil
 }
 
-// SaveCoreProtectLogsToFile writes the provided CoreProtect log entries to a file.
-func SaveCoreProtectLogsToFile(entries []coreprotectparser.CoreProtectLogEntry, filename string) error {
+// SaveCoreProtectLogsToFile writes the provided CoreProtect log entries to a file in the given format.
+func SaveCoreProtectLogsToFile(entries []coreprotectparser.CoreProtectLogEntry, opts ExportOptions) error {
 	if len(entries) == 0 {
 		return fmt.Errorf("no CoreProtect entries to save")
 	}
 
-	var contentBuilder strings.Builder
-	for _, entry := range entries {
-		contentBuilder.WriteString(entry.RawLine + "\n")
+	return writeOutputFile(opts, func(encoder EntryEncoder) error {
+		for _, entry := range entries {
+			if err := encoder.WriteCoreProtect("", entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeOutputFile creates the export file, and any directories leading to it
+// in the output directory, and fills it in the given format using write
+func writeOutputFile(opts ExportOptions, write func(encoder EntryEncoder) error) error {
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = DefaultOutputDir
+	}
+	filePath := opts.Path
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := checkWithinDir(outputDir, filepath.Dir(filePath)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Overwriting through a symbolic link would write wherever it points
+	if info, err := os.Lstat(filePath); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symbolic link: %w", filePath, ErrUnsafeFilename)
 	}
 
-	outputDir := "output"
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		err = os.Mkdir(outputDir, 0755)
-		if err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+	// An existing file is only replaced once the export has been written in
+	// full, so that a failed export leaves it as it was
+	var file *os.File
+	var err error
+	if opts.Overwrite {
+		file, err = os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+"-*.tmp")
+		if err == nil {
+			err = file.Chmod(0644)
+		}
+	} else {
+		file, err = os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s: %w", filePath, fs.ErrExist)
+		}
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
 		}
+		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+	}
+	written := false
+	defer func() {
+		file.Close()
+		if !written {
+			os.Remove(file.Name())
+		}
+	}()
+
+	writer := bufio.NewWriter(file)
+	var out io.Writer = writer
+	var compressor *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(filePath), GzipExtension) {
+		compressor = gzip.NewWriter(writer)
+		compressor.Name = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+		out = compressor
 	}
 
-	filePath := fmt.Sprintf("%s/%s", outputDir, filename)
-	err := os.WriteFile(filePath, []byte(contentBuilder.String()), 0644)
+	encoder, err := NewEntryEncoder(out, opts.Format)
 	if err != nil {
+		return err
+	}
+	if err := write(encoder); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+	}
+	if err := file.Close(); err != nil {
 		return fmt.Errorf("failed to write to file %s: %w", filePath, err)
 	}
+	if opts.Overwrite {
+		if err := os.Rename(file.Name(), filePath); err != nil {
+			return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+		}
+	}
+	written = true
+	return nil
+}
+
+// checkWithinDir returns ErrUnsafeFilename if dir, once symbolic links are
+// followed, is not inside root. Directories that don't exist yet are judged by
+// their nearest existing parent.
+func checkWithinDir(root, dir string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil || existing == root {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	resolvedDir, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	rel, err := filepath.Rel(resolvedRoot, resolvedDir)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return fmt.Errorf("%s: %w", dir, ErrUnsafeFilename)
+	}
 	return nil
 }
@@ -0,0 +1,127 @@
+package fileops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var saveTestEntries = []logparser.LogEntry{{RawLine: "[12:00:00] [Server thread/INFO]: Done"}}
+
+func TestCheckWithinDir(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "reports"), 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "reports"), filepath.Join(root, "inside")))
+
+	tests := []struct {
+		name string
+		dir  string
+		safe bool
+	}{
+		{"root", root, true},
+		{"subdirectory", filepath.Join(root, "reports"), true},
+		{"not created yet", filepath.Join(root, "reports", "2024", "may"), true},
+		{"link within root", filepath.Join(root, "inside"), true},
+		{"link out of root", filepath.Join(root, "escape"), false},
+		{"below a link out of root", filepath.Join(root, "escape", "new"), false},
+		{"outside", outside, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWithinDir(root, tt.dir)
+			if tt.safe {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrUnsafeFilename)
+			}
+		})
+	}
+}
+
+func TestSaveStandardLogsToFile(t *testing.T) {
+	outputDir := t.TempDir()
+	path, err := ResolveOutputPath(outputDir, "2024/export.txt")
+	require.NoError(t, err)
+	opts := ExportOptions{OutputDir: outputDir, Path: path, Format: FormatText}
+
+	require.NoError(t, SaveStandardLogsToFile(saveTestEntries, opts))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[12:00:00] [Server thread/INFO]: Done\n", string(data))
+
+	// Existing files are only replaced when asked to
+	assert.ErrorIs(t, SaveStandardLogsToFile(saveTestEntries, opts), os.ErrExist)
+	opts.Overwrite = true
+	assert.NoError(t, SaveStandardLogsToFile(saveTestEntries, opts))
+}
+
+func TestWriteOutputFile_OverwritesOnlyOnceWritten(t *testing.T) {
+	outputDir := t.TempDir()
+	path := filepath.Join(outputDir, "export.txt")
+	require.NoError(t, os.WriteFile(path, []byte("keep me"), 0o644))
+	opts := ExportOptions{OutputDir: outputDir, Path: path, Format: FormatText, Overwrite: true}
+
+	// A failed export leaves the file as it was, and nothing else behind
+	err := writeOutputFile(opts, func(encoder EntryEncoder) error {
+		if err := encoder.WriteStandard("", saveTestEntries[0]); err != nil {
+			return err
+		}
+		return errors.New("disk full")
+	})
+	assert.Error(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+	files, err := os.ReadDir(outputDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	require.NoError(t, SaveStandardLogsToFile(saveTestEntries, opts))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[12:00:00] [Server thread/INFO]: Done\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	files, err = os.ReadDir(outputDir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestSaveStandardLogsToFile_RefusesSymlinks(t *testing.T) {
+	outputDir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "victim.txt")
+	require.NoError(t, os.WriteFile(target, []byte("keep me"), 0o644))
+
+	// A link planted at the export's own path, not just in a directory above it
+	link := filepath.Join(outputDir, "export.txt")
+	require.NoError(t, os.Symlink(target, link))
+	for _, overwrite := range []bool{false, true} {
+		err := SaveStandardLogsToFile(saveTestEntries, ExportOptions{OutputDir: outputDir, Path: link, Format: FormatText, Overwrite: overwrite})
+		assert.Error(t, err)
+	}
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+
+	// A dangling link would otherwise create its target
+	dangling := filepath.Join(outputDir, "dangling.txt")
+	missing := filepath.Join(t.TempDir(), "created.txt")
+	require.NoError(t, os.Symlink(missing, dangling))
+	err = SaveStandardLogsToFile(saveTestEntries, ExportOptions{OutputDir: outputDir, Path: dangling, Format: FormatText, Overwrite: true})
+	assert.ErrorIs(t, err, ErrUnsafeFilename)
+	assert.NoFileExists(t, missing)
+
+	// As would a link to a directory outside
+	require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(outputDir, "elsewhere")))
+	err = SaveStandardLogsToFile(saveTestEntries, ExportOptions{OutputDir: outputDir, Path: filepath.Join(outputDir, "elsewhere", "export.txt"), Format: FormatText})
+	assert.ErrorIs(t, err, ErrUnsafeFilename)
+}
@@ -4,33 +4,102 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"goparselogs/pkg/coreprotectparser"
 )
 
-// ScanLogFiles returns a list of .log and .log.gz files from the logs directory
-func ScanLogFiles() ([]string, error) {
-	// Get the logs directory relative to the current working directory
-	logsDir := "logs"
+// DefaultLogsDir is the directory scanned when no log directories or files are given
+const DefaultLogsDir = "logs"
+
+// LogSources are the directories and individual files listed in the file menu.
+// With neither, DefaultLogsDir is used and created if it does not exist.
+type LogSources struct {
+	Dirs  []string
+	Files []string
+}
+
+// LogGroup is the set of log files found under one source directory.
+type LogGroup struct {
+	Root  string   // Directory the files were found in, empty for individually given files
+	Files []string // Paths of the files, including Root
+}
+
+// ScanLogSources returns the log files of each source directory, followed by
+// the individually given files. Directories are scanned in the order given, and
+// files found more than once are only listed the first time.
+func ScanLogSources(sources LogSources) ([]LogGroup, error) {
+	if len(sources.Dirs) == 0 && len(sources.Files) == 0 {
+		// Create logs directory if it doesn't exist
+		if _, err := os.Stat(DefaultLogsDir); os.IsNotExist(err) {
+			if err := os.Mkdir(DefaultLogsDir, 0755); err != nil {
+				return nil, err
+			}
+		}
+		sources.Dirs = []string{DefaultLogsDir}
+	}
+
+	seen := map[string]bool{}
+	unseen := func(files []string) []string {
+		var kept []string
+		for _, file := range files {
+			if !seen[file] {
+				seen[file] = true
+				kept = append(kept, file)
+			}
+		}
+		return kept
+	}
 
-	// Create logs directory if it doesn't exist
-	if _, err := os.Stat(logsDir); os.IsNotExist(err) {
-		if err := os.Mkdir(logsDir, 0755); err != nil {
+	var groups []LogGroup
+	for _, dir := range sources.Dirs {
+		files, err := ScanLogDir(dir)
+		if err != nil {
 			return nil, err
 		}
+		groups = append(groups, LogGroup{Root: filepath.ToSlash(dir), Files: unseen(files)})
 	}
+	var files []string
+	for _, file := range sources.Files {
+		file = filepath.ToSlash(file)
+		if !isArchive(file) {
+			files = append(files, file)
+			continue
+		}
+		// A given archive stands for the logs in it
+		members, err := ListArchive(file)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, members...)
+	}
+	if files = unseen(files); len(files) > 0 {
+		groups = append(groups, LogGroup{Files: files})
+	}
+	return groups, nil
+}
 
+// ScanLogDir returns a list of the logs, compressed or not, in a logs directory
+// and its subdirectories, including those in zip and tar archives, followed by
+// CoreProtect's database if the server it belongs to has one
+func ScanLogDir(logsDir string) ([]string, error) {
 	var files []string
 	err := filepath.Walk(logsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			ext := strings.ToLower(filepath.Ext(path))
-			// Check for .log files and .gz files that end with .log.gz
-			if ext == ".log" || (ext == ".gz" && strings.HasSuffix(strings.ToLower(path), ".log.gz")) {
-				// Convert path separators to forward slashes for consistency
-				normalizedPath := filepath.ToSlash(path)
-				files = append(files, normalizedPath)
+		if info.IsDir() {
+			return nil
+		}
+		// Convert path separators to forward slashes for consistency
+		normalizedPath := filepath.ToSlash(path)
+		switch {
+		case isArchive(path):
+			// An archive that can't be read is left out rather than hiding every other log
+			if members, err := ListArchive(normalizedPath); err == nil {
+				files = append(files, members...)
 			}
+		case isLogName(path):
+			files = append(files, normalizedPath)
 		}
 		return nil
 	})
@@ -39,5 +108,21 @@ func ScanLogFiles() ([]string, error) {
 		return nil, err
 	}
 
+	// The database lives in the server directory, which holds the logs directory
+	// but may also have been given itself
+	for _, serverDir := range []string{filepath.Dir(filepath.Clean(logsDir)), logsDir} {
+		database := filepath.Join(serverDir, coreprotectparser.DatabasePath)
+		if info, err := os.Stat(database); err == nil && !info.IsDir() {
+			files = append(files, filepath.ToSlash(database))
+			break
+		}
+	}
+
 	return files, nil
 }
+
+// IsCoreProtectDatabase reports whether the path is a CoreProtect SQLite database rather than a log file
+func IsCoreProtectDatabase(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".db" || ext == ".sqlite"
+}
@@ -0,0 +1,250 @@
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"goparselogs/pkg/logparser"
+)
+
+// searchHitLimit caps the entries kept per file so a broad search can't
+// exhaust memory. Matches beyond it are still counted.
+const searchHitLimit = 500
+
+// maxSearchWorkers caps the number of files searched at once
+const maxSearchWorkers = 8
+
+// indexPollInterval is how often the index of a file searched through the
+// index cache is checked on
+const indexPollInterval = 50 * time.Millisecond
+
+// errSearchCancelled stops a file's scan once its search has been cancelled
+var errSearchCancelled = errors.New("search cancelled")
+
+// SearchHit is an entry matching a global search.
+type SearchHit struct {
+	Index int // Position of the entry among the file's matching entries, as in a LogIndex with the same filter
+	Entry logparser.LogEntry
+}
+
+// FileMatches holds the matches of a global search in one log file.
+type FileMatches struct {
+	Path  string
+	Hits  []SearchHit // The first matches, up to searchHitLimit, without continuation lines
+	Count int         // Number of matching entries, including those not kept in Hits
+	Done  bool        // True once the whole file has been searched
+	Err   error
+}
+
+// SearchOptions configure a Search.
+type SearchOptions struct {
+	Workers int         // Number of files searched at once, a number suited to the machine if 0
+	Cache   *IndexCache // Cache of indexes to search files through, if any
+	Terms   [][]string  // Terms required by the filter, as for LogIndex.SetCache
+}
+
+// Search runs a filter over many log files at once with a bounded pool of
+// workers. Results can be read while it is running.
+type Search struct {
+	filter   logparser.Filter
+	location *time.Location
+	opts     SearchOptions
+
+	mu       sync.Mutex
+	files    []FileMatches
+	searched int
+
+	cancelled atomic.Bool
+	wg        sync.WaitGroup
+}
+
+// StartSearch starts searching the log files for entries accepted by filter.
+// Files are searched in the background by up to opts.Workers goroutines.
+// With a cache, files are searched by building their index, so that files
+// searched before only need the entries containing the filter's terms read.
+func StartSearch(paths []string, filter logparser.Filter, loc *time.Location, opts SearchOptions) *Search {
+	if loc == nil {
+		loc = time.Local
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = min(runtime.NumCPU(), maxSearchWorkers)
+	}
+	workers = max(min(workers, len(paths)), 1)
+
+	s := &Search{
+		filter:   filter,
+		location: loc,
+		opts:     opts,
+		files:    make([]FileMatches, len(paths)),
+	}
+	for i, path := range paths {
+		s.files[i].Path = path
+	}
+
+	jobs := make(chan int)
+	s.wg.Add(workers)
+	for range workers {
+		go func() {
+			defer s.wg.Done()
+			for i := range jobs {
+				s.searchFile(i)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range paths {
+			if s.cancelled.Load() {
+				return
+			}
+			jobs <- i
+		}
+	}()
+	return s
+}
+
+// searchFile scans one file, publishing matches as they are found
+func (s *Search) searchFile(i int) {
+	err := s.scanFile(i)
+	if errors.Is(err, errSearchCancelled) {
+		err = nil
+	}
+
+	s.mu.Lock()
+	s.files[i].Done = true
+	s.files[i].Err = err
+	s.searched++
+	s.mu.Unlock()
+}
+
+func (s *Search) scanFile(i int) error {
+	if s.opts.Cache != nil {
+		return s.scanIndexed(i)
+	}
+
+	path := s.files[i].Path
+	parser, err := NewParserForFile(path, s.location)
+	if err != nil {
+		return err
+	}
+	reader, err := OpenLogFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read log file %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	scanner := parser.NewEntryScanner(reader)
+	for scanner.Scan() {
+		if s.cancelled.Load() {
+			return errSearchCancelled
+		}
+		entry := scanner.Entry()
+		if s.filter != nil && !s.filter(entry) {
+			continue
+		}
+
+		s.mu.Lock()
+		matches := &s.files[i]
+		if len(matches.Hits) < searchHitLimit {
+			entry.Continuation = nil
+			matches.Hits = append(matches.Hits, SearchHit{Index: matches.Count, Entry: entry})
+		}
+		matches.Count++
+		s.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to parse log content from %s: %w", path, err)
+	}
+
+	// Midnight rollovers are only known at the end when dating back from the file's mtime
+	if days := scanner.RolloverCorrection(); days > 0 {
+		s.mu.Lock()
+		hits := make([]SearchHit, len(s.files[i].Hits))
+		for j, hit := range s.files[i].Hits {
+			if !hit.Entry.Time.IsZero() {
+				hit.Entry.Time = logparser.ShiftDays(hit.Entry.Time, -days)
+			}
+			hits[j] = hit
+		}
+		// Replaced rather than updated in place, since readers may hold the old slice
+		s.files[i].Hits = hits
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// scanIndexed searches one file by indexing it through the cache
+func (s *Search) scanIndexed(i int) error {
+	index := NewLogIndex(s.files[i].Path, s.filter, s.location)
+	index.SetCache(s.opts.Cache, s.opts.Terms)
+	index.Start()
+	defer index.Close()
+
+	for {
+		done, err := index.Done()
+		s.mu.Lock()
+		s.files[i].Count = index.Len()
+		s.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+		if s.cancelled.Load() {
+			return errSearchCancelled
+		}
+		time.Sleep(indexPollInterval)
+	}
+
+	entries, err := index.ReadEntries(0, searchHitLimit)
+	if err != nil {
+		return err
+	}
+	hits := make([]SearchHit, len(entries))
+	for j, entry := range entries {
+		entry.Continuation = nil
+		hits[j] = SearchHit{Index: j, Entry: entry}
+	}
+	s.mu.Lock()
+	s.files[i].Hits = hits
+	s.mu.Unlock()
+	return nil
+}
+
+// Results returns the matches found so far in each file, in the order the files were given.
+func (s *Search) Results() []FileMatches {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]FileMatches, len(s.files))
+	copy(results, s.files)
+	return results
+}
+
+// Progress returns the number of files searched and the total number of files.
+func (s *Search) Progress() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.searched, len(s.files)
+}
+
+// Done reports whether every file has been searched, or the search was cancelled.
+func (s *Search) Done() bool {
+	searched, total := s.Progress()
+	return searched == total || s.cancelled.Load()
+}
+
+// Cancel stops the search. Files already searched keep their results.
+func (s *Search) Cancel() {
+	s.cancelled.Store(true)
+}
+
+// Wait blocks until the workers have stopped.
+func (s *Search) Wait() {
+	s.wg.Wait()
+}
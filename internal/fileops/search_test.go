@@ -0,0 +1,141 @@
+package fileops
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func joinedFilter(entry logparser.LogEntry) bool {
+	return strings.Contains(entry.Message, "joined the game")
+}
+
+// writeSearchLogs writes logs with the given numbers of entries matching joinedFilter
+func writeSearchLogs(t *testing.T, joins ...int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, count := range joins {
+		var log strings.Builder
+		for j := 0; j < count; j++ {
+			fmt.Fprintf(&log, "[12:00:00] [Server thread/INFO]: Player%d joined the game\n", j)
+			fmt.Fprintf(&log, "[12:00:01] [Server thread/INFO]: Saving chunks\n")
+		}
+		paths = append(paths, writeTestLog(t, dir, fmt.Sprintf("2024-05-0%d-1.log", i+1), log.String()))
+	}
+	return paths
+}
+
+func runSearch(t *testing.T, paths []string, opts SearchOptions) []FileMatches {
+	t.Helper()
+	search := StartSearch(paths, joinedFilter, time.UTC, opts)
+	search.Wait()
+	assert.True(t, search.Done())
+	searched, total := search.Progress()
+	assert.Equal(t, len(paths), searched)
+	assert.Equal(t, len(paths), total)
+	return search.Results()
+}
+
+func TestSearch_HitsAcrossFiles(t *testing.T) {
+	paths := writeSearchLogs(t, 2, 0, 3)
+	cache, err := OpenIndexCache(t.TempDir())
+	require.NoError(t, err)
+
+	for name, opts := range map[string]SearchOptions{
+		"scanned":  {Workers: 2},
+		"indexed":  {Cache: cache},
+		"cached":   {Cache: cache, Terms: [][]string{{"joined"}}},
+		"one file": {Workers: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			results := runSearch(t, paths, opts)
+			require.Len(t, results, 3)
+
+			// Results stay in the order the files were given
+			for i, result := range results {
+				assert.Equal(t, paths[i], result.Path)
+				assert.True(t, result.Done)
+				assert.NoError(t, result.Err)
+			}
+			assert.Equal(t, 2, results[0].Count)
+			assert.Equal(t, 0, results[1].Count)
+			assert.Empty(t, results[1].Hits)
+			assert.Equal(t, 3, results[2].Count)
+
+			// Hit indices match a LogIndex with the same filter
+			for j, hit := range results[2].Hits {
+				assert.Equal(t, j, hit.Index)
+				assert.Equal(t, fmt.Sprintf("Player%d joined the game", j), hit.Entry.Message)
+				assert.Equal(t, 2*j+1, hit.Entry.LineNumber)
+			}
+		})
+	}
+}
+
+func TestSearch_MissingFile(t *testing.T) {
+	paths := append(writeSearchLogs(t, 1), filepath.Join(t.TempDir(), "missing.log"))
+	results := runSearch(t, paths, SearchOptions{})
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 1, results[0].Count)
+	assert.Error(t, results[1].Err)
+	assert.True(t, results[1].Done)
+}
+
+func TestSearch_HitLimit(t *testing.T) {
+	paths := writeSearchLogs(t, searchHitLimit+100)
+	cache, err := OpenIndexCache(t.TempDir())
+	require.NoError(t, err)
+
+	for name, opts := range map[string]SearchOptions{
+		"scanned": {},
+		"indexed": {Cache: cache},
+	} {
+		t.Run(name, func(t *testing.T) {
+			results := runSearch(t, paths, opts)
+			// Matches beyond the limit are counted but not kept
+			assert.Len(t, results[0].Hits, searchHitLimit)
+			assert.Equal(t, searchHitLimit+100, results[0].Count)
+			last := results[0].Hits[searchHitLimit-1]
+			assert.Equal(t, searchHitLimit-1, last.Index)
+		})
+	}
+}
+
+func TestSearch_Cancel(t *testing.T) {
+	paths := writeSearchLogs(t, 50, 50)
+	reached := make(chan struct{})
+	release := make(chan struct{})
+	seen := 0
+	filter := func(entry logparser.LogEntry) bool {
+		// Only one file is searched at a time, so seen needn't be guarded
+		seen++
+		if seen == 10 {
+			close(reached)
+			<-release
+		}
+		return joinedFilter(entry)
+	}
+
+	search := StartSearch(paths, filter, time.UTC, SearchOptions{Workers: 1})
+	<-reached
+	search.Cancel()
+	assert.True(t, search.Done())
+	close(release)
+	search.Wait()
+
+	// The file being searched keeps what was found, and no more is searched
+	results := search.Results()
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 5, results[0].Count)
+	assert.Len(t, results[0].Hits, 5)
+	assert.Zero(t, results[1].Count)
+	assert.NoError(t, results[1].Err)
+}
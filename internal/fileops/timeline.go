@@ -0,0 +1,234 @@
+package fileops
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"goparselogs/pkg/events"
+	"goparselogs/pkg/logparser"
+)
+
+// timelineReadSize is the number of merged entries Each reads at a time
+const timelineReadSize = 1000
+
+// timelineRef locates an entry of a Timeline in the index of its log
+type timelineRef struct {
+	file  int32
+	entry int32
+}
+
+// Timeline merges the entries of several logs, such as a server's rotated
+// logs or the logs of each server in a network, into one chronological
+// stream. Each log is indexed with a LogIndex, and entries are tagged with
+// the log they came from through LogEntry.Source. Entries are only available
+// once every log has been indexed, since the merge needs all their times.
+type Timeline struct {
+	Paths []string // The logs being merged
+
+	indexes []*LogIndex
+
+	mu     sync.RWMutex
+	order  []timelineRef  // Entries in chronological order
+	events []events.Event // Events of every log, with EntryIndex into order
+	done   bool
+	err    error
+}
+
+var _ EntryIndex = (*Timeline)(nil)
+
+// NewTimeline creates a timeline of the entries of the logs accepted by the
+// filter. Call Start to build it and Close to release it.
+func NewTimeline(paths []string, filter logparser.Filter, loc *time.Location) *Timeline {
+	t := &Timeline{Paths: paths}
+	for _, path := range paths {
+		t.indexes = append(t.indexes, NewLogIndex(path, filter, loc))
+	}
+	return t
+}
+
+// SetCache makes each log's index use the cache, as LogIndex.SetCache does.
+func (t *Timeline) SetCache(cache *IndexCache, terms [][]string) {
+	for _, index := range t.indexes {
+		index.SetCache(cache, terms)
+	}
+}
+
+// Start indexes the logs in the background, a few at a time, and then merges them.
+func (t *Timeline) Start() {
+	go t.Build()
+}
+
+// Build indexes the logs and merges their entries.
+func (t *Timeline) Build() {
+	workers := min(runtime.NumCPU(), maxSearchWorkers, len(t.indexes))
+	jobs := make(chan *LogIndex)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				index.Build()
+			}
+		}()
+	}
+	for _, index := range t.indexes {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	order, merged, err := t.merge()
+
+	t.mu.Lock()
+	t.order = order
+	t.events = merged
+	t.done = true
+	t.err = err
+	t.mu.Unlock()
+}
+
+// merge orders the entries of every log by time. The entries of each log keep
+// their order, even where its clock went backwards or times are unknown, and
+// entries logged at the same moment keep the order the logs were given in.
+func (t *Timeline) merge() ([]timelineRef, []events.Event, error) {
+	type keyed struct {
+		ref  timelineRef
+		time int64
+	}
+	var entries []keyed
+	for i, index := range t.indexes {
+		if _, err := index.Done(); err != nil {
+			return nil, nil, err
+		}
+		index.mu.RLock()
+		var last int64
+		for j, nanos := range index.times {
+			last = max(last, nanos)
+			entries = append(entries, keyed{ref: timelineRef{file: int32(i), entry: int32(j)}, time: last})
+		}
+		index.mu.RUnlock()
+	}
+	slices.SortStableFunc(entries, func(a, b keyed) int {
+		switch {
+		case a.time < b.time:
+			return -1
+		case a.time > b.time:
+			return 1
+		}
+		return 0
+	})
+
+	order := make([]timelineRef, len(entries))
+	positions := make([][]int, len(t.indexes)) // Merged position of each entry of each log
+	for i, index := range t.indexes {
+		positions[i] = make([]int, index.Len())
+	}
+	for position, entry := range entries {
+		order[position] = entry.ref
+		positions[entry.ref.file][entry.ref.entry] = position
+	}
+
+	var merged []events.Event
+	for i, index := range t.indexes {
+		for _, event := range index.Events() {
+			event.EntryIndex = positions[i][event.EntryIndex]
+			merged = append(merged, event)
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b events.Event) int {
+		return a.EntryIndex - b.EntryIndex
+	})
+	return order, merged, nil
+}
+
+// Len returns the number of merged entries, which is 0 until every log has been indexed.
+func (t *Timeline) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.order)
+}
+
+// Done reports whether the timeline has been merged, and any error that stopped it.
+func (t *Timeline) Done() (bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.done, t.err
+}
+
+// Events returns the events of every log, in the order of the timeline.
+func (t *Timeline) Events() []events.Event {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]events.Event(nil), t.events...)
+}
+
+// ReadEntries parses the merged entries with indices in [start, end).
+func (t *Timeline) ReadEntries(start, end int) ([]logparser.LogEntry, error) {
+	t.mu.RLock()
+	start = max(start, 0)
+	end = min(end, len(t.order))
+	if start >= end {
+		t.mu.RUnlock()
+		return nil, nil
+	}
+	refs := t.order[start:end:end]
+	t.mu.RUnlock()
+
+	// Read the span of each log covered by the window in one pass. Entries of a
+	// log keep their order in the timeline, so each span holds its refs in order.
+	first := make(map[int32]int32)
+	last := make(map[int32]int32)
+	for _, ref := range refs {
+		if _, ok := first[ref.file]; !ok {
+			first[ref.file] = ref.entry
+		}
+		last[ref.file] = ref.entry
+	}
+	spans := make(map[int32][]logparser.LogEntry, len(first))
+	for file, from := range first {
+		entries, err := t.indexes[file].ReadEntries(int(from), int(last[file])+1)
+		if err != nil {
+			return nil, err
+		}
+		spans[file] = entries
+	}
+
+	entries := make([]logparser.LogEntry, 0, len(refs))
+	for _, ref := range refs {
+		if i := int(ref.entry - first[ref.file]); i < len(spans[ref.file]) {
+			entries = append(entries, spans[ref.file][i])
+		}
+	}
+	return entries, nil
+}
+
+// Each calls fn for every merged entry in order, reading the logs a window at a time.
+func (t *Timeline) Each(fn func(i int, entry logparser.LogEntry) error) error {
+	total := t.Len()
+	for start := 0; start < total; start += timelineReadSize {
+		entries, err := t.ReadEntries(start, start+timelineReadSize)
+		if err != nil {
+			return err
+		}
+		for i, entry := range entries {
+			if err := fn(start+i, entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops indexing the logs and removes any temporary files.
+func (t *Timeline) Close() error {
+	var firstErr error
+	for _, index := range t.indexes {
+		if err := index.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,135 @@
+package fileops
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestTimeline(t *testing.T, paths []string, filter logparser.Filter) *Timeline {
+	t.Helper()
+	timeline := NewTimeline(paths, filter, time.UTC)
+	timeline.Build()
+	t.Cleanup(func() { timeline.Close() })
+	done, err := timeline.Done()
+	require.True(t, done)
+	require.NoError(t, err)
+	return timeline
+}
+
+// timelineMessages returns the source and message of each merged entry
+func timelineMessages(t *testing.T, timeline *Timeline) [][2]string {
+	t.Helper()
+	var messages [][2]string
+	require.NoError(t, timeline.Each(func(i int, entry logparser.LogEntry) error {
+		assert.Equal(t, len(messages), i)
+		messages = append(messages, [2]string{entry.Source, entry.Message})
+		return nil
+	}))
+	return messages
+}
+
+func TestTimeline_MergesByTime(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestLog(t, dir, "2024-05-01-1.log", "[10:00:00] [Server thread/INFO]: a1\n"+
+		"[10:00:02] [Server thread/INFO]: a2\n"+
+		"[10:00:04] [Server thread/INFO]: a3\n")
+	second := writeTestLog(t, dir, "2024-05-01-2.log", "[10:00:01] [Server thread/INFO]: b1\n"+
+		"[10:00:03] [Server thread/INFO]: b2\n"+
+		"\tat Example.run(Example.java:1)\n"+
+		"[10:00:05] [Server thread/INFO]: b3\n")
+	timeline := buildTestTimeline(t, []string{first, second}, nil)
+
+	assert.Equal(t, 6, timeline.Len())
+	assert.Equal(t, [][2]string{
+		{first, "a1"}, {second, "b1"}, {first, "a2"}, {second, "b2"}, {first, "a3"}, {second, "b3"},
+	}, timelineMessages(t, timeline))
+
+	// A window reads only the entries it covers, with their stack traces
+	entries, err := timeline.ReadEntries(3, 5)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b2", entries[0].Message)
+	assert.Equal(t, []string{"\tat Example.run(Example.java:1)"}, entries[0].Continuation)
+	assert.Equal(t, 2, entries[0].LineNumber)
+	assert.Equal(t, "a3", entries[1].Message)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 4, 0, time.UTC), entries[1].Time)
+
+	entries, err = timeline.ReadEntries(5, 100)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = timeline.ReadEntries(6, 100)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestTimeline_TiesKeepTheOrderOfTheLogs(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestLog(t, dir, "2024-05-01-1.log", "[10:00:00] [Server thread/INFO]: a1\n"+
+		"[10:00:01] [Server thread/INFO]: a2\n"+
+		"[10:00:01] [Server thread/INFO]: a3\n")
+	second := writeTestLog(t, dir, "2024-05-01-2.log", "[10:00:00] [Server thread/INFO]: b1\n"+
+		"[10:00:01] [Server thread/INFO]: b2\n")
+
+	// Entries logged at the same moment come in the order the logs were given
+	assert.Equal(t, [][2]string{
+		{first, "a1"}, {second, "b1"}, {first, "a2"}, {first, "a3"}, {second, "b2"},
+	}, timelineMessages(t, buildTestTimeline(t, []string{first, second}, nil)))
+	assert.Equal(t, [][2]string{
+		{second, "b1"}, {first, "a1"}, {second, "b2"}, {first, "a2"}, {first, "a3"},
+	}, timelineMessages(t, buildTestTimeline(t, []string{second, first}, nil)))
+}
+
+func TestTimeline_KeepsTheOrderOfEachLog(t *testing.T) {
+	dir := t.TempDir()
+	// The clock of the first log goes back, so a2 is placed by the time of a1
+	first := writeTestLog(t, dir, "2024-05-01-1.log", "[10:00:05] [Server thread/INFO]: a1\n"+
+		"[10:00:01] [Server thread/INFO]: a2\n"+
+		"[10:00:06] [Server thread/INFO]: a3\n")
+	second := writeTestLog(t, dir, "2024-05-01-2.log", "[10:00:03] [Server thread/INFO]: b1\n"+
+		"[10:00:07] [Server thread/INFO]: b2\n")
+
+	assert.Equal(t, [][2]string{
+		{second, "b1"}, {first, "a1"}, {first, "a2"}, {first, "a3"}, {second, "b2"},
+	}, timelineMessages(t, buildTestTimeline(t, []string{first, second}, nil)))
+}
+
+func TestTimeline_FilterAndEvents(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestLog(t, dir, "2024-05-01-1.log", "[10:00:00] [Server thread/INFO]: Steve joined the game\n"+
+		"[10:00:02] [Server thread/INFO]: Saving\n"+
+		"[10:00:04] [Server thread/INFO]: Steve left the game\n")
+	second := writeTestLog(t, dir, "2024-05-01-2.log", "[10:00:01] [Server thread/INFO]: Alex joined the game\n"+
+		"[10:00:03] [Server thread/INFO]: Saving\n")
+	timeline := buildTestTimeline(t, []string{first, second}, func(entry logparser.LogEntry) bool {
+		return entry.Message != "Saving"
+	})
+
+	assert.Equal(t, [][2]string{
+		{first, "Steve joined the game"}, {second, "Alex joined the game"}, {first, "Steve left the game"},
+	}, timelineMessages(t, timeline))
+
+	// Events point at the merged entries
+	events := timeline.Events()
+	require.Len(t, events, 3)
+	for i, event := range events {
+		assert.Equal(t, i, event.EntryIndex)
+	}
+	assert.Equal(t, second, events[1].Entry.Source)
+}
+
+func TestTimeline_MissingLog(t *testing.T) {
+	dir := t.TempDir()
+	timeline := NewTimeline([]string{writeTestLog(t, dir, "latest.log", indexTestLog), filepath.Join(dir, "missing.log")}, nil, time.UTC)
+	timeline.Build()
+	defer timeline.Close()
+	done, err := timeline.Done()
+	assert.True(t, done)
+	assert.Error(t, err)
+	assert.Zero(t, timeline.Len())
+}
@@ -0,0 +1,408 @@
+package fileops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// watchDebounce is how long the watcher waits for changes to settle before reporting them
+	watchDebounce = 200 * time.Millisecond
+	// watchMaxDelay caps how long a change is held back while a file keeps being written
+	watchMaxDelay = time.Second
+	// pollInterval is how often watched files are checked when change notifications are unavailable
+	pollInterval = time.Second
+	// pollRescanEvery is the number of polls between walks of the source directories for new files
+	pollRescanEvery = 5
+)
+
+// ChangeOp is the kind of change made to a watched file.
+type ChangeOp int
+
+const (
+	FileAdded ChangeOp = iota
+	FileRemoved
+	FileModified
+)
+
+// FileChange is a change to a log file in the watched sources.
+type FileChange struct {
+	Path string // As listed by ScanLogSources
+	Op   ChangeOp
+}
+
+// WatchEvent is a batch of changes to the watched files.
+type WatchEvent struct {
+	Changes []FileChange
+	Err     error // Error scanning the sources, if the last scan failed
+}
+
+// Structural reports whether files were added or removed, so the list of files must be rescanned.
+func (e WatchEvent) Structural() bool {
+	for _, change := range e.Changes {
+		if change.Op != FileModified {
+			return true
+		}
+	}
+	return false
+}
+
+// notification is a raw change reported by a notifier, for an empty path if
+// notifications were lost
+type notification struct {
+	path       string
+	structural bool  // Entries were added to or removed from a directory
+	err        error // Why the notifier stopped, after which it sends nothing more
+}
+
+// notifier reports changes made in watched directories as they happen
+type notifier interface {
+	// Add watches a directory's entries and the files in it. Adding a directory twice has no effect.
+	Add(dir string) error
+	Notifications() <-chan notification
+	Close() error
+}
+
+// errNotifyUnsupported is returned by newNotifier where change notifications aren't implemented
+var errNotifyUnsupported = errors.New("file change notifications are not supported")
+
+// Watcher reports files being added to, removed from or modified in the log
+// sources, batching up bursts of changes. It relies on change notifications
+// from the operating system where available, and otherwise polls the files.
+type Watcher struct {
+	sources LogSources
+	events  chan WatchEvent
+	done    chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	followed string // File watched on top of the sources, such as the log being followed
+
+	// Owned by the run loop
+	notifier notifier
+	polling  bool                   // Whether files are polled, as they are once the notifier fails
+	files    map[string]os.FileInfo // Last known state of each watched file
+}
+
+// NewWatcher starts watching the log files in sources. Call Close to stop it.
+func NewWatcher(sources LogSources) *Watcher {
+	n, err := newNotifier()
+	if err != nil {
+		n = nil
+	}
+	return newWatcher(sources, n)
+}
+
+// newWatcher starts watching with the given notifier, polling if it is nil
+func newWatcher(sources LogSources, n notifier) *Watcher {
+	w := &Watcher{
+		sources:  sources,
+		events:   make(chan WatchEvent),
+		done:     make(chan struct{}),
+		notifier: n,
+		polling:  n == nil,
+		files:    map[string]os.FileInfo{},
+	}
+	// The first scan records the files already there, so only later changes are reported
+	w.rescan()
+	w.watchDirs()
+	go w.run()
+	return w
+}
+
+// Events returns the channel batches of changes are delivered on. It is
+// never closed, so receivers should also stop once the watcher is closed.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Done returns a channel that is closed once the watcher is closed.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Follow watches a file on top of those in the sources, so that changes to
+// a log being followed are reported wherever it is. An empty path stops it.
+func (w *Watcher) Follow(path string) {
+	if path != "" {
+		path = filepath.ToSlash(path)
+	}
+	w.mu.Lock()
+	w.followed = path
+	w.mu.Unlock()
+	if path != "" && w.notifier != nil {
+		w.notifier.Add(filepath.Dir(filepath.FromSlash(diskPath(path))))
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	if w.notifier != nil {
+		return w.notifier.Close()
+	}
+	return nil
+}
+
+// run collects notifications, or polls, and delivers the changes found
+func (w *Watcher) run() {
+	var notifications <-chan notification
+	var poll <-chan time.Time
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	if w.polling {
+		poll = ticker.C
+	} else {
+		ticker.Stop()
+		notifications = w.notifier.Notifications()
+	}
+
+	settle := time.NewTimer(0)
+	<-settle.C
+	var first time.Time // When the first change still held back was noticed, zero if none
+	needRescan := false
+	dirty := map[string]bool{}
+	polls := 0
+
+	var pending WatchEvent
+	ready := false
+	for {
+		var out chan<- WatchEvent
+		if ready {
+			out = w.events
+		}
+
+		select {
+		case <-w.done:
+			return
+
+		case n := <-notifications:
+			switch {
+			case n.err != nil:
+				// Poll from now on, first checking everything in case changes were missed
+				w.polling = true
+				notifications = nil
+				poll = ticker.C
+				ticker.Reset(pollInterval)
+				w.notifier.Close()
+				needRescan = true
+				for path := range w.watched() {
+					dirty[path] = true
+				}
+			case n.path == "":
+				// Notifications were lost, so check everything
+				needRescan = true
+				for path := range w.watched() {
+					dirty[path] = true
+				}
+			case n.structural && w.inSourceDirs(n.path), isArchive(n.path):
+				// The logs in an archive may have changed along with it
+				needRescan = true
+				dirty[filepath.ToSlash(n.path)] = true // Such as a log replaced by a new one
+			default:
+				dirty[filepath.ToSlash(n.path)] = true
+			}
+			now := time.Now()
+			if first.IsZero() {
+				first = now
+			}
+			settle.Reset(min(watchDebounce, watchMaxDelay-now.Sub(first)))
+			continue
+
+		case <-poll:
+			polls++
+			needRescan = polls%pollRescanEvery == 0
+			for path := range w.watched() {
+				dirty[path] = true
+			}
+
+		case <-settle.C:
+
+		case out <- pending:
+			pending = WatchEvent{}
+			ready = false
+			continue
+		}
+
+		// Work out what changed since the last check
+		first = time.Time{}
+		var changes []FileChange
+		var err error
+		if needRescan {
+			changes, err = w.rescan()
+			w.watchDirs()
+		}
+		changes = append(changes, w.check(dirty)...)
+		needRescan = false
+		dirty = map[string]bool{}
+
+		for _, change := range changes {
+			pending.Changes = mergeChange(pending.Changes, change)
+		}
+		if err != nil {
+			pending.Err = err
+		}
+		ready = len(pending.Changes) > 0 || pending.Err != nil
+	}
+}
+
+// watched returns the files being watched, including the followed one
+func (w *Watcher) watched() map[string]bool {
+	paths := make(map[string]bool, len(w.files)+1)
+	for path := range w.files {
+		paths[path] = true
+	}
+	w.mu.Lock()
+	if w.followed != "" {
+		paths[w.followed] = true
+	}
+	w.mu.Unlock()
+	return paths
+}
+
+// rescan lists the files in the sources, recording those added or removed
+func (w *Watcher) rescan() ([]FileChange, error) {
+	groups, err := ScanLogSources(w.sources)
+	if err != nil {
+		return nil, err
+	}
+	listed := map[string]bool{}
+	for _, group := range groups {
+		for _, file := range group.Files {
+			listed[file] = true
+		}
+	}
+
+	var changes []FileChange
+	for path := range listed {
+		if _, ok := w.files[path]; ok {
+			continue
+		}
+		info, err := os.Stat(diskPath(path))
+		if err != nil {
+			continue // Removed again already
+		}
+		w.files[path] = info
+		changes = append(changes, FileChange{Path: path, Op: FileAdded})
+	}
+	w.mu.Lock()
+	followed := w.followed
+	w.mu.Unlock()
+	for path := range w.files {
+		if !listed[path] && path != followed {
+			delete(w.files, path)
+			changes = append(changes, FileChange{Path: path, Op: FileRemoved})
+		}
+	}
+	return changes, nil
+}
+
+// check compares the files at paths with their last known state. Paths
+// that aren't watched are ignored, other than the followed file.
+func (w *Watcher) check(paths map[string]bool) []FileChange {
+	w.mu.Lock()
+	followed := w.followed
+	w.mu.Unlock()
+
+	// A change to an archive is a change to each log in it
+	for path := range w.files {
+		if archive, _, ok := SplitArchivePath(path); ok && paths[archive] {
+			paths[path] = true
+		}
+	}
+
+	var changes []FileChange
+	for path := range paths {
+		previous, known := w.files[path]
+		if !known && path != followed {
+			continue
+		}
+		info, err := os.Stat(diskPath(path))
+		switch {
+		case err != nil && known:
+			delete(w.files, path)
+			changes = append(changes, FileChange{Path: path, Op: FileRemoved})
+		case err != nil:
+		case !known:
+			// The followed file outside the sources is only reported as modified
+			w.files[path] = info
+			changes = append(changes, FileChange{Path: path, Op: FileModified})
+		case info.Size() != previous.Size() || !info.ModTime().Equal(previous.ModTime()) || !os.SameFile(info, previous):
+			w.files[path] = info
+			changes = append(changes, FileChange{Path: path, Op: FileModified})
+		}
+	}
+	return changes
+}
+
+// watchDirs has the notifier watch every directory in the sources, including
+// any created since they were last watched
+func (w *Watcher) watchDirs() {
+	if w.polling {
+		return
+	}
+	for _, dir := range w.sourceDirs() {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				w.notifier.Add(path)
+			}
+			return nil
+		})
+	}
+	// Individually given files and databases are watched through their directory
+	for path := range w.files {
+		if !w.inSourceDirs(path) || IsCoreProtectDatabase(path) {
+			w.notifier.Add(filepath.Dir(filepath.FromSlash(diskPath(path))))
+		}
+	}
+}
+
+// sourceDirs returns the directories being watched, cleaned
+func (w *Watcher) sourceDirs() []string {
+	dirs := w.sources.Dirs
+	if len(dirs) == 0 && len(w.sources.Files) == 0 {
+		dirs = []string{DefaultLogsDir}
+	}
+	cleaned := make([]string, len(dirs))
+	for i, dir := range dirs {
+		cleaned[i] = filepath.Clean(dir)
+	}
+	return cleaned
+}
+
+// inSourceDirs reports whether path is in, or is, one of the source directories
+func (w *Watcher) inSourceDirs(path string) bool {
+	path = filepath.FromSlash(path)
+	for _, dir := range w.sourceDirs() {
+		if rel, err := filepath.Rel(dir, path); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeChange adds a change to those not yet delivered, combining it with an
+// earlier change to the same file
+func mergeChange(changes []FileChange, change FileChange) []FileChange {
+	for i, earlier := range changes {
+		if earlier.Path != change.Path {
+			continue
+		}
+		switch {
+		case earlier.Op == FileAdded && change.Op == FileRemoved:
+			return append(changes[:i], changes[i+1:]...)
+		case earlier.Op == FileAdded:
+			// Still new to the receiver
+		case earlier.Op == FileRemoved && change.Op == FileAdded:
+			changes[i].Op = FileModified
+		default:
+			changes[i].Op = change.Op
+		}
+		return changes
+	}
+	return append(changes, change)
+}
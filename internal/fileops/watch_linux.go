@@ -0,0 +1,160 @@
+//go:build linux
+
+package fileops
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the inotify events that can change the watched logs
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_MODIFY | unix.IN_CLOSE_WRITE | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotifyPollTimeout is how long a read waits for events before checking whether the notifier was closed
+const inotifyPollTimeout = 500 // milliseconds
+
+// inotifyNotifier reports changes with Linux's inotify
+type inotifyNotifier struct {
+	fd            int
+	notifications chan notification
+	closed        chan struct{}
+	once          sync.Once
+
+	mu   sync.Mutex     // Guards fd too, which is -1 once the reader has closed it
+	dirs map[int]string // Directory of each watch descriptor
+	wds  map[string]int
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotifyNotifier{
+		fd:            fd,
+		notifications: make(chan notification, 64),
+		closed:        make(chan struct{}),
+		dirs:          map[int]string{},
+		wds:           map[string]int{},
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotifyNotifier) Add(dir string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	dir = filepath.Clean(dir)
+	if n.fd < 0 {
+		return os.ErrClosed
+	}
+	if _, ok := n.wds[dir]; ok {
+		return nil
+	}
+	wd, err := unix.InotifyAddWatch(n.fd, dir, inotifyMask|unix.IN_ONLYDIR)
+	if err != nil {
+		return err
+	}
+	n.dirs[wd] = dir
+	n.wds[dir] = wd
+	return nil
+}
+
+func (n *inotifyNotifier) Notifications() <-chan notification {
+	return n.notifications
+}
+
+func (n *inotifyNotifier) Close() error {
+	// The reader notices within inotifyPollTimeout and closes the descriptor
+	n.once.Do(func() { close(n.closed) })
+	return nil
+}
+
+// read turns inotify events into notifications until the notifier is closed,
+// or reading fails
+func (n *inotifyNotifier) read() {
+	defer func() {
+		n.mu.Lock()
+		unix.Close(n.fd)
+		n.fd = -1
+		n.mu.Unlock()
+	}()
+	buf := make([]byte, 64*1024)
+	fds := []unix.PollFd{{Fd: int32(n.fd), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-n.closed:
+			return
+		default:
+		}
+
+		if _, err := unix.Poll(fds, inotifyPollTimeout); err != nil && !errors.Is(err, unix.EINTR) {
+			n.send(notification{err: fmt.Errorf("failed to wait for file change notifications: %w", err)})
+			return
+		}
+		count, err := unix.Read(n.fd, buf)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			n.send(notification{err: fmt.Errorf("failed to read file change notifications: %w", err)})
+			return
+		}
+		if count < unix.SizeofInotifyEvent {
+			n.send(notification{err: fmt.Errorf("failed to read file change notifications: short read of %d bytes", count)})
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= count; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+			if !n.send(n.notification(event, nameBytes)) {
+				return
+			}
+		}
+	}
+}
+
+// notification describes an inotify event
+func (n *inotifyNotifier) notification(event *unix.InotifyEvent, nameBytes []byte) notification {
+	n.mu.Lock()
+	dir := n.dirs[int(event.Wd)]
+	if event.Mask&unix.IN_IGNORED != 0 {
+		// The directory was removed, so it must be watched again if it comes back
+		delete(n.dirs, int(event.Wd))
+		delete(n.wds, dir)
+	}
+	n.mu.Unlock()
+
+	if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+		return notification{}
+	}
+	name := string(nameBytes)
+	for len(name) > 0 && name[len(name)-1] == 0 {
+		name = name[:len(name)-1]
+	}
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	structural := event.Mask&(unix.IN_CREATE|unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_MOVED_TO|unix.IN_DELETE_SELF|unix.IN_MOVE_SELF|unix.IN_IGNORED) != 0
+	return notification{path: path, structural: structural}
+}
+
+// send delivers a notification, returning false if the notifier was closed
+func (n *inotifyNotifier) send(note notification) bool {
+	select {
+	case n.notifications <- note:
+		return true
+	case <-n.closed:
+		return false
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fileops
+
+// newNotifier reports that change notifications aren't available, so the watcher polls instead
+func newNotifier() (notifier, error) {
+	return nil, errNotifyUnsupported
+}
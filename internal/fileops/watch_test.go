@@ -0,0 +1,164 @@
+package fileops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifier delivers the notifications a test sends it
+type fakeNotifier struct {
+	notifications chan notification
+	closed        atomic.Bool
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{notifications: make(chan notification)}
+}
+
+func (n *fakeNotifier) Add(dir string) error               { return nil }
+func (n *fakeNotifier) Notifications() <-chan notification { return n.notifications }
+func (n *fakeNotifier) Close() error {
+	n.closed.Store(true)
+	return nil
+}
+
+// startTestWatcher watches a directory holding one log, returning the log's path as the watcher reports it
+func startTestWatcher(t *testing.T, n notifier) (*Watcher, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := writeTestLog(t, dir, "latest.log", indexTestLog)
+	w := newWatcher(LogSources{Dirs: []string{dir}}, n)
+	t.Cleanup(func() { w.Close() })
+	return w, filepath.ToSlash(path)
+}
+
+func nextWatchEvent(t *testing.T, w *Watcher) WatchEvent {
+	t.Helper()
+	select {
+	case event := <-w.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+		return WatchEvent{}
+	}
+}
+
+func TestMergeChange(t *testing.T) {
+	added := FileChange{Path: "a.log", Op: FileAdded}
+	removed := FileChange{Path: "a.log", Op: FileRemoved}
+	modified := FileChange{Path: "a.log", Op: FileModified}
+	other := FileChange{Path: "b.log", Op: FileModified}
+
+	tests := []struct {
+		name    string
+		changes []FileChange
+		change  FileChange
+		want    []FileChange
+	}{
+		{"first change", nil, modified, []FileChange{modified}},
+		{"other file", []FileChange{other}, modified, []FileChange{other, modified}},
+		{"added then removed", []FileChange{other, added}, removed, []FileChange{other}},
+		{"added then modified", []FileChange{added}, modified, []FileChange{added}},
+		{"removed then added", []FileChange{removed}, added, []FileChange{modified}},
+		{"modified then removed", []FileChange{modified}, removed, []FileChange{removed}},
+		{"modified twice", []FileChange{modified}, modified, []FileChange{modified}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeChange(tt.changes, tt.change))
+		})
+	}
+}
+
+func TestWatcher_CheckComparesFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestLog(t, dir, "latest.log", indexTestLog)
+	listed := filepath.ToSlash(path)
+	followed := writeTestLog(t, t.TempDir(), "followed.log", indexTestLog)
+	w := &Watcher{sources: LogSources{Dirs: []string{dir}}, polling: true, files: map[string]os.FileInfo{}}
+
+	changes, err := w.rescan()
+	require.NoError(t, err)
+	assert.Equal(t, []FileChange{{Path: listed, Op: FileAdded}}, changes)
+	assert.Empty(t, w.check(map[string]bool{listed: true}), "unchanged")
+
+	appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Alex joined the game\n")
+	assert.Equal(t, []FileChange{{Path: listed, Op: FileModified}}, w.check(map[string]bool{listed: true}))
+
+	// Files outside the sources are ignored unless followed
+	assert.Empty(t, w.check(map[string]bool{followed: true}))
+	w.Follow(followed)
+	assert.Equal(t, []FileChange{{Path: followed, Op: FileModified}}, w.check(map[string]bool{followed: true}))
+
+	require.NoError(t, os.Remove(path))
+	assert.Equal(t, []FileChange{{Path: listed, Op: FileRemoved}}, w.check(map[string]bool{listed: true}))
+	assert.Empty(t, w.check(map[string]bool{listed: true}), "already removed")
+}
+
+func TestWatcher_Debounces(t *testing.T) {
+	n := newFakeNotifier()
+	w, path := startTestWatcher(t, n)
+
+	appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Alex joined the game\n")
+	start := time.Now()
+	n.notifications <- notification{path: path}
+	event := nextWatchEvent(t, w)
+	elapsed := time.Since(start)
+
+	assert.Equal(t, []FileChange{{Path: path, Op: FileModified}}, event.Changes)
+	assert.GreaterOrEqual(t, elapsed, watchDebounce)
+	assert.Less(t, elapsed, watchMaxDelay)
+}
+
+func TestWatcher_MaxDelay(t *testing.T) {
+	n := newFakeNotifier()
+	w, path := startTestWatcher(t, n)
+
+	// A file written to more often than the debounce is still reported
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+	start := time.Now()
+	go func() {
+		defer close(stopped)
+		for {
+			appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Saving\n")
+			select {
+			case n.notifications <- notification{path: path}:
+			case <-stop:
+				return
+			}
+			time.Sleep(watchDebounce / 4)
+		}
+	}()
+
+	event := nextWatchEvent(t, w)
+	elapsed := time.Since(start)
+	assert.Equal(t, []FileChange{{Path: path, Op: FileModified}}, event.Changes)
+	assert.GreaterOrEqual(t, elapsed, watchMaxDelay)
+	assert.Less(t, elapsed, watchMaxDelay+2*watchDebounce)
+}
+
+func TestWatcher_PollsOnceNotifierFails(t *testing.T) {
+	n := newFakeNotifier()
+	w, path := startTestWatcher(t, n)
+
+	n.notifications <- notification{err: errors.New("inotify broke")}
+	appendTestLog(t, path, "[12:00:04] [Server thread/INFO]: Alex joined the game\n")
+
+	// Without notifications, the change is found by polling
+	event := nextWatchEvent(t, w)
+	assert.Equal(t, []FileChange{{Path: path, Op: FileModified}}, event.Changes)
+	assert.NoError(t, event.Err)
+	assert.True(t, n.closed.Load())
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"time"
+
+	"goparselogs/internal/fileops"
 	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/events"
 	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -13,6 +18,7 @@ const (
 	MenuView      AppState = iota // Main menu with log files and filter input
 	LogView                       // View for displaying logs
 	SaveInputView                 // View for entering filename to save
+	SearchView                    // View for the results of searching every log file
 )
 
 // Messages for save operation
@@ -27,10 +33,14 @@ const (
 )
 
 type Model struct {
-	State           AppState
-	FocusedPane     FocusablePane // To manage focus within menuView (or left pane in logView)
-	PreviousState   AppState      // To store the state before entering saveInputView
-	CoreProtectMode bool          // True if CoreProtect parsing is enabled
+	State             AppState
+	FocusedPane       FocusablePane       // To manage focus within menuView (or left pane in logView)
+	PreviousState     AppState            // To store the state before entering saveInputView
+	CoreProtectMode   bool                // True if CoreProtect parsing is enabled
+	CoreProtectForced bool                // True if CoreProtectMode was only turned on to open a CoreProtect database
+	Location          *time.Location      // Time zone used to reconstruct full log entry times
+	IndexCache        *fileops.IndexCache // Cache of log indexes, nil if caching is disabled
+	Watcher           *fileops.Watcher    // Reports changes to the log files, nil if not watching
 
 	// Window / Layout
 	TermWidth     int
@@ -38,24 +48,76 @@ type Model struct {
 	LeftPaneWidth int // Desired width for the left (menu) pane
 
 	// Menu View / Shared
-	MenuChoices []string // Log files + "Exit"
-	MenuCursor  int      // For logFilePane
-	FilterInput string   // Current text in filter input field
-	Filters     []string // List of active filters
-	InputActive bool     // True when filterInput has focus (i.e., focusedPane == filterPane)
+	LogSources  fileops.LogSources // Directories and files listed in the menu
+	MenuChoices []string           // Log files + "Exit"
+	FileGroups  map[string]string  // Source directory each log file was found in, empty for individually given files
+	FileFormats map[string]string  // Detected log format name per log file path
+	MenuCursor  int                // For logFilePane
+	Selected    map[string]bool    // Log files selected to be merged into a timeline
+	SelectFrom  string             // Last file selected or unselected, where a range selection starts
+	FilterInput string             // Current text in filter input field
+	Filters     []*query.Query     // List of active filter queries, matched with OR logic
+	FilterErr   error              // Error from parsing the last submitted filter
+	InputActive bool               // True when filterInput has focus (i.e., focusedPane == filterPane)
 
 	// Log View
-	LogEntries            []logparser.LogEntry
+	LogIndex              fileops.EntryIndex   // Background index of the standard log or merged timeline being viewed
+	LogEntries            []logparser.LogEntry // Window of standard log entries loaded from LogIndex
+	LogWindowStart        int                  // Index of the first entry in LogEntries
+	LogWindowLoading      bool                 // True while a new window is being read from LogIndex
+	Following             bool                 // True when new entries appended to the log are picked up
+	FollowPolling         bool                 // True while the followed log is being checked for appended entries
+	FollowPending         bool                 // True when the followed log changed again during the check
 	CoreProtectLogEntries []coreprotectparser.CoreProtectLogEntry
-	LogCursor             int   // cursor for log view (applies to either type of log)
-	Err                   error // General errors
+	CoreProtectSessions   []coreprotectparser.Session // Lookups the CoreProtect entries were read from
+	CoreProtectLoading    bool                        // True while a CoreProtect log is being parsed
+	LogCursor             int                         // cursor for log view (applies to either type of log)
+	LogOffset             int                         // Index of the first entry shown, which moves to keep the cursor in view
+	LogColumn             int                         // Number of columns the entries are scrolled to the right by when not wrapped
+	WrapLines             bool                        // True when long entries are wrapped onto more lines instead of cut off
+	JumpActive            bool                        // True while the number of the entry to jump to is being typed
+	JumpInput             string                      // Number of the entry to jump to typed so far
+	ShowDetails           bool                        // True when the entry under the cursor is shown in full below the list
+	DetailEntry           int                         // Entry the detail pane was scrolled for
+	DetailOffset          int                         // Number of lines the detail pane is scrolled down by
+	FindActive            bool                        // True while the text to search the loaded entries for is being typed
+	FindInput             string                      // Text to search for typed so far
+	FindPattern           string                      // Text last searched for, highlighted in the entries
+	FindBackward          bool                        // True when the search was started with ? and n moves up
+	FindOrigin            int                         // Entry the cursor was on when the search started
+	FindScan              *FindScan                   // Search of the whole log for FindPattern, nil if none
+	ExpandedEntries       map[int]bool                // Indices of standard log entries whose continuation lines are shown
+	Err                   error                       // General errors
+
+	// Event View (standard logs only)
+	EventMode      bool           // True when the log view lists extracted events instead of raw lines
+	Events         []events.Event // Events extracted from the whole log by LogIndex
+	EventTypeIndex int            // 0 shows all event types, otherwise 1 + index into events.AllTypes
+	EventCursor    int            // Cursor within the events of the selected type
+
+	// Player Summary View (CoreProtect only)
+	SummaryMode   bool                              // True when the log view lists players instead of CoreProtect entries
+	Summaries     []coreprotectparser.PlayerSummary // Activity of each player in the CoreProtect entries
+	SummaryCursor int                               // Cursor within Summaries
+	PlayerFilter  string                            // If set, only this player's CoreProtect entries are listed
+
+	// Search View
+	Search        *fileops.Search       // Search of every log file with the active filters, nil if none has been run
+	SearchResults []fileops.FileMatches // Results of Search as of the last poll
+	SearchCursor  int                   // Cursor within the rows of the search results
+	FromSearch    bool                  // True when the open log was jumped to from the search results
 
 	// Save Input View
-	SaveFilenameInput string
-	SaveMessage       string // To display "Saved!" or "Error saving."
+	OutputDir            string // Directory exports are saved in
+	SaveFilenameInput    string
+	SaveFormat           fileops.ExportFormat // Format to save in, following the filename's extension
+	SaveConfirmOverwrite bool                 // True while asking whether to replace an existing export
+	SaveCompletions      []string             // Directories matching the last completed filename
+	SaveMessage          string               // To display "Saved!" or "Error saving."
 
 	// Styles
 	HighlightStyle    lipgloss.Style
+	MatchStyle        lipgloss.Style // Text matching the search in the log view
 	SubtleStyle       lipgloss.Style
 	InputStyle        lipgloss.Style
 	FocusedInputStyle lipgloss.Style
@@ -64,3 +126,18 @@ type Model struct {
 	ErrorStyle        lipgloss.Style
 	SuccessStyle      lipgloss.Style
 }
+
+// FindScan is a search of every entry of the log being viewed for the text
+// found with / or ?, rather than only those loaded
+type FindScan struct {
+	Index   fileops.EntryIndex // Index searched
+	Pattern string             // Text searched for
+	Stop    chan struct{}      // Closed to stop the search early
+	Jump    bool               // Move the cursor to the first match once found, as no loaded entry matched
+
+	Done    bool
+	Err     error
+	Count   int          // Number of entries searched
+	Matches []int        // Entries matching, in order
+	InTrace map[int]bool // Matches found only in an entry's stack trace
+}
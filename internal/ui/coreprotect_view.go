@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"goparselogs/pkg/coreprotectparser"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// coreProtectActionColors gives each kind of CoreProtect lookup result its own colour
+var coreProtectActionColors = map[coreprotectparser.Action]lipgloss.Color{
+	coreprotectparser.ActionChat:    lipgloss.Color("252"), // Light grey
+	coreprotectparser.ActionCommand: lipgloss.Color("11"),  // Yellow
+	coreprotectparser.ActionSign:    lipgloss.Color("180"), // Tan
+	coreprotectparser.ActionPlace:   lipgloss.Color("10"),  // Green
+	coreprotectparser.ActionBreak:   lipgloss.Color("9"),   // Red
+	coreprotectparser.ActionClick:   lipgloss.Color("12"),  // Blue
+	coreprotectparser.ActionKill:    lipgloss.Color("13"),  // Magenta
+	coreprotectparser.ActionAdd:     lipgloss.Color("42"),  // Teal green
+	coreprotectparser.ActionRemove:  lipgloss.Color("208"), // Orange
+	coreprotectparser.ActionDrop:    lipgloss.Color("172"), // Dark orange
+	coreprotectparser.ActionPickup:  lipgloss.Color("78"),  // Pale green
+	coreprotectparser.ActionLogin:   lipgloss.Color("14"),  // Cyan
+	coreprotectparser.ActionLogout:  lipgloss.Color("245"), // Grey
+}
+
+// coreProtectActionStyle returns the style used to render entries with the given action
+func coreProtectActionStyle(action coreprotectparser.Action) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(coreProtectActionColors[action])
+}
+
+// formatCoreProtectLine renders a CoreProtect entry as a single line, tagged with its action
+func formatCoreProtectLine(entry coreprotectparser.CoreProtectLogEntry) string {
+	tag := fmt.Sprintf("%-9s", "["+strings.ToUpper(entry.Action.String())+"]")
+
+	var detail string
+	switch entry.Action {
+	case coreprotectparser.ActionChat, coreprotectparser.ActionCommand, coreprotectparser.ActionSign:
+		detail = fmt.Sprintf("%s: %s", entry.Username, entry.Message)
+	default:
+		detail = fmt.Sprintf("%s %s", entry.Username, entry.Message)
+	}
+
+	when := entry.AgoString() + " ago"
+	if at := entry.EventTimeString(); at != "" {
+		when += " (" + at + ")"
+	}
+	line := fmt.Sprintf("%s %s %s", when, tag, detail)
+	if entry.Location != nil {
+		line += fmt.Sprintf(" @ %s", entry.Location)
+	}
+	return line
+}
+
+// formatSessionSummary describes which pages of a lookup were read, so that
+// missing pages can be looked up again in game
+func formatSessionSummary(session coreprotectparser.Session) string {
+	summary := fmt.Sprintf("Lookup %d", session.Index+1)
+	if session.TotalPages > 0 {
+		summary += fmt.Sprintf(": %d of %d pages", len(session.Pages), session.TotalPages)
+		if missing := session.MissingPages(); len(missing) > 0 {
+			summary += ", missing " + coreprotectparser.FormatPageRanges(missing)
+		}
+	}
+	if session.DuplicatePages > 0 {
+		summary += fmt.Sprintf(", %d repeated page(s) dropped", session.DuplicatePages)
+	}
+	return summary
+}
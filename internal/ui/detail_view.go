@@ -0,0 +1,197 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"goparselogs/internal/models"
+	"goparselogs/pkg/coreprotectparser"
+	"goparselogs/pkg/logparser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// detailField is a labelled value shown in the detail pane
+type detailField struct {
+	label string
+	value string
+}
+
+// logEntryDetails lists everything known about a standard log entry
+func logEntryDetails(m models.Model, entry logparser.LogEntry, i int) []detailField {
+	fields := []detailField{{"Source", fmt.Sprintf("%s, line %d", entry.Source, entry.LineNumber)}}
+	if format, ok := m.FileFormats[entry.Source]; ok {
+		fields = append(fields, detailField{"Format", format})
+	}
+	if !entry.Time.IsZero() {
+		fields = append(fields, detailField{"Time", entry.Time.Format("2006-01-02 15:04:05 MST")})
+	} else {
+		fields = append(fields, detailField{"Time", entry.Timestamp + " (date unknown)"})
+	}
+	for _, field := range []detailField{
+		{"Date", entry.Date},
+		{"Thread", entry.Thread},
+		{"Level", entry.Level},
+		{"Logger", entry.Logger},
+	} {
+		if field.value != "" {
+			fields = append(fields, field)
+		}
+	}
+	fields = append(fields, detailField{"Message", entry.Message})
+	for _, event := range m.Events {
+		if event.EntryIndex == i {
+			fields = append(fields, detailField{"Event", formatEventLine(event)})
+		}
+	}
+	fields = append(fields, detailField{"Raw line", entry.RawLine})
+	if entry.HasContinuation() {
+		fields = append(fields, detailField{"Stack trace", fmt.Sprintf("%d lines", len(entry.Continuation))})
+		for _, line := range entry.Continuation {
+			fields = append(fields, detailField{"", strings.ReplaceAll(line, "\t", "    ")})
+		}
+	}
+	return fields
+}
+
+// coreProtectEntryDetails lists everything known about a CoreProtect entry,
+// decoding the action it records
+func coreProtectEntryDetails(m models.Model, entry coreprotectparser.CoreProtectLogEntry) []detailField {
+	var fields []detailField
+	if m.MenuCursor < len(m.MenuChoices) {
+		fields = append(fields, detailField{"Source", m.MenuChoices[m.MenuCursor]})
+	}
+	if entry.Session < len(m.CoreProtectSessions) {
+		lookup := formatSessionSummary(m.CoreProtectSessions[entry.Session])
+		if entry.Page > 0 {
+			lookup += fmt.Sprintf(" (entry on page %d)", entry.Page)
+		}
+		fields = append(fields, detailField{"Lookup", lookup})
+	}
+
+	when := entry.AgoString() + " ago"
+	if at := entry.EventTimeString(); at != "" {
+		when += ", at " + at
+		if entry.EventTimeError > 0 {
+			when += fmt.Sprintf(" (±%s)", entry.EventTimeError)
+		}
+	}
+	fields = append(fields,
+		detailField{"When", when},
+		detailField{"Player", entry.Username},
+		detailField{"Action", entry.Action.String()},
+	)
+	if entry.Target != "" {
+		fields = append(fields, detailField{"Target", entry.Target})
+	}
+	if entry.Amount > 0 {
+		fields = append(fields, detailField{"Amount", fmt.Sprintf("%d", entry.Amount)})
+	}
+	if entry.Location != nil {
+		fields = append(fields,
+			detailField{"Location", fmt.Sprintf("x %d, y %d, z %d", entry.Location.X, entry.Location.Y, entry.Location.Z)},
+			detailField{"World", entry.Location.World},
+		)
+	}
+	fields = append(fields, detailField{"Message", entry.Message})
+	if !entry.LoggedAt.IsZero() {
+		fields = append(fields, detailField{"Logged at", entry.LoggedAt.Format("2006-01-02 15:04:05 MST")})
+	}
+	fields = append(fields, detailField{"Raw line", entry.RawLine})
+	if entry.LocationLine != "" {
+		fields = append(fields, detailField{"Location line", entry.LocationLine})
+	}
+	return fields
+}
+
+// entryDetailRows returns the lines of the detail pane for the entry under the
+// cursor, wrapped to fit, or false if the entry hasn't been loaded yet
+func entryDetailRows(m models.Model, layout logViewLayout) ([]string, bool) {
+	var fields []detailField
+	if m.CoreProtectMode {
+		if m.LogCursor >= len(layout.cpEntries) {
+			return nil, false
+		}
+		fields = coreProtectEntryDetails(m, layout.cpEntries[m.LogCursor])
+	} else {
+		entry, ok := logEntryAt(m, m.LogCursor)
+		if !ok {
+			return nil, false
+		}
+		fields = logEntryDetails(m, entry, m.LogCursor)
+	}
+
+	labelWidth := 0
+	for _, field := range fields {
+		labelWidth = Max(labelWidth, len(field.label))
+	}
+	var rows []string
+	for _, field := range fields {
+		label := strings.Repeat(" ", labelWidth+2)
+		if field.label != "" {
+			label = fmt.Sprintf("%-*s", labelWidth+2, field.label+":")
+		}
+		// Multi-line values such as JSON payloads keep their own line breaks
+		for _, line := range strings.Split(field.value, "\n") {
+			rows = append(rows, fitLine(label, line, layout.width, true, 0)...)
+			label = strings.Repeat(" ", labelWidth+2)
+		}
+	}
+	return rows, true
+}
+
+// detailOffset returns how far the detail pane is scrolled, which is only
+// kept while the cursor stays on the same entry
+func detailOffset(m models.Model) int {
+	if m.DetailEntry != m.LogCursor {
+		return 0
+	}
+	return m.DetailOffset
+}
+
+// handleDetailInput handles the keys that show and scroll the detail pane. It
+// returns false for keys that should fall through to the regular log view handling.
+func handleDetailInput(msg tea.KeyMsg, m models.Model) (bool, models.Model) {
+	switch msg.String() {
+	case "d":
+		m.ShowDetails = !m.ShowDetails
+	case "J", "K":
+		if !m.ShowDetails {
+			return false, m
+		}
+		offset := detailOffset(m)
+		if msg.String() == "J" {
+			offset++
+		} else {
+			offset--
+		}
+		layout := newLogViewLayout(m)
+		rows, _ := entryDetailRows(m, layout)
+		m.DetailEntry = m.LogCursor
+		m.DetailOffset = Max(Min(offset, len(rows)-layout.detailLines), 0)
+	default:
+		return false, m
+	}
+	return true, m
+}
+
+// renderEntryDetails renders the detail pane for the entry under the cursor
+func renderEntryDetails(m models.Model, layout logViewLayout) string {
+	var view strings.Builder
+	view.WriteString(m.SubtleStyle.Render(strings.Repeat("─", layout.width+2)) + "\n")
+
+	rows, ok := entryDetailRows(m, layout)
+	if !ok {
+		view.WriteString("Loading entry...\n")
+		return view.String()
+	}
+	offset := Max(Min(detailOffset(m), len(rows)-layout.detailLines), 0)
+	end := Min(offset+layout.detailLines, len(rows))
+	for _, row := range rows[offset:end] {
+		view.WriteString(row + "\n")
+	}
+	if len(rows) > layout.detailLines {
+		view.WriteString(m.SubtleStyle.Render(fmt.Sprintf("Details %d-%d of %d lines (J/K: Scroll, D: Hide)", offset+1, end, len(rows))) + "\n")
+	}
+	return view.String()
+}
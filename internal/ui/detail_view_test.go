@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"goparselogs/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const detailTestLog = "[12:00:00] [Server thread/INFO]: Starting\n" +
+	"[12:00:01] [Server thread/WARN]: Can't keep up!\n" +
+	"java.lang.Exception: slow\n" +
+	"\tat Example.run(Example.java:1)\n"
+
+// renderedDetails renders the detail pane, returning its lines after the rule above it
+func renderedDetails(t *testing.T, m models.Model) []string {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(renderEntryDetails(m, newLogViewLayout(m)), "\n"), "\n")
+	require.NotEmpty(t, lines)
+	assert.Equal(t, strings.Repeat("─", newLogViewLayout(m).width+2), lines[0])
+	return lines[1:]
+}
+
+func TestRenderEntryDetails_MultiLineEntry(t *testing.T) {
+	m := testLogModel(t, detailTestLog)
+	m.ShowDetails = true
+	m.TermWidth = 200 // Wide enough for the log's temporary path
+	m.LogCursor = 1
+	source := m.LogEntries[1].Source
+
+	// The first lines fill the pane, with a footer saying how many there are
+	lines := renderedDetails(t, m)
+	require.Len(t, lines, 6)
+	assert.Equal(t, "Source:      "+source+", line 2", lines[0])
+	assert.Equal(t, "Format:      vanilla", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "Time:        "))
+	assert.Equal(t, "Details 1-5 of 10 lines (J/K: Scroll, D: Hide)", lines[5])
+
+	// Scrolled to the end, the stack trace is listed a line at a time under its label
+	for i := 0; i < 10; i++ {
+		_, m = handleDetailInput(keyMsg("J"), m)
+	}
+	assert.Equal(t, 5, m.DetailOffset, "stops at the last line")
+	assert.Equal(t, []string{
+		"Message:     Can't keep up!",
+		"Raw line:    [12:00:01] [Server thread/WARN]: Can't keep up!",
+		"Stack trace: 2 lines",
+		"             java.lang.Exception: slow",
+		"                 at Example.run(Example.java:1)",
+		"Details 6-10 of 10 lines (J/K: Scroll, D: Hide)",
+	}, renderedDetails(t, m))
+
+	_, m = handleDetailInput(keyMsg("K"), m)
+	assert.Equal(t, "Details 5-9 of 10 lines (J/K: Scroll, D: Hide)", renderedDetails(t, m)[5])
+
+	// Moving to another entry starts from its top
+	m.LogCursor = 0
+	lines = renderedDetails(t, m)
+	assert.Equal(t, "Source:   "+source+", line 1", lines[0])
+	assert.Equal(t, "Details 1-5 of 7 lines (J/K: Scroll, D: Hide)", lines[5])
+}
+
+func TestRenderEntryDetails_WrapsLongValues(t *testing.T) {
+	m := testLogModel(t, "[12:00:00] [Server thread/INFO]: "+strings.Repeat("word ", 20)+"\n")
+	m.ShowDetails = true
+	m.TermHeight = 40
+	layout := newLogViewLayout(m)
+
+	rows, ok := entryDetailRows(m, layout)
+	require.True(t, ok)
+	var message []string
+	for i, row := range rows {
+		assert.LessOrEqual(t, len(row), layout.width)
+		if strings.HasPrefix(row, "Message:") {
+			message = rows[i : i+2]
+		}
+	}
+	require.Len(t, message, 2)
+	assert.True(t, strings.HasPrefix(message[1], "          word"), "wrapped lines are indented under the label")
+}
+
+func TestRenderEntryDetails_EntryNotLoaded(t *testing.T) {
+	m := testLogModel(t, detailTestLog)
+	m.ShowDetails = true
+	m.LogCursor = 5
+	assert.Equal(t, []string{"Loading entry..."}, renderedDetails(t, m))
+}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"goparselogs/internal/models"
+	"goparselogs/pkg/events"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// selectedEventType returns the event type selected in the event view, or false if all types are shown
+func selectedEventType(m models.Model) (events.Type, bool) {
+	if m.EventTypeIndex <= 0 || m.EventTypeIndex > len(events.AllTypes) {
+		return 0, false
+	}
+	return events.AllTypes[m.EventTypeIndex-1], true
+}
+
+// visibleEvents returns the events of the selected type
+func visibleEvents(m models.Model) []events.Event {
+	if t, ok := selectedEventType(m); ok {
+		return events.FilterByType(m.Events, t)
+	}
+	return m.Events
+}
+
+// handleEventViewInput handles the keys specific to the event view. It returns
+// false for keys that should fall through to the regular log view handling.
+func handleEventViewInput(msg tea.KeyMsg, m models.Model) (bool, models.Model) {
+	switch msg.String() {
+	case "up", "k":
+		if m.EventCursor > 0 {
+			m.EventCursor--
+		}
+	case "down", "j":
+		if m.EventCursor < len(visibleEvents(m))-1 {
+			m.EventCursor++
+		}
+	case "left", "h":
+		m.EventTypeIndex = (m.EventTypeIndex + len(events.AllTypes)) % (len(events.AllTypes) + 1)
+		m.EventCursor = 0
+	case "right", "l":
+		m.EventTypeIndex = (m.EventTypeIndex + 1) % (len(events.AllTypes) + 1)
+		m.EventCursor = 0
+	case "enter":
+		// Jump to the raw log entry behind the selected event
+		visible := visibleEvents(m)
+		if m.EventCursor < len(visible) {
+			m.LogCursor = visible[m.EventCursor].EntryIndex
+			m.EventMode = false
+			m = centreLogView(m)
+		}
+	default:
+		return false, m
+	}
+	return true, m
+}
+
+// renderEventTypeTabs renders the event type selector with per-type counts
+func renderEventTypeTabs(m models.Model) string {
+	counts := events.CountByType(m.Events)
+	tabs := make([]string, 0, len(events.AllTypes)+1)
+
+	label := fmt.Sprintf("All (%d)", len(m.Events))
+	if m.EventTypeIndex == 0 {
+		label = m.HighlightStyle.Render("[" + label + "]")
+	}
+	tabs = append(tabs, label)
+
+	for i, t := range events.AllTypes {
+		label := fmt.Sprintf("%s (%d)", t, counts[t])
+		if m.EventTypeIndex == i+1 {
+			label = m.HighlightStyle.Render("[" + label + "]")
+		} else if counts[t] == 0 {
+			// Keep the selector to one line by hiding empty types
+			continue
+		}
+		tabs = append(tabs, label)
+	}
+	return strings.Join(tabs, "  ")
+}
+
+// formatEventLine renders a single event as a line of the event list
+func formatEventLine(event events.Event) string {
+	var line strings.Builder
+	line.WriteString(fmt.Sprintf("[%s] %-12s", event.Entry.Timestamp, strings.ToUpper(event.Type.String())))
+	if event.Player != "" {
+		line.WriteString(" " + event.Player)
+	}
+
+	switch event.Type {
+	case events.Join:
+		var details []string
+		if event.UUID != "" {
+			details = append(details, event.UUID)
+		}
+		if event.IP != "" {
+			details = append(details, event.IP)
+		}
+		if len(details) > 0 {
+			line.WriteString(fmt.Sprintf(" (%s)", strings.Join(details, ", ")))
+		}
+	case events.Chat:
+		line.WriteString(": " + event.Detail)
+	case events.Kick, events.Ban:
+		if event.Actor != "" {
+			line.WriteString(" by " + event.Actor)
+		}
+		if event.Detail != "" {
+			line.WriteString(": " + event.Detail)
+		}
+	default:
+		if event.Detail != "" {
+			line.WriteString(" " + event.Detail)
+		}
+	}
+	return line.String()
+}
+
+// renderEventList renders the events of the selected type into the right pane
+func renderEventList(m models.Model, maxLineTextWidth, availableLines int) string {
+	var view strings.Builder
+
+	view.WriteString("Events (LEFT/RIGHT: Type, ENTER: Go to line, V: Raw log):\n")
+	view.WriteString(renderEventTypeTabs(m) + "\n\n")
+
+	visible := visibleEvents(m)
+	if len(visible) == 0 {
+		view.WriteString("No events of this type.")
+		return view.String()
+	}
+
+	// The type selector takes up two extra lines
+	start, end := visibleEntryRange(m.EventCursor, len(visible), Max(availableLines-2, 1), func(int) int { return 1 })
+	for i := start; i < end; i++ {
+		line := formatEventLine(visible[i])
+		if len(line) > maxLineTextWidth {
+			line = line[:maxLineTextWidth-3] + "..."
+		}
+		if i == m.EventCursor {
+			view.WriteString(m.HighlightStyle.Render("> "+line) + "\n")
+		} else {
+			view.WriteString("  " + line + "\n")
+		}
+	}
+	view.WriteString(fmt.Sprintf("\nViewing %d-%d of %d\n", start+1, end, len(visible)))
+
+	return view.String()
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+)
+
+// buildMenuChoices lists the files of each group followed by the CoreProtect
+// toggle and exit, along with the source directory of each file
+func buildMenuChoices(groups []fileops.LogGroup, coreProtectMode bool) ([]string, map[string]string) {
+	files := groupFiles(groups)
+	choices := make([]string, 0, len(files)+2) // +2 for toggle and exit
+	choices = append(choices, files...)
+	choices = append(choices,
+		fmt.Sprintf("%s (%s)", CoreProtectToggleBaseText, map[bool]string{true: "ON", false: "OFF"}[coreProtectMode]),
+		ExitText,
+	)
+
+	fileGroups := make(map[string]string, len(files))
+	for _, group := range groups {
+		for _, file := range group.Files {
+			fileGroups[file] = group.Root
+		}
+	}
+	return choices, fileGroups
+}
+
+// groupFiles returns the files of all groups, in order
+func groupFiles(groups []fileops.LogGroup) []string {
+	var files []string
+	for _, group := range groups {
+		files = append(files, group.Files...)
+	}
+	return files
+}
+
+// menuFiles returns the files listed in the menu, in order
+func menuFiles(m models.Model) []string {
+	var files []string
+	for _, choice := range m.MenuChoices {
+		if _, ok := m.FileGroups[choice]; ok {
+			files = append(files, choice)
+		}
+	}
+	return files
+}
+
+// menuGrouped reports whether files from more than one source are listed,
+// in which case they are shown under a heading per source
+func menuGrouped(m models.Model) bool {
+	roots := map[string]bool{}
+	for _, root := range m.FileGroups {
+		roots[root] = true
+	}
+	return len(roots) > 1
+}
+
+// menuGroupHeader returns the heading to show above menu choice i if it is
+// the first file of its source
+func menuGroupHeader(m models.Model, i int) (string, bool) {
+	root, ok := m.FileGroups[m.MenuChoices[i]]
+	if !ok || !menuGrouped(m) {
+		return "", false
+	}
+	if i > 0 {
+		if previous, ok := m.FileGroups[m.MenuChoices[i-1]]; ok && previous == root {
+			return "", false
+		}
+	}
+	if root == "" {
+		return "Files:", true
+	}
+	return root + ":", true
+}
+
+// menuChoiceLabel returns the text shown for menu choice i, without the
+// source directory when it is shown in a heading
+func menuChoiceLabel(m models.Model, i int) string {
+	choice := m.MenuChoices[i]
+	root, ok := m.FileGroups[choice]
+	if !ok || !menuGrouped(m) {
+		return choice
+	}
+	if root != "" {
+		choice = strings.TrimPrefix(choice, strings.TrimSuffix(root, "/")+"/")
+	}
+	return "  " + choice
+}
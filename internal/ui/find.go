@@ -0,0 +1,359 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"goparselogs/internal/models"
+	"goparselogs/pkg/logparser"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errFindStopped stops searching the whole log once the search is replaced
+var errFindStopped = errors.New("find stopped")
+
+// findScanMsg carries the entries of the whole log matching the text searched for
+type findScanMsg struct {
+	scan    *models.FindScan
+	count   int
+	matches []int
+	inTrace map[int]bool
+	err     error
+}
+
+// findPattern returns the text being searched for in the log view, as typed so
+// far while it is being entered
+func findPattern(m models.Model) string {
+	if m.FindActive {
+		return m.FindInput
+	}
+	return m.FindPattern
+}
+
+// findRegexp returns a case-insensitive expression matching the text literally, nil for no text
+func findRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+}
+
+// loadedEntryRange returns the [start, end) range of entries that can be
+// searched without reading the log again
+func loadedEntryRange(m models.Model, layout logViewLayout) (int, int) {
+	if m.CoreProtectMode {
+		return 0, len(layout.cpEntries)
+	}
+	return m.LogWindowStart, m.LogWindowStart + len(m.LogEntries)
+}
+
+// entryMatches reports whether entry i contains a match on its own line, or
+// only in its stack trace
+func entryMatches(m models.Model, layout logViewLayout, re *regexp.Regexp, i int) (onLine, inTrace bool) {
+	if m.CoreProtectMode {
+		return re.MatchString(formatCoreProtectLine(layout.cpEntries[i])), false
+	}
+	entry, ok := logEntryAt(m, i)
+	if !ok {
+		return false, false
+	}
+	return logEntryMatches(re, entry)
+}
+
+// logEntryMatches reports whether a standard log entry contains a match on its
+// own line, or only in its stack trace
+func logEntryMatches(re *regexp.Regexp, entry logparser.LogEntry) (onLine, inTrace bool) {
+	if re.MatchString(formatLogEntryLine(entry)) {
+		return true, false
+	}
+	for _, line := range entry.Continuation {
+		if re.MatchString(line) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// findMatches returns the loaded entries matching the expression, in order
+func findMatches(m models.Model, layout logViewLayout, re *regexp.Regexp) []int {
+	var matches []int
+	start, end := loadedEntryRange(m, layout)
+	for i := start; i < end; i++ {
+		if onLine, inTrace := entryMatches(m, layout, re, i); onLine || inTrace {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// findNext moves the cursor to the next entry matching the pattern after from
+// in the given direction, wrapping around at the end of the log. Only the
+// loaded entries are searched until the whole log has been. A match only in a
+// collapsed stack trace expands it. The cursor stays put if nothing matches.
+func findNext(m models.Model, pattern string, from int, forward bool) models.Model {
+	re := findRegexp(pattern)
+	if re == nil {
+		return m
+	}
+	if scan, ok := findScanResults(m, pattern); ok {
+		if len(scan.Matches) == 0 {
+			return m
+		}
+		// The first match after from, or the last before it
+		k := sort.SearchInts(scan.Matches, from+1)
+		if !forward {
+			k = sort.SearchInts(scan.Matches, from) - 1
+		}
+		i := scan.Matches[(k+len(scan.Matches))%len(scan.Matches)]
+		if scan.InTrace[i] {
+			if m.ExpandedEntries == nil {
+				m.ExpandedEntries = map[int]bool{}
+			}
+			m.ExpandedEntries[i] = true
+		}
+		m.LogCursor = i
+		return m
+	}
+
+	layout := newLogViewLayout(m)
+	start, end := loadedEntryRange(m, layout)
+	if end <= start {
+		return m
+	}
+	step := 1
+	if !forward {
+		step = -1
+	}
+	count := end - start
+	for n := 1; n <= count; n++ {
+		i := start + ((from-start+n*step)%count+count)%count
+		onLine, inTrace := entryMatches(m, layout, re, i)
+		if !onLine && !inTrace {
+			continue
+		}
+		if inTrace {
+			if m.ExpandedEntries == nil {
+				m.ExpandedEntries = map[int]bool{}
+			}
+			m.ExpandedEntries[i] = true
+		}
+		m.LogCursor = i
+		return m
+	}
+	return m
+}
+
+// handleFindKeys handles the keys that start searching the log view and move
+// between matches. It returns false for keys that should fall through to the
+// regular log view handling.
+func handleFindKeys(msg tea.KeyMsg, m models.Model) (bool, models.Model) {
+	switch msg.String() {
+	case "/", "?":
+		m.FindActive = true
+		m.FindInput = ""
+		m.FindBackward = msg.String() == "?"
+		m.FindOrigin = m.LogCursor
+	case "n":
+		m = findNext(m, m.FindPattern, m.LogCursor, !m.FindBackward)
+	case "N":
+		m = findNext(m, m.FindPattern, m.LogCursor, m.FindBackward)
+	default:
+		return false, m
+	}
+	return true, m
+}
+
+// handleFindInput handles typing the text to search for, moving the cursor to
+// the first match as it is typed
+func handleFindInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		// Back to where the search started
+		m.FindActive = false
+		m.LogCursor = m.FindOrigin
+	case "enter":
+		m.FindActive = false
+		if m.FindInput != "" {
+			m.FindPattern = m.FindInput
+			m = stopFindScan(m)
+		} else {
+			// Search for the last text again
+			m = findNext(m, m.FindPattern, m.LogCursor, !m.FindBackward)
+		}
+		var cmd tea.Cmd
+		m, cmd = startFindScan(m)
+		if m.FindScan != nil && !m.FindScan.Done && m.LogCursor == m.FindOrigin {
+			if onLine, inTrace := entryMatches(m, newLogViewLayout(m), findRegexp(m.FindPattern), m.LogCursor); !onLine && !inTrace {
+				// Nothing loaded matched, so go to the first match anywhere once found
+				m.FindScan.Jump = true
+			}
+		}
+		m, windowCmd := ensureLogWindow(scrollLogView(m))
+		return m, tea.Batch(cmd, windowCmd)
+	case "backspace":
+		if len(m.FindInput) > 0 {
+			runes := []rune(m.FindInput)
+			m.FindInput = string(runes[:len(runes)-1])
+		}
+		m.LogCursor = m.FindOrigin
+		m = findNext(m, m.FindInput, m.FindOrigin, !m.FindBackward)
+	default:
+		if (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && len(msg.Runes) > 0 {
+			m.FindInput += string(msg.Runes)
+			m.LogCursor = m.FindOrigin
+			m = findNext(m, m.FindInput, m.FindOrigin, !m.FindBackward)
+		}
+	}
+	return ensureLogWindow(scrollLogView(m))
+}
+
+// highlightMatches renders a row of the log view in the base style, with the
+// parts matching the text being found in the match style
+func highlightMatches(row logRow, base, match lipgloss.Style) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range row.matches {
+		if span[0] > last {
+			b.WriteString(base.Render(row.text[last:span[0]]))
+		}
+		b.WriteString(match.Render(row.text[span[0]:span[1]]))
+		last = span[1]
+	}
+	if last < len(row.text) {
+		b.WriteString(base.Render(row.text[last:]))
+	}
+	return b.String()
+}
+
+// findStatus describes where the cursor is among the entries matching the
+// pattern, for the footer of the log view. Until the whole log has been
+// searched, only the loaded entries are counted.
+func findStatus(m models.Model, layout logViewLayout) string {
+	pattern := findPattern(m)
+	re := findRegexp(pattern)
+	if re == nil {
+		return ""
+	}
+	if scan, ok := findScanResults(m, pattern); ok {
+		return matchStatus(scan.Matches, m.LogCursor, "")
+	}
+
+	suffix := ""
+	if start, end := loadedEntryRange(m, layout); start > 0 || end < logEntryCount(m) {
+		suffix = " in loaded entries"
+	}
+	if scan := m.FindScan; scan != nil && !scan.Done && scan.Pattern == pattern {
+		suffix += ", searching..."
+	}
+	return matchStatus(findMatches(m, layout, re), m.LogCursor, suffix)
+}
+
+// matchStatus describes where the cursor is among the matches
+func matchStatus(matches []int, cursor int, suffix string) string {
+	if len(matches) == 0 {
+		return "no matches" + suffix
+	}
+	if k := sort.SearchInts(matches, cursor); k < len(matches) && matches[k] == cursor {
+		return fmt.Sprintf("match %d of %d%s", k+1, len(matches), suffix)
+	}
+	return fmt.Sprintf("%d matches%s", len(matches), suffix)
+}
+
+// findScanResults returns the search of the whole log for the pattern, if it
+// has finished and the log hasn't changed since
+func findScanResults(m models.Model, pattern string) (*models.FindScan, bool) {
+	scan := m.FindScan
+	if scan == nil || !scan.Done || scan.Err != nil || scan.Pattern != pattern ||
+		m.CoreProtectMode || scan.Index != m.LogIndex || scan.Count != logEntryCount(m) {
+		return nil, false
+	}
+	return scan, true
+}
+
+// startFindScan starts searching the whole log for FindPattern in the
+// background, unless it has been searched already or is still being indexed.
+// CoreProtect entries are all loaded, so they needn't be.
+func startFindScan(m models.Model) (models.Model, tea.Cmd) {
+	if m.FindPattern == "" || m.CoreProtectMode || m.LogIndex == nil {
+		return stopFindScan(m), nil
+	}
+	if scan := m.FindScan; scan != nil && scan.Index == m.LogIndex && scan.Pattern == m.FindPattern &&
+		(!scan.Done || scan.Count == m.LogIndex.Len()) {
+		// Searched or being searched already; one that is out of date is redone when it finishes
+		return m, nil
+	}
+	if done, _ := m.LogIndex.Done(); !done {
+		return m, nil
+	}
+
+	m = stopFindScan(m)
+	m.FindScan = &models.FindScan{Index: m.LogIndex, Pattern: m.FindPattern, Stop: make(chan struct{})}
+	return m, findScanCmd(m.FindScan)
+}
+
+// stopFindScan stops and forgets any search of the whole log
+func stopFindScan(m models.Model) models.Model {
+	if m.FindScan != nil && !m.FindScan.Done {
+		close(m.FindScan.Stop)
+	}
+	m.FindScan = nil
+	return m
+}
+
+// findScanCmd searches every entry of the index for the pattern of the scan
+func findScanCmd(scan *models.FindScan) tea.Cmd {
+	index, re, stop := scan.Index, findRegexp(scan.Pattern), scan.Stop
+	return func() tea.Msg {
+		msg := findScanMsg{scan: scan, count: index.Len(), inTrace: map[int]bool{}}
+		msg.err = index.Each(func(i int, entry logparser.LogEntry) error {
+			select {
+			case <-stop:
+				return errFindStopped
+			default:
+			}
+			if onLine, inTrace := logEntryMatches(re, entry); onLine || inTrace {
+				msg.matches = append(msg.matches, i)
+				if !onLine {
+					msg.inTrace[i] = true
+				}
+			}
+			return nil
+		})
+		return msg
+	}
+}
+
+// handleFindScan records the matches found by searching the whole log,
+// searching it again if it has grown since
+func handleFindScan(msg findScanMsg, m models.Model) (models.Model, tea.Cmd) {
+	scan := m.FindScan
+	if scan != msg.scan || errors.Is(msg.err, errFindStopped) {
+		return m, nil // Replaced by another search
+	}
+	// The scan is replaced rather than updated, as earlier copies of the model share it
+	m.FindScan = &models.FindScan{
+		Index:   scan.Index,
+		Pattern: scan.Pattern,
+		Stop:    scan.Stop,
+		Done:    true,
+		Err:     msg.err,
+		Count:   msg.count,
+		Matches: msg.matches,
+		InTrace: msg.inTrace,
+	}
+	if msg.err != nil {
+		m.Err = msg.err
+		return m, nil
+	}
+	if scan.Jump && m.LogCursor == m.FindOrigin {
+		m = findNext(m, scan.Pattern, m.LogCursor, !m.FindBackward)
+	}
+	m, cmd := startFindScan(m)
+	m, windowCmd := ensureLogWindow(scrollLogView(m))
+	return m, tea.Batch(cmd, windowCmd)
+}
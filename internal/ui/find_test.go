@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"goparselogs/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findTestModel returns a model viewing a log of 3000 entries with the first
+// window of them loaded. Entries 10, 500 and 2500 contain "needle", as does
+// the stack trace of entry 1500.
+func findTestModel(t *testing.T) models.Model {
+	t.Helper()
+	var log strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&log, "[12:00:00] [Server thread/INFO]: Entry %d", i)
+		if i == 10 || i == 500 || i == 2500 {
+			log.WriteString(" needle")
+		}
+		log.WriteString("\n")
+		if i == 1500 {
+			log.WriteString("\tat Needle.run(Needle.java:1)\n")
+		}
+	}
+	m := testLogModel(t, log.String())
+	m.LogEntries = m.LogEntries[:logWindowSize]
+	return m
+}
+
+// finishFindScan runs the search of the whole log the model started
+func finishFindScan(t *testing.T, m models.Model) models.Model {
+	t.Helper()
+	require.NotNil(t, m.FindScan)
+	msg, ok := findScanCmd(m.FindScan)().(findScanMsg)
+	require.True(t, ok)
+	m, _ = handleFindScan(msg, m)
+	require.True(t, m.FindScan.Done)
+	return m
+}
+
+func TestFindStatus_LoadedEntries(t *testing.T) {
+	m := findTestModel(t)
+	m.FindPattern = "needle"
+	m.LogCursor = 10
+	layout := newLogViewLayout(m)
+	assert.Equal(t, "match 1 of 2 in loaded entries", findStatus(m, layout))
+	m.LogCursor = 11
+	assert.Equal(t, "2 matches in loaded entries", findStatus(m, layout))
+	m.FindPattern = "haystack"
+	assert.Equal(t, "no matches in loaded entries", findStatus(m, layout))
+
+	// Once every entry is loaded there is nothing more to search
+	m = testLogModel(t, numberedLog(20))
+	m.FindPattern = "Entry 1"
+	m.LogCursor = 10
+	assert.Equal(t, "match 3 of 11", findStatus(m, newLogViewLayout(m)))
+}
+
+func TestFindScan_WholeLog(t *testing.T) {
+	m := findTestModel(t)
+	m.FindPattern = "needle"
+	m.LogCursor = 10
+	m, cmd := startFindScan(m)
+	require.NotNil(t, cmd)
+	assert.Equal(t, "match 1 of 2 in loaded entries, searching...", findStatus(m, newLogViewLayout(m)))
+
+	// Searching the same text again waits for the search already running
+	scan := m.FindScan
+	m, cmd = startFindScan(m)
+	assert.Nil(t, cmd)
+	assert.Same(t, scan, m.FindScan)
+
+	m = finishFindScan(t, m)
+	assert.Equal(t, []int{10, 500, 1500, 2500}, m.FindScan.Matches)
+	assert.Equal(t, map[int]bool{1500: true}, m.FindScan.InTrace)
+	assert.Equal(t, "match 1 of 4", findStatus(m, newLogViewLayout(m)))
+
+	// n and N move to matches outside the loaded entries, which are then loaded
+	m.LogCursor = 500
+	handled, m := handleFindKeys(keyMsg("n"), m)
+	require.True(t, handled)
+	assert.Equal(t, 1500, m.LogCursor)
+	assert.True(t, m.ExpandedEntries[1500], "a match in a stack trace expands it")
+	m, cmd = ensureLogWindow(m)
+	assert.NotNil(t, cmd)
+
+	_, m = handleFindKeys(keyMsg("n"), m)
+	assert.Equal(t, 2500, m.LogCursor)
+	_, m = handleFindKeys(keyMsg("n"), m)
+	assert.Equal(t, 10, m.LogCursor, "wraps around to the start")
+	_, m = handleFindKeys(keyMsg("N"), m)
+	assert.Equal(t, 2500, m.LogCursor, "wraps around to the end")
+	_, m = handleFindKeys(keyMsg("N"), m)
+	assert.Equal(t, 1500, m.LogCursor)
+
+	// From between matches
+	m.LogCursor = 700
+	_, m = handleFindKeys(keyMsg("N"), m)
+	assert.Equal(t, 500, m.LogCursor)
+}
+
+func TestFindScan_JumpsToFirstMatchOutsideLoadedEntries(t *testing.T) {
+	m := findTestModel(t)
+	m.LogCursor = 100
+	_, m = handleFindKeys(keyMsg("/"), m)
+	for _, key := range []string{"E", "n", "t", "r", "y", " ", "2", "9", "9", "9", "enter"} {
+		m, _ = handleFindInput(keyMsg(key), m)
+	}
+	assert.Equal(t, "Entry 2999", m.FindPattern)
+	assert.Equal(t, 100, m.LogCursor, "nothing loaded matches")
+	require.NotNil(t, m.FindScan)
+	assert.True(t, m.FindScan.Jump)
+
+	m = finishFindScan(t, m)
+	assert.Equal(t, 2999, m.LogCursor)
+	assert.Equal(t, "match 1 of 1", findStatus(m, newLogViewLayout(m)))
+}
+
+func TestFindScan_Stopped(t *testing.T) {
+	m := findTestModel(t)
+	m.FindPattern = "needle"
+	m, _ = startFindScan(m)
+	scan := m.FindScan
+
+	// Clearing the search stops it, and its results are ignored
+	m, _ = handleLogViewInput(keyMsg("esc"), m)
+	assert.Empty(t, m.FindPattern)
+	assert.Nil(t, m.FindScan)
+	msg := findScanCmd(scan)().(findScanMsg)
+	assert.ErrorIs(t, msg.err, errFindStopped)
+	m, _ = handleFindScan(msg, m)
+	assert.Nil(t, m.FindScan)
+
+	// As are those of a search replaced by another
+	m.FindPattern = "needle"
+	m, _ = startFindScan(m)
+	old := m.FindScan
+	m.FindPattern = "Entry 1"
+	m, _ = startFindScan(m)
+	assert.NotSame(t, old, m.FindScan)
+	m, _ = handleFindScan(findScanMsg{scan: old, count: 3000, matches: []int{10}}, m)
+	assert.False(t, m.FindScan.Done)
+}
+
+func TestFitLineRows_MatchesAcrossWrappedRows(t *testing.T) {
+	rows := fitLineRows("", "abcdefneedle needle", 10, true, 0, findRegexp("NEEDLE"))
+	assert.Equal(t, []logRow{
+		{text: "abcdefneed", matches: [][2]int{{6, 10}}},
+		{text: "le needle", matches: [][2]int{{0, 2}, {3, 9}}},
+	}, rows)
+
+	// Ranges are in bytes, after the prefix and any columns scrolled past
+	rows = fitLineRows("é | ", "aéneedle", 20, false, 1, findRegexp("ÉNEED"))
+	assert.Equal(t, []logRow{{text: "é | éneedle", matches: [][2]int{{5, 11}}}}, rows)
+
+	// Only the part of a match before a line is cut off is marked
+	rows = fitLineRows("", "abcdefneedle", 10, false, 0, findRegexp("needle"))
+	assert.Equal(t, []logRow{{text: "abcdefn...", matches: [][2]int{{6, 7}}}}, rows)
+
+	// The log view marks matches in wrapped entries the same way
+	m := testLogModel(t, "[12:00:00] [Server thread/INFO]: "+strings.Repeat("x", testLogWidth-len("[12:00:00] [Server thread/INFO]: ")-3)+"needle\n")
+	m.WrapLines = true
+	m.FindPattern = "needle"
+	rows = logEntryRows(m, newLogViewLayout(m), 0)
+	require.Len(t, rows, 2)
+	assert.Equal(t, [][2]int{{testLogWidth - 3, testLogWidth}}, rows[0].matches)
+	assert.Equal(t, logRow{text: "dle", matches: [][2]int{{0, 3}}}, rows[1])
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"errors"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logRefreshMsg is sent after checking the followed log for appended entries
+type logRefreshMsg struct {
+	index     *fileops.LogIndex
+	prevCount int // Number of entries before the refresh
+	from      int // Index of the first entry added or changed
+	err       error
+}
+
+// followLogCmd picks up any entries appended to the log
+func followLogCmd(index *fileops.LogIndex) tea.Cmd {
+	return func() tea.Msg {
+		prevCount := index.Len()
+		from, err := index.Refresh()
+		return logRefreshMsg{index: index, prevCount: prevCount, from: from, err: err}
+	}
+}
+
+// scheduleFollow checks the followed log for appended entries, or checks
+// again once the check already running is done
+func scheduleFollow(m models.Model) (models.Model, tea.Cmd) {
+	index, ok := m.LogIndex.(*fileops.LogIndex)
+	if !m.Following || !ok {
+		return m, nil
+	}
+	if m.FollowPolling {
+		m.FollowPending = true
+		return m, nil
+	}
+	m.FollowPolling = true
+	m.FollowPending = false
+	return m, followLogCmd(index)
+}
+
+// toggleFollow turns follow mode on or off, jumping to the end of the log when turned on
+func toggleFollow(m models.Model) (models.Model, tea.Cmd) {
+	if _, ok := m.LogIndex.(*fileops.LogIndex); !ok {
+		return m, nil // Timelines are built from whole logs
+	}
+	m.Following = !m.Following
+	if !m.Following {
+		m = unfollow(m)
+		return m, nil
+	}
+	if m.Watcher != nil {
+		// The watcher reports each change to the log, even outside the sources
+		m.Watcher.Follow(m.LogIndex.(*fileops.LogIndex).Path)
+	}
+	m.LogCursor = Max(logEntryCount(m)-1, 0)
+	return scheduleFollow(m)
+}
+
+// unfollow stops the watcher reporting changes to the log that was being followed
+func unfollow(m models.Model) models.Model {
+	if m.Watcher != nil {
+		m.Watcher.Follow("")
+	}
+	m.FollowPending = false
+	return m
+}
+
+// handleLogRefresh updates the view after the followed log has been checked
+func handleLogRefresh(msg logRefreshMsg, m models.Model) (models.Model, tea.Cmd) {
+	m.FollowPolling = false
+	if msg.index != m.LogIndex || !m.Following {
+		// The log being viewed changed, or follow mode was turned off, during the check
+		if !m.FollowPending {
+			return m, nil
+		}
+		return scheduleFollow(m)
+	}
+
+	if errors.Is(msg.err, fileops.ErrLogRotated) {
+		// The server moved the old log aside, so start over with the new file
+		m, cmd := startLogIndex(m, msg.index.Path)
+		m, followCmd := scheduleFollow(m)
+		return m, tea.Batch(cmd, followCmd)
+	}
+	if msg.err != nil {
+		m.Err = msg.err
+		return m, nil
+	}
+
+	// Drop loaded entries that were re-read so the window is reloaded
+	if loaded := msg.from - m.LogWindowStart; loaded < len(m.LogEntries) {
+		m.LogEntries = m.LogEntries[:Max(loaded, 0)]
+	}
+	if m.LogCursor >= msg.prevCount-1 {
+		m.LogCursor = Max(logEntryCount(m)-1, 0)
+	}
+	m.Events = msg.index.Events()
+
+	m, findCmd := startFindScan(m)
+	m, cmd := ensureLogWindow(m)
+	cmd = tea.Batch(cmd, findCmd)
+	if !m.FollowPending {
+		return m, cmd
+	}
+	// The log changed again during the check
+	m, followCmd := scheduleFollow(m)
+	return m, tea.Batch(cmd, followCmd)
+}
@@ -6,6 +6,10 @@ import (
 	"goparselogs/internal/models"
 )
 
+// logViewKeys are the keys for finding and moving around in any log view,
+// listed after those of the kind of log being viewed
+var logViewKeys = []string{"/ ?: Find", "n/N: Next/Prev", "PGUP/PGDN: Page", "HOME/g END/G: Top/Bottom", "h/l: Scroll", "D: Details", "W: Wrap", ":: Go to", "ESC: Menu"}
+
 // buildHelpText creates the appropriate help text based on the current state
 func buildHelpText(m models.Model) string {
 	var helpText string
@@ -15,7 +19,12 @@ func buildHelpText(m models.Model) string {
 
 	switch m.State {
 	case models.LogView:
-		specificHelp := []string{"E: Save", "ESC: Menu"}
+		specificHelp := append([]string{"E: Save", "ENTER: Trace", "V: Events", "F: Follow", "S: Search All"}, logViewKeys...)
+		if m.CoreProtectMode {
+			specificHelp = append([]string{"E: Save", "V: Players"}, logViewKeys...)
+		} else if _, ok := currentTimeline(m); ok {
+			specificHelp = append([]string{"E: Save", "ENTER: Trace", "V: Events", "S: Search All"}, logViewKeys...)
+		}
 		if m.LeftPaneWidth < 45 { // Threshold for single line help
 			helpParts = append(baseHelp, specificHelp...)
 			helpText = "\n" + strings.Join(helpParts, " | ")
@@ -25,6 +34,9 @@ func buildHelpText(m models.Model) string {
 
 	case models.MenuView:
 		specificHelp := []string{"ESC: Unfocus"}
+		if !m.CoreProtectMode {
+			specificHelp = append(specificHelp, "S: Search All", "SPACE/R: Select", "M: Merge")
+		}
 		if m.LeftPaneWidth < 40 {
 			helpParts = append(baseHelp, specificHelp...)
 			helpText = "\n" + strings.Join(helpParts, " | ")
@@ -32,6 +44,15 @@ func buildHelpText(m models.Model) string {
 			helpText = "\n" + strings.Join(baseHelp, " | ") + "\n" + strings.Join(specificHelp, " | ")
 		}
 
+	case models.SearchView:
+		specificHelp := []string{"ENTER: Open", "ESC: Menu"}
+		if m.LeftPaneWidth < 45 {
+			helpParts = append(baseHelp, specificHelp...)
+			helpText = "\n" + strings.Join(helpParts, " | ")
+		} else {
+			helpText = "\n" + strings.Join(baseHelp, " | ") + "\n" + strings.Join(specificHelp, " | ")
+		}
+
 	case models.SaveInputView:
 		helpText = "\nEnter filename. ENTER: Save, ESC: Cancel."
 	}
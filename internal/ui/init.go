@@ -1,12 +1,13 @@
 package ui
 
 import (
-	"fmt"
+	"time"
 
 	"goparselogs/internal/fileops"
 	"goparselogs/internal/models"
 	"goparselogs/pkg/coreprotectparser"
 	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -17,26 +18,25 @@ const (
 )
 
 // createInitialState creates and returns a new model with default settings and styles
-func createInitialState() models.Model {
+func createInitialState(opts Options) models.Model {
 	// Get list of log files
-	logFiles, err := fileops.ScanLogFiles()
+	groups, err := fileops.ScanLogSources(opts.Sources)
 	if err != nil {
-		// If we can't read the logs directory, start with empty list
-		logFiles = []string{}
+		// If we can't read the log directories, start with empty list
+		groups = nil
 	}
 
 	// Create menu choices with log files
-	menuChoices := make([]string, 0, len(logFiles)+2) // +2 for toggle and exit
-	menuChoices = append(menuChoices, logFiles...)
-	menuChoices = append(menuChoices,
-		fmt.Sprintf("%s (OFF)", CoreProtectToggleBaseText),
-		ExitText,
-	)
+	menuChoices, fileGroups := buildMenuChoices(groups, opts.CoreProtect)
 
 	highlightStyle := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("10"))
 
+	matchStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("0")).
+		Background(lipgloss.Color("11")) // Black on yellow
+
 	subtleStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240"))
 
@@ -62,6 +62,16 @@ func createInitialState() models.Model {
 	rightPaneStyle := lipgloss.NewStyle().
 		Padding(1, 2)
 
+	location := opts.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = fileops.DefaultOutputDir
+	}
+
 	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))    // Red
 	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10")) // Green
 
@@ -69,14 +79,21 @@ func createInitialState() models.Model {
 		State:                 models.MenuView,
 		FocusedPane:           models.LogFilePane,
 		LeftPaneWidth:         60, // Initial default, will be updated by WindowSizeMsg
+		LogSources:            opts.Sources,
 		MenuChoices:           menuChoices,
+		FileGroups:            fileGroups,
+		FileFormats:           map[string]string{}, // Detected in the background by Init
 		MenuCursor:            0,
-		Filters:               []string{},
-		CoreProtectMode:       false,
+		Filters:               []*query.Query{},
+		CoreProtectMode:       opts.CoreProtect,
+		Location:              location,
 		LogEntries:            []logparser.LogEntry{},
 		CoreProtectLogEntries: []coreprotectparser.CoreProtectLogEntry{},
 		LogCursor:             0,
+		OutputDir:             outputDir,
+		IndexCache:            opts.IndexCache,
 		HighlightStyle:        highlightStyle,
+		MatchStyle:            matchStyle,
 		SubtleStyle:           subtleStyle,
 		InputStyle:            inputStyle,
 		FocusedInputStyle:     focusedInputStyle,
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"goparselogs/internal/models"
+	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -16,22 +18,40 @@ func renderLogView(m models.Model) string {
 
 	leftPane.WriteString("Log Files (UP/DOWN, ENTER):\n\n")
 	for i, choice := range m.MenuChoices {
+		if header, ok := menuGroupHeader(m, i); ok {
+			leftPane.WriteString(m.SubtleStyle.Render(header) + "\n")
+		}
 		cursor := "  "
-		line := choice
+		line := menuChoiceLabel(m, i)
+		formatTag := ""
+		if name, ok := m.FileFormats[choice]; ok {
+			formatTag = fmt.Sprintf(" [%s]", name)
+		}
 
 		availableWidthForFilename := m.LeftPaneWidth - m.LeftPaneStyle.GetHorizontalPadding() - len(cursor)
 		if availableWidthForFilename < 5 {
 			availableWidthForFilename = 5
 		}
 
-		if len(line) > availableWidthForFilename {
-			line = line[:availableWidthForFilename-3] + "..."
+		if len(line)+len(formatTag) > availableWidthForFilename {
+			if availableWidthForFilename-len(formatTag) < 5 {
+				formatTag = ""
+			}
+			if len(line) > availableWidthForFilename-len(formatTag) {
+				line = line[:availableWidthForFilename-len(formatTag)-3] + "..."
+			}
 		}
 
-		if m.FocusedPane == models.LogFilePane && m.MenuCursor == i {
-			cursor = "> "
+		if m.Selected[choice] {
+			cursor = " *"
+		}
+		if m.FocusedPane == models.LogFilePane && m.MenuCursor == i && m.State != models.SearchView {
+			cursor = ">" + cursor[1:]
 			line = m.HighlightStyle.Render(line)
+		} else if m.Selected[choice] {
+			line = m.SuccessStyle.Render(line)
 		}
+		line += m.SubtleStyle.Render(formatTag)
 		leftPane.WriteString(fmt.Sprintf("%s%s\n", cursor, line))
 	}
 
@@ -41,31 +61,30 @@ func renderLogView(m models.Model) string {
 		leftPane.WriteString(m.SubtleStyle.Render("  None\n"))
 	} else {
 		for _, f := range m.Filters {
-			leftPane.WriteString(fmt.Sprintf("  - %s\n", f))
+			leftPane.WriteString(fmt.Sprintf("  - %s\n", f.Source))
 		}
 	}
 
 	// Filter input
 	filterPrompt := "\nAdd Filter (Type & ENTER):\n"
-	if m.CoreProtectMode {
-		leftPane.WriteString(m.SubtleStyle.Render("\nFilters disabled in CoreProtect mode.\n"))
-	} else {
-		if m.LeftPaneWidth < 45 {
-			filterPrompt = "\nFilter:\n"
-		}
-		leftPane.WriteString(filterPrompt)
-		currentInputStyle := m.InputStyle
-		filterText := m.FilterInput
-		if m.FocusedPane == models.FilterPane {
-			currentInputStyle = m.FocusedInputStyle
-			filterText += "▌"
-		}
+	if m.LeftPaneWidth < 45 {
+		filterPrompt = "\nFilter:\n"
+	}
+	leftPane.WriteString(filterPrompt)
+	currentInputStyle := m.InputStyle
+	filterText := m.FilterInput
+	if m.FocusedPane == models.FilterPane {
+		currentInputStyle = m.FocusedInputStyle
+		filterText += "▌"
+	}
 
-		inputRenderWidth := m.LeftPaneWidth - m.LeftPaneStyle.GetHorizontalPadding() - currentInputStyle.GetHorizontalFrameSize() - 2
-		if inputRenderWidth < 5 {
-			inputRenderWidth = 5
-		}
-		leftPane.WriteString(currentInputStyle.Width(inputRenderWidth).Render(filterText))
+	inputRenderWidth := m.LeftPaneWidth - m.LeftPaneStyle.GetHorizontalPadding() - currentInputStyle.GetHorizontalFrameSize() - 2
+	if inputRenderWidth < 5 {
+		inputRenderWidth = 5
+	}
+	leftPane.WriteString(currentInputStyle.Width(inputRenderWidth).Render(filterText))
+	if m.FilterErr != nil {
+		leftPane.WriteString("\n" + m.ErrorStyle.Render(fmt.Sprintf("Invalid filter: %v", m.FilterErr)))
 	}
 
 	// Help text
@@ -89,128 +108,132 @@ func renderLogView(m models.Model) string {
 
 	// Build right pane (logs) content
 	var rightPane strings.Builder
-	rightPaneWidth := 0
-	if m.TermWidth > m.LeftPaneWidth+m.LeftPaneStyle.GetHorizontalBorderSize() {
-		rightPaneWidth = m.TermWidth - m.LeftPaneWidth - m.LeftPaneStyle.GetHorizontalBorderSize()
-	}
+	rightPaneWidth := calcRightPaneWidth(m)
 
 	if rightPaneWidth <= 10 {
 		rightPane.WriteString(m.ErrorStyle.Render("Terminal too narrow for logs."))
+	} else if m.State == models.SearchView {
+		availableHeightForResults := Max(m.TermHeight-m.RightPaneStyle.GetVerticalPadding()-4, 1)
+		maxLineTextWidth := Max(rightPaneWidth-m.RightPaneStyle.GetHorizontalPadding()-2, 5)
+		rightPane.WriteString(renderSearchResults(m, maxLineTextWidth, availableHeightForResults))
 	} else if m.State == models.MenuView {
 		// Custom message when no file is selected
 		rightPane.WriteString("Select a log file from the left panel to view its contents.\n\n")
 		rightPane.WriteString(m.SubtleStyle.Render("Use UP/DOWN or J/K to navigate\n"))
 		rightPane.WriteString(m.SubtleStyle.Render("Press ENTER to view a file\n"))
+		rightPane.WriteString(m.SubtleStyle.Render("Press TAB to focus on filters\n"))
 		if !m.CoreProtectMode {
-			rightPane.WriteString(m.SubtleStyle.Render("Press TAB to focus on filters\n"))
+			rightPane.WriteString(m.SubtleStyle.Render("Press S to search every log file with the filters\n"))
 		}
-	} else if len(m.LogEntries) == 0 && m.Err == nil && !m.CoreProtectMode {
-		if len(m.Filters) > 0 {
-			rightPane.WriteString(fmt.Sprintf("No log entries matching filters: %s\n", strings.Join(m.Filters, ", ")))
-		} else {
-			rightPane.WriteString("Loading or parsing log file...")
-		}
-	} else if len(m.CoreProtectLogEntries) == 0 && m.Err == nil && m.CoreProtectMode {
+	} else if logEntryCount(m) == 0 && m.Err == nil && !m.CoreProtectMode && !logIndexDone(m) {
+		rightPane.WriteString("Loading or parsing log file...")
+	} else if m.CoreProtectLoading && m.Err == nil && m.CoreProtectMode {
 		rightPane.WriteString("Loading or parsing CoreProtect log file...")
 	} else if m.Err != nil {
 		rightPane.WriteString(m.ErrorStyle.Render("Error loading logs. See left pane."))
+	} else if m.EventMode && !m.CoreProtectMode {
+		availableHeightForEvents := Max(m.TermHeight-m.RightPaneStyle.GetVerticalPadding()-6, 1)
+		maxLineTextWidth := Max(rightPaneWidth-m.RightPaneStyle.GetHorizontalPadding()-2, 5)
+		rightPane.WriteString(renderEventList(m, maxLineTextWidth, availableHeightForEvents))
+	} else if m.SummaryMode && m.CoreProtectMode {
+		availableHeightForPlayers := Max(m.TermHeight-m.RightPaneStyle.GetVerticalPadding()-6, 1)
+		maxLineTextWidth := Max(rightPaneWidth-m.RightPaneStyle.GetHorizontalPadding()-2, 5)
+		rightPane.WriteString(renderSummaryList(m, maxLineTextWidth, availableHeightForPlayers))
 	} else {
-		headerFooterAndPaddingHeight := m.RightPaneStyle.GetVerticalPadding() + 2 + 1 + 1 + 1 + 1
-		availableHeightForLogs := m.TermHeight - headerFooterAndPaddingHeight
-		if availableHeightForLogs < 1 {
-			availableHeightForLogs = 1
-		}
-		numEntriesToShow := availableHeightForLogs
-
-		var currentEntriesCount int
+		layout := newLogViewLayout(m)
+		currentEntriesCount := logEntryCount(m)
+		cpEntries := layout.cpEntries
 		if m.CoreProtectMode {
-			rightPane.WriteString("CoreProtect Log Entries (Sorted by Time Ago):\n\n")
-			currentEntriesCount = len(m.CoreProtectLogEntries)
+			rightPane.WriteString("CoreProtect Log Entries (Sorted by Time Ago)")
+			if m.PlayerFilter != "" {
+				rightPane.WriteString(fmt.Sprintf(" for %s (ESC: Players)", m.HighlightStyle.Render(m.PlayerFilter)))
+			}
+		} else if timeline, ok := currentTimeline(m); ok {
+			rightPane.WriteString(timelineTitle(timeline))
 		} else {
 			rightPane.WriteString("Parsed Log Entries")
-			if len(m.Filters) > 0 {
-				rightPane.WriteString(fmt.Sprintf(" (Filters: %s)", m.HighlightStyle.Render(strings.Join(m.Filters, ", "))))
-			}
-			rightPane.WriteString(":\n\n")
-			currentEntriesCount = len(m.LogEntries)
 		}
+		if len(m.Filters) > 0 {
+			rightPane.WriteString(fmt.Sprintf(" (Filters: %s)", m.HighlightStyle.Render(strings.Join(query.Sources(m.Filters), ", "))))
+		}
+		rightPane.WriteString(":\n\n")
 
 		if currentEntriesCount == 0 {
-			if m.CoreProtectMode {
+			if len(m.Filters) > 0 {
+				rightPane.WriteString(fmt.Sprintf("No log entries matching filters: %s", strings.Join(query.Sources(m.Filters), ", ")))
+			} else if m.CoreProtectMode {
 				rightPane.WriteString("No CoreProtect entries found or parsed.")
-			} else if len(m.Filters) > 0 {
-				rightPane.WriteString(fmt.Sprintf("No log entries matching filters: %s", strings.Join(m.Filters, ", ")))
 			} else {
 				rightPane.WriteString("No log entries.")
 			}
 		} else {
-			start := m.LogCursor - (numEntriesToShow / 2)
-			if start < 0 {
-				start = 0
-			}
-			end := start + numEntriesToShow
-			if end > currentEntriesCount {
-				end = currentEntriesCount
-				start = end - numEntriesToShow
-				if start < 0 {
-					start = 0
-				}
-			}
-			if end == start && currentEntriesCount > 0 {
-				end = start + 1
-			}
+			maxLineTextWidth := layout.width
+			start, end := logViewport(m, layout)
 
 			for i := start; i < end; i++ {
-				var line string
+				lineStyle := lipgloss.NewStyle()
 				if m.CoreProtectMode {
-					entry := m.CoreProtectLogEntries[i]
-					var timeAgoStr string
-
-
-					if entry.IsInDays {
-
-
-						timeAgoStr = fmt.Sprintf("%.2f/d ago", entry.DaysAgo)
-
-
-					} else {
-
-
-						timeAgoStr = fmt.Sprintf("%.2f/h ago", entry.HoursAgo)
-
-
+					lineStyle = coreProtectActionStyle(cpEntries[i].Action)
+				}
+				for j, row := range logEntryRows(m, layout, i) {
+					marker := "  "
+					if i == m.LogCursor && j == 0 {
+						marker = "> "
 					}
-
-
-					line = fmt.Sprintf("%s - %s: %s", timeAgoStr, entry.Username, entry.Message)
-				} else {
-					entry := m.LogEntries[i]
-					line = fmt.Sprintf("[%s] [%s/%s]: %s", entry.Timestamp, entry.Thread, entry.Level, entry.Message)
+					var styledLine string
+					switch {
+					case i == m.LogCursor && !row.subtle:
+						styledLine = m.HighlightStyle.Render(marker) + highlightMatches(row, m.HighlightStyle, m.MatchStyle)
+					case row.subtle:
+						styledLine = marker + highlightMatches(row, m.SubtleStyle, m.MatchStyle)
+					default:
+						styledLine = marker + highlightMatches(row, lineStyle, m.MatchStyle)
+					}
+					rightPane.WriteString(styledLine + "\n")
 				}
+			}
 
-				maxLineTextWidth := rightPaneWidth - m.RightPaneStyle.GetHorizontalPadding() - 2
-				if maxLineTextWidth < 5 {
-					maxLineTextWidth = 5
+			if m.JumpActive {
+				rightPane.WriteString(fmt.Sprintf("\nGo to entry (1-%d): %s▌\n", currentEntriesCount, m.JumpInput))
+			} else if m.FindActive {
+				prompt := "/"
+				if m.FindBackward {
+					prompt = "?"
 				}
-
-				if len(line) > maxLineTextWidth {
-					line = line[:maxLineTextWidth-3] + "..."
+				rightPane.WriteString(fmt.Sprintf("\n%s%s▌", prompt, m.FindInput))
+				if status := findStatus(m, layout); status != "" {
+					rightPane.WriteString(m.SubtleStyle.Render(" (" + status + ")"))
 				}
-
-				var styledLine string
-				if i == m.LogCursor {
-					styledLine = m.HighlightStyle.Render(fmt.Sprintf("> %s", line))
-				} else {
-					styledLine = fmt.Sprintf("  %s", line)
+				rightPane.WriteString("\n")
+			} else if currentEntriesCount > 0 {
+				rightPane.WriteString(fmt.Sprintf("\nViewing %d-%d of %d", start+1, end, currentEntriesCount))
+				if m.LogColumn > 0 {
+					rightPane.WriteString(fmt.Sprintf(" from column %d", m.LogColumn+1))
 				}
-				rightPane.WriteString(styledLine + "\n")
-			}
-
-			if currentEntriesCount > 0 {
-				rightPane.WriteString(fmt.Sprintf("\nViewing %d-%d of %d\n", start+1, end, currentEntriesCount))
+				if status := findStatus(m, layout); status != "" {
+					rightPane.WriteString(fmt.Sprintf(" | %q: %s", m.FindPattern, status))
+				}
+				if !logIndexDone(m) {
+					rightPane.WriteString(" (indexing...)")
+				} else if m.Following {
+					rightPane.WriteString(" (following)")
+				}
+				if m.CoreProtectMode && m.LogCursor < len(cpEntries) {
+					if session := cpEntries[m.LogCursor].Session; session < len(m.CoreProtectSessions) {
+						summary := " | " + formatSessionSummary(m.CoreProtectSessions[session])
+						if room := maxLineTextWidth - len(fmt.Sprintf("Viewing %d-%d of %d", start+1, end, currentEntriesCount)); len(summary) > room {
+							summary = summary[:Max(room-3, 0)] + "..."
+						}
+						rightPane.WriteString(m.SubtleStyle.Render(summary))
+					}
+				}
+				rightPane.WriteString("\n")
 			} else {
 				rightPane.WriteString("\nNo entries to display.\n")
 			}
+			if m.ShowDetails {
+				rightPane.WriteString(renderEntryDetails(m, layout))
+			}
 		}
 	}
 
@@ -219,3 +242,62 @@ func renderLogView(m models.Model) string {
 	// Combine panes horizontally
 	return lipgloss.JoinHorizontal(lipgloss.Top, styledLeftPane, styledRightPane)
 }
+
+// calcRightPaneWidth returns the width of the right pane, next to the menu
+func calcRightPaneWidth(m models.Model) int {
+	if m.TermWidth > m.LeftPaneWidth+m.LeftPaneStyle.GetHorizontalBorderSize() {
+		return m.TermWidth - m.LeftPaneWidth - m.LeftPaneStyle.GetHorizontalBorderSize()
+	}
+	return 0
+}
+
+// formatLogEntryLine renders a standard log entry as a single line, adapting
+// to the fields present in the entry's format.
+func formatLogEntryLine(entry logparser.LogEntry) string {
+	var header string
+	if entry.Thread == "" {
+		header = fmt.Sprintf("[%s %s]", entry.Timestamp, entry.Level)
+	} else {
+		header = fmt.Sprintf("[%s] [%s/%s]", entry.Timestamp, entry.Thread, entry.Level)
+	}
+	if entry.Logger != "" {
+		header += fmt.Sprintf(" [%s]", entry.Logger)
+	}
+	return fmt.Sprintf("%s: %s", header, entry.Message)
+}
+
+// visibleEntryRange returns the [start, end) range of entries to render so that the
+// entry under the cursor is visible and roughly centred, given the number of
+// available lines and the number of lines each entry occupies.
+func visibleEntryRange(cursor, count, availableLines int, entryHeight func(int) int) (int, int) {
+	if count == 0 {
+		return 0, 0
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor >= count {
+		cursor = count - 1
+	}
+
+	start, end := cursor, cursor+1
+	used := entryHeight(cursor)
+
+	// Fill up to half of the remaining space above the cursor first
+	above := (availableLines - used) / 2
+	for start > 0 && entryHeight(start-1) <= above {
+		above -= entryHeight(start - 1)
+		used += entryHeight(start - 1)
+		start--
+	}
+	// Then fill below the cursor, and give any leftover space back to the top
+	for end < count && used+entryHeight(end) <= availableLines {
+		used += entryHeight(end)
+		end++
+	}
+	for start > 0 && used+entryHeight(start-1) <= availableLines {
+		used += entryHeight(start - 1)
+		start--
+	}
+	return start, end
+}
@@ -14,8 +14,15 @@ func renderMenuView(m models.Model) string {
 	// Build menu content
 	view.WriteString("Log Files (UP/DOWN, ENTER):\n\n")
 	for i, choice := range m.MenuChoices {
+		if header, ok := menuGroupHeader(m, i); ok {
+			view.WriteString(m.SubtleStyle.Render(header) + "\n")
+		}
 		cursor := "  "
-		line := choice
+		line := menuChoiceLabel(m, i)
+		formatTag := ""
+		if name, ok := m.FileFormats[choice]; ok {
+			formatTag = fmt.Sprintf(" [%s]", name)
+		}
 
 		// Truncate long filenames
 		availableWidthForFilename := m.TermWidth - m.LeftPaneStyle.GetHorizontalPadding() - len(cursor)
@@ -23,14 +30,20 @@ func renderMenuView(m models.Model) string {
 			availableWidthForFilename = 5
 		}
 
-		if len(line) > availableWidthForFilename {
-			line = line[:availableWidthForFilename-3] + "..."
+		if len(line)+len(formatTag) > availableWidthForFilename {
+			if availableWidthForFilename-len(formatTag) < 5 {
+				formatTag = ""
+			}
+			if len(line) > availableWidthForFilename-len(formatTag) {
+				line = line[:availableWidthForFilename-len(formatTag)-3] + "..."
+			}
 		}
 
 		if m.FocusedPane == models.LogFilePane && m.MenuCursor == i {
 			cursor = "> "
 			line = m.HighlightStyle.Render(line)
 		}
+		line += m.SubtleStyle.Render(formatTag)
 		view.WriteString(fmt.Sprintf("%s%s\n", cursor, line))
 	}
 
@@ -40,31 +53,30 @@ func renderMenuView(m models.Model) string {
 		view.WriteString(m.SubtleStyle.Render("  None\n"))
 	} else {
 		for _, f := range m.Filters {
-			view.WriteString(fmt.Sprintf("  - %s\n", f))
+			view.WriteString(fmt.Sprintf("  - %s\n", f.Source))
 		}
 	}
 
 	// Filter input section
 	filterPrompt := "\nAdd Filter (Type & ENTER):\n"
-	if m.CoreProtectMode {
-		view.WriteString(m.SubtleStyle.Render("\nFilters disabled in CoreProtect mode.\n"))
-	} else {
-		if m.TermWidth < 45 {
-			filterPrompt = "\nFilter:\n"
-		}
-		view.WriteString(filterPrompt)
-		currentInputStyle := m.InputStyle
-		filterText := m.FilterInput
-		if m.FocusedPane == models.FilterPane {
-			currentInputStyle = m.FocusedInputStyle
-			filterText += "▌"
-		}
+	if m.TermWidth < 45 {
+		filterPrompt = "\nFilter:\n"
+	}
+	view.WriteString(filterPrompt)
+	currentInputStyle := m.InputStyle
+	filterText := m.FilterInput
+	if m.FocusedPane == models.FilterPane {
+		currentInputStyle = m.FocusedInputStyle
+		filterText += "▌"
+	}
 
-		inputRenderWidth := m.TermWidth - m.LeftPaneStyle.GetHorizontalPadding() - currentInputStyle.GetHorizontalFrameSize() - 2
-		if inputRenderWidth < 5 {
-			inputRenderWidth = 5
-		}
-		view.WriteString(currentInputStyle.Width(inputRenderWidth).Render(filterText))
+	inputRenderWidth := m.TermWidth - m.LeftPaneStyle.GetHorizontalPadding() - currentInputStyle.GetHorizontalFrameSize() - 2
+	if inputRenderWidth < 5 {
+		inputRenderWidth = 5
+	}
+	view.WriteString(currentInputStyle.Width(inputRenderWidth).Render(filterText))
+	if m.FilterErr != nil {
+		view.WriteString("\n" + m.ErrorStyle.Render(fmt.Sprintf("Invalid filter: %v", m.FilterErr)))
 	}
 
 	// Help text
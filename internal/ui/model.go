@@ -1,27 +1,45 @@
 package ui
 
 import (
+	"time"
+
+	"goparselogs/internal/fileops"
 	"goparselogs/internal/models"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// Options configure the TUI when it starts.
+type Options struct {
+	Sources     fileops.LogSources  // Where to look for log files, the logs directory if empty
+	CoreProtect bool                // Start with CoreProtect parsing enabled
+	Open        string              // Log file to open straight away, if any
+	OutputDir   string              // Directory exports are saved in, fileops.DefaultOutputDir if empty
+	IndexCache  *fileops.IndexCache // Cache of log indexes, if any
+	Location    *time.Location      // Time zone log entries are dated in, the local time zone if nil
+}
+
 // TUIModel wraps our models.Model to implement tea.Model interface
 type TUIModel struct {
-	state models.Model
+	state   models.Model
+	initCmd tea.Cmd
 }
 
 // InitialModel creates a new TUIModel with initial state
-func InitialModel() TUIModel {
+func InitialModel(opts Options) TUIModel {
 	model := TUIModel{
-		state: createInitialState(),
+		state: createInitialState(opts),
+	}
+	model.state.Watcher = fileops.NewWatcher(opts.Sources)
+	if opts.Open != "" {
+		model.state, model.initCmd = openLogFile(model.state, opts.Open)
 	}
 	return model
 }
 
 // Init implements tea.Model
 func (m TUIModel) Init() tea.Cmd {
-	return nil
+	return tea.Batch(m.initCmd, waitForChangesCmd(m.state.Watcher), detectFormatsCmd(menuFiles(m.state)))
 }
 
 // Update implements tea.Model
@@ -35,3 +53,17 @@ func (m TUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m TUIModel) View() string {
 	return View(m.state)
 }
+
+// Close releases resources held by the model, such as temporary files used
+// to index compressed logs. It should be called once the program exits.
+func (m TUIModel) Close() {
+	if m.state.LogIndex != nil {
+		m.state.LogIndex.Close()
+	}
+	if m.state.Search != nil {
+		m.state.Search.Cancel()
+	}
+	if m.state.Watcher != nil {
+		m.state.Watcher.Close()
+	}
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"strings"
 
+	"goparselogs/internal/fileops"
 	"goparselogs/internal/models"
 
 	"github.com/charmbracelet/lipgloss"
@@ -12,17 +13,40 @@ import (
 func renderSaveInputView(m models.Model) string {
 	var saveView strings.Builder
 
-	saveView.WriteString("Enter filename to save logs (ENTER to save, ESC to cancel):\n\n")
+	saveView.WriteString("Enter filename to save logs (ENTER to save, ESC to cancel):\n")
+	saveView.WriteString(m.SubtleStyle.Render("TAB: complete directory | ←/→: format | Ctrl+G: gzip | {file} {date} {time} {filter}") + "\n\n")
 
 	// Use a style for the save input field without its own border to avoid conflict with modal border
 	saveInputRenderStyle := m.FocusedInputStyle.Copy().Border(lipgloss.Border{})
 	saveView.WriteString(saveInputRenderStyle.Width(m.TermWidth / 2).Render(m.SaveFilenameInput + "▌"))
-	saveView.WriteString("\n\n")
+	saveView.WriteString("\n\nFormat: ")
+	for i, format := range fileops.ExportFormats {
+		if i > 0 {
+			saveView.WriteString("  ")
+		}
+		if format == m.SaveFormat {
+			saveView.WriteString(m.HighlightStyle.Render("[" + string(format) + "]"))
+		} else {
+			saveView.WriteString(m.SubtleStyle.Render(string(format)))
+		}
+	}
+	saveView.WriteString("\n")
+	if len(m.SaveCompletions) > 0 {
+		saveView.WriteString(m.SubtleStyle.Render(strings.Join(m.SaveCompletions, "/  ")+"/") + "\n")
+	}
+	if m.SaveFilenameInput != "" {
+		if path, err := exportPath(m); err == nil {
+			saveView.WriteString(m.SubtleStyle.Render("Saves to: "+path) + "\n")
+		}
+	}
+	saveView.WriteString("\n")
 
 	if m.SaveMessage != "" {
 		styleToUse := m.SubtleStyle
 		if strings.HasPrefix(strings.ToLower(m.SaveMessage), "error") {
 			styleToUse = m.ErrorStyle
+		} else if m.SaveConfirmOverwrite {
+			styleToUse = m.HighlightStyle
 		}
 		saveView.WriteString(styleToUse.Render(m.SaveMessage) + "\n")
 	}
@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+	"goparselogs/pkg/query"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const searchPollInterval = 200 * time.Millisecond
+
+// searchProgressMsg is sent periodically while a search of every log file is running
+type searchProgressMsg struct {
+	search *fileops.Search
+}
+
+// pollSearchCmd waits briefly and then reports on the progress of the search
+func pollSearchCmd(search *fileops.Search) tea.Cmd {
+	return tea.Tick(searchPollInterval, func(time.Time) tea.Msg {
+		return searchProgressMsg{search: search}
+	})
+}
+
+// searchRow is a line of the search results: a file heading if hit is -1, otherwise one of its hits
+type searchRow struct {
+	file int
+	hit  int
+}
+
+// searchRows lists the rows of the search results, leaving out files without matches
+func searchRows(results []fileops.FileMatches) []searchRow {
+	var rows []searchRow
+	for i, file := range results {
+		if file.Count == 0 && file.Err == nil {
+			continue
+		}
+		rows = append(rows, searchRow{file: i, hit: -1})
+		for j := range file.Hits {
+			rows = append(rows, searchRow{file: i, hit: j})
+		}
+	}
+	return rows
+}
+
+// searchFiles returns the standard log files listed in the menu
+func searchFiles(m models.Model) []string {
+	var files []string
+	for _, choice := range m.MenuChoices {
+		if _, ok := m.FileGroups[choice]; ok && !fileops.IsCoreProtectDatabase(choice) {
+			files = append(files, choice)
+		}
+	}
+	return files
+}
+
+// startGlobalSearch switches to the search view and searches every log file
+// with the active filters, replacing any search already running. Without
+// filters, the filter input is focused so one can be added first.
+func startGlobalSearch(m models.Model) (models.Model, tea.Cmd) {
+	if m.Search != nil {
+		m.Search.Cancel()
+		m.Search = nil
+	}
+	m.State = models.SearchView
+	m.SearchResults = nil
+	m.SearchCursor = 0
+	m.FromSearch = false
+	if len(m.Filters) == 0 {
+		m.FocusedPane = models.FilterPane
+		m.InputActive = true
+		return m, nil
+	}
+
+	m.FocusedPane = models.LogFilePane
+	m.InputActive = false
+	m.Search = fileops.StartSearch(searchFiles(m), queryFilter(m.Filters), m.Location, fileops.SearchOptions{
+		Cache: m.IndexCache,
+		Terms: queryTerms(m.Filters),
+	})
+	return m, pollSearchCmd(m.Search)
+}
+
+// handleSearchProgress updates the search results, keeping the cursor on the
+// same row as files finish out of order
+func handleSearchProgress(msg searchProgressMsg, m models.Model) (models.Model, tea.Cmd) {
+	if msg.search != m.Search {
+		return m, nil // A search that has been replaced or cancelled
+	}
+
+	rows := searchRows(m.SearchResults)
+	m.SearchResults = msg.search.Results()
+	if m.SearchCursor < len(rows) {
+		selected := rows[m.SearchCursor]
+		for i, row := range searchRows(m.SearchResults) {
+			if row == selected {
+				m.SearchCursor = i
+				break
+			}
+		}
+	}
+
+	if msg.search.Done() {
+		return m, nil
+	}
+	return m, pollSearchCmd(msg.search)
+}
+
+// handleSearchViewInput handles input when in search view
+func handleSearchViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	// Typing into the filter input works the same as in the menu view
+	if m.FocusedPane == models.FilterPane {
+		return handleMenuViewInput(msg, m)
+	}
+
+	rows := searchRows(m.SearchResults)
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		if m.SearchCursor > 0 {
+			m.SearchCursor--
+		}
+	case "down", "j":
+		if m.SearchCursor < len(rows)-1 {
+			m.SearchCursor++
+		}
+	case "enter":
+		if m.SearchCursor < len(rows) {
+			return openSearchRow(m, rows[m.SearchCursor])
+		}
+	case "tab":
+		m.FocusedPane = models.FilterPane
+		m.InputActive = true
+	case "esc":
+		if m.Search != nil {
+			m.Search.Cancel()
+		}
+		m.State = models.MenuView
+		m.FocusedPane = models.LogFilePane
+		m.InputActive = false
+	}
+	return m, nil
+}
+
+// openSearchRow opens the file of a search result with the cursor on the hit,
+// or on the file's first match for a file heading
+func openSearchRow(m models.Model, row searchRow) (models.Model, tea.Cmd) {
+	file := m.SearchResults[row.file]
+	cursor := 0
+	if row.hit >= 0 {
+		cursor = file.Hits[row.hit].Index
+	}
+
+	m, cmd := openLogFile(m, file.Path)
+	// The log is indexed with the same filters, so hits line up with its entries
+	m.LogCursor = cursor
+	m = centreLogView(m)
+	m.FromSearch = true
+	m, windowCmd := ensureLogWindow(m)
+	return m, tea.Batch(cmd, windowCmd)
+}
+
+// renderSearchResults renders the matches of the search grouped by file
+func renderSearchResults(m models.Model, maxLineWidth, availableHeight int) string {
+	var b strings.Builder
+
+	b.WriteString("Search All Logs")
+	if len(m.Filters) > 0 {
+		b.WriteString(fmt.Sprintf(" (Filters: %s)", m.HighlightStyle.Render(strings.Join(query.Sources(m.Filters), ", "))))
+	}
+	b.WriteString(":\n\n")
+
+	if m.Search == nil {
+		b.WriteString("Type a filter and press ENTER to search every log file.\n")
+		return b.String()
+	}
+
+	rows := searchRows(m.SearchResults)
+	searched, total := m.Search.Progress()
+	matches, matchedFiles := 0, 0
+	for _, file := range m.SearchResults {
+		if file.Count > 0 {
+			matches += file.Count
+			matchedFiles++
+		}
+	}
+
+	if len(rows) == 0 {
+		if m.Search.Done() {
+			b.WriteString("No log entries matching filters.\n")
+		} else {
+			b.WriteString("Searching...\n")
+		}
+	}
+
+	start, end := visibleEntryRange(m.SearchCursor, len(rows), Max(availableHeight-2, 1), func(int) int { return 1 })
+	for i := start; i < end; i++ {
+		row := rows[i]
+		file := m.SearchResults[row.file]
+
+		var line string
+		if row.hit < 0 {
+			line = fmt.Sprintf("%s (%d matches", file.Path, file.Count)
+			if file.Count > len(file.Hits) {
+				line += fmt.Sprintf(", first %d shown", len(file.Hits))
+			}
+			if !file.Done {
+				line += ", searching"
+			}
+			line += ")"
+			if file.Err != nil {
+				line = fmt.Sprintf("%s (error: %v)", file.Path, file.Err)
+			}
+		} else {
+			line = "  " + formatLogEntryLine(file.Hits[row.hit].Entry)
+		}
+		if len(line) > maxLineWidth {
+			line = line[:Max(maxLineWidth-3, 0)] + "..."
+		}
+
+		switch {
+		case i == m.SearchCursor:
+			line = m.HighlightStyle.Render("> " + line)
+		case row.hit < 0 && file.Err != nil:
+			line = "  " + m.ErrorStyle.Render(line)
+		case row.hit < 0:
+			line = "  " + m.SubtleStyle.Render(line)
+		default:
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\nSearched %d of %d files, %d matches in %d files", searched, total, matches, matchedFiles))
+	if !m.Search.Done() {
+		b.WriteString(" (searching...)")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"goparselogs/internal/models"
+	"goparselogs/pkg/coreprotectparser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// playerDetailLines is the number of lines the selected player's details take up below the player list
+const playerDetailLines = 6
+
+// coreProtectEntries returns the CoreProtect entries being listed, which are
+// only those of the selected player after drilling into them from the summary
+func coreProtectEntries(m models.Model) []coreprotectparser.CoreProtectLogEntry {
+	if m.PlayerFilter == "" {
+		return m.CoreProtectLogEntries
+	}
+	var entries []coreprotectparser.CoreProtectLogEntry
+	for _, entry := range m.CoreProtectLogEntries {
+		if entry.Username == m.PlayerFilter {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// handleSummaryViewInput handles the keys specific to the player summary. It
+// returns false for keys that should fall through to the regular log view handling.
+func handleSummaryViewInput(msg tea.KeyMsg, m models.Model) (bool, models.Model) {
+	switch msg.String() {
+	case "up", "k":
+		if m.SummaryCursor > 0 {
+			m.SummaryCursor--
+		}
+	case "down", "j":
+		if m.SummaryCursor < len(m.Summaries)-1 {
+			m.SummaryCursor++
+		}
+	case "enter":
+		// Drill into the entries of the selected player
+		if m.SummaryCursor < len(m.Summaries) {
+			m.PlayerFilter = m.Summaries[m.SummaryCursor].Username
+			m.SummaryMode = false
+			m.LogCursor = 0
+		}
+	default:
+		return false, m
+	}
+	return true, m
+}
+
+// formatActionCounts lists how often each action appears, most frequent first
+func formatActionCounts(actions map[coreprotectparser.Action]int) string {
+	names := make([]coreprotectparser.Action, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if actions[names[i]] != actions[names[j]] {
+			return actions[names[i]] > actions[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, action := range names {
+		parts[i] = fmt.Sprintf("%s %d", action, actions[action])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSeen formats when a player was first or last seen, falling back to
+// the time ago when the log's date is unknown
+func formatSeen(at time.Time, hoursAgo float64) string {
+	if at.IsZero() {
+		return fmt.Sprintf("%.2f/h ago", hoursAgo)
+	}
+	return at.Format("2006-01-02 15:04:05")
+}
+
+// formatPlayerSummaryLine renders a player as a single line of the summary list
+func formatPlayerSummaryLine(summary coreprotectparser.PlayerSummary) string {
+	return fmt.Sprintf("%-16s %6d  %s", summary.Username, summary.Entries, formatActionCounts(summary.Actions))
+}
+
+// renderPlayerDetails renders the first/last seen times, top materials and
+// busiest hours of a player
+func renderPlayerDetails(summary coreprotectparser.PlayerSummary) []string {
+	materials := "none"
+	if top := summary.TopMaterials(5); len(top) > 0 {
+		parts := make([]string, len(top))
+		for i, count := range top {
+			parts[i] = fmt.Sprintf("%s %d", count.Name, count.Count)
+		}
+		materials = strings.Join(parts, ", ")
+	}
+
+	hours := "unknown"
+	if busiest := summary.BusiestHours(3); len(busiest) > 0 {
+		parts := make([]string, len(busiest))
+		for i, hour := range busiest {
+			parts[i] = fmt.Sprintf("%02d:00 (%d)", hour, summary.Hours[hour])
+		}
+		hours = strings.Join(parts, ", ")
+	}
+
+	return []string{
+		fmt.Sprintf("%s: %d entries", summary.Username, summary.Entries),
+		fmt.Sprintf("First seen %s, last seen %s",
+			formatSeen(summary.FirstSeen, summary.MaxHoursAgo), formatSeen(summary.LastSeen, summary.MinHoursAgo)),
+		"Actions: " + formatActionCounts(summary.Actions),
+		"Top materials: " + materials,
+		"Busiest hours: " + hours,
+	}
+}
+
+// renderSummaryList renders the players in the CoreProtect entries, with the
+// details of the selected one below the list
+func renderSummaryList(m models.Model, maxLineTextWidth, availableLines int) string {
+	var view strings.Builder
+
+	view.WriteString("Players (ENTER: Show entries, V: All entries):\n\n")
+	if len(m.Summaries) == 0 {
+		view.WriteString("No players in the CoreProtect entries.")
+		return view.String()
+	}
+
+	truncate := func(line string) string {
+		if len(line) > maxLineTextWidth {
+			return line[:maxLineTextWidth-3] + "..."
+		}
+		return line
+	}
+
+	start, end := visibleEntryRange(m.SummaryCursor, len(m.Summaries), Max(availableLines-playerDetailLines, 1), func(int) int { return 1 })
+	for i := start; i < end; i++ {
+		line := truncate(formatPlayerSummaryLine(m.Summaries[i]))
+		if i == m.SummaryCursor {
+			view.WriteString(m.HighlightStyle.Render("> "+line) + "\n")
+		} else {
+			view.WriteString("  " + line + "\n")
+		}
+	}
+
+	view.WriteString("\n")
+	if m.SummaryCursor < len(m.Summaries) {
+		for _, line := range renderPlayerDetails(m.Summaries[m.SummaryCursor]) {
+			view.WriteString(m.SubtleStyle.Render(truncate(line)) + "\n")
+		}
+	}
+
+	return view.String()
+}
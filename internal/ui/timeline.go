@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxGutterWidth caps the width of the source column of a merged timeline
+const maxGutterWidth = 20
+
+// selectableFile reports whether a menu choice is a log that can be merged into a timeline
+func selectableFile(m models.Model, choice string) bool {
+	_, ok := m.FileGroups[choice]
+	return ok && !fileops.IsCoreProtectDatabase(choice)
+}
+
+// toggleSelected selects or unselects the file under the menu cursor
+func toggleSelected(m models.Model) models.Model {
+	choice := m.MenuChoices[m.MenuCursor]
+	if !selectableFile(m, choice) {
+		return m
+	}
+	selected := make(map[string]bool, len(m.Selected)+1)
+	for file := range m.Selected {
+		selected[file] = true
+	}
+	if selected[choice] {
+		delete(selected, choice)
+	} else {
+		selected[choice] = true
+	}
+	m.Selected = selected
+	m.SelectFrom = choice
+	return m
+}
+
+// selectRange selects every file between the last file selected and the
+// menu cursor. Rotated logs are named by date, so this picks a date range.
+func selectRange(m models.Model) models.Model {
+	from := -1
+	for i, choice := range m.MenuChoices {
+		if choice == m.SelectFrom {
+			from = i
+		}
+	}
+	if from < 0 {
+		return toggleSelected(m)
+	}
+
+	selected := make(map[string]bool, len(m.Selected))
+	for file := range m.Selected {
+		selected[file] = true
+	}
+	for i := Min(from, m.MenuCursor); i <= Max(from, m.MenuCursor); i++ {
+		if selectableFile(m, m.MenuChoices[i]) {
+			selected[m.MenuChoices[i]] = true
+		}
+	}
+	m.Selected = selected
+	m.SelectFrom = m.MenuChoices[m.MenuCursor]
+	return m
+}
+
+// selectedFiles returns the selected files in menu order
+func selectedFiles(m models.Model) []string {
+	var files []string
+	for _, choice := range m.MenuChoices {
+		if m.Selected[choice] {
+			files = append(files, choice)
+		}
+	}
+	return files
+}
+
+// openTimeline switches to the log view and starts merging the selected
+// files, or the file under the cursor if none are selected
+func openTimeline(m models.Model) (models.Model, tea.Cmd) {
+	files := selectedFiles(m)
+	if len(files) == 0 {
+		if choice := m.MenuChoices[m.MenuCursor]; selectableFile(m, choice) {
+			files = []string{choice}
+		} else {
+			return m, nil
+		}
+	}
+	m = resetLogView(m)
+	return startTimeline(m, files)
+}
+
+// currentTimeline returns the timeline being viewed, if any
+func currentTimeline(m models.Model) (*fileops.Timeline, bool) {
+	timeline, ok := m.LogIndex.(*fileops.Timeline)
+	return timeline, ok && !m.CoreProtectMode
+}
+
+// sourceLabel returns the short name of a log shown in the gutter of a
+// timeline, including its server when logs from several are listed
+func sourceLabel(m models.Model, path string) string {
+	name := fileops.LogBaseName(path)
+
+	root := m.FileGroups[path]
+	if !menuGrouped(m) || root == "" {
+		return name
+	}
+	server := filepath.Base(root)
+	if server == "logs" {
+		server = filepath.Base(filepath.Dir(root))
+	}
+	return server + "/" + name
+}
+
+// timelineGutter returns a function rendering the gutter naming each entry's
+// log, padded so the entries line up
+func timelineGutter(m models.Model, timeline *fileops.Timeline) func(source string) string {
+	labels := make(map[string]string, len(timeline.Paths))
+	width := 0
+	for _, path := range timeline.Paths {
+		label := sourceLabel(m, path)
+		if len(label) > maxGutterWidth {
+			// The end of the name tells rotated logs apart
+			label = ".." + label[len(label)-maxGutterWidth+2:]
+		}
+		labels[path] = label
+		width = Max(width, len(label))
+	}
+	return func(source string) string {
+		label := labels[source]
+		return label + strings.Repeat(" ", width-len(label)) + " | "
+	}
+}
+
+// timelineTitle describes the logs merged into a timeline
+func timelineTitle(timeline *fileops.Timeline) string {
+	return fmt.Sprintf("Merged Timeline of %d Logs", len(timeline.Paths))
+}
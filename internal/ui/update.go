@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -9,29 +12,42 @@ import (
 	"goparselogs/internal/models"
 	"goparselogs/pkg/coreprotectparser"
 	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// scanLogsMsg is sent when the logs directory has been rescanned
+// scanLogsMsg is sent when the log sources have been rescanned
 type scanLogsMsg struct {
-	files []string
-	err   error
+	groups []fileops.LogGroup
+	err    error
 }
 
-// scanLogsDirCmd rescans the logs directory for changes
-func scanLogsDirCmd() tea.Cmd {
+// scanLogsDirCmd rescans the log sources for changes
+func scanLogsDirCmd(sources fileops.LogSources) tea.Cmd {
 	return func() tea.Msg {
-		files, err := fileops.ScanLogFiles()
-		return scanLogsMsg{files: files, err: err}
+		groups, err := fileops.ScanLogSources(sources)
+		if err != nil {
+			return scanLogsMsg{err: err}
+		}
+		return scanLogsMsg{groups: groups}
 	}
 }
 
-// periodicScanCmd sends a tick every 5 seconds to rescan the logs directory
-func periodicScanCmd() tea.Cmd {
-	return tea.Every(5*time.Second, func(t time.Time) tea.Msg {
-		return scanLogsDirCmd()()
-	})
+// detectFormatsMsg is sent when the formats of the listed files have been detected
+type detectFormatsMsg struct {
+	formats map[string]string
+}
+
+// detectFormatsCmd detects the format of each file in the background, since
+// it means opening every file and reading through archives
+func detectFormatsCmd(files []string) tea.Cmd {
+	if len(files) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		return detectFormatsMsg{formats: fileops.DetectLogFormats(files)}
+	}
 }
 
 // Update handles all the state updates based on incoming messages
@@ -72,7 +88,7 @@ func Update(msg tea.Msg, m models.Model) (models.Model, tea.Cmd) {
 		}
 
 		m.LeftPaneWidth = targetWidth
-		return m, periodicScanCmd() // Start periodic scanning when window is ready
+		return m, nil
 
 	case scanLogsMsg:
 		if msg.err != nil {
@@ -81,12 +97,7 @@ func Update(msg tea.Msg, m models.Model) (models.Model, tea.Cmd) {
 		}
 
 		// Create new menu choices with updated log files
-		newChoices := make([]string, 0, len(msg.files)+2)
-		newChoices = append(newChoices, msg.files...)
-		newChoices = append(newChoices,
-			fmt.Sprintf("%s (%s)", CoreProtectToggleBaseText, map[bool]string{true: "ON", false: "OFF"}[m.CoreProtectMode]),
-			ExitText,
-		)
+		newChoices, fileGroups := buildMenuChoices(msg.groups, m.CoreProtectMode)
 
 		// Adjust cursor if needed
 		if m.MenuCursor >= len(newChoices) {
@@ -94,7 +105,25 @@ func Update(msg tea.Msg, m models.Model) (models.Model, tea.Cmd) {
 		}
 
 		m.MenuChoices = newChoices
-		return m, periodicScanCmd() // Continue periodic scanning
+		m.FileGroups = fileGroups
+		return m, detectFormatsCmd(groupFiles(msg.groups))
+
+	case detectFormatsMsg:
+		// Formats detected before a rescan are kept until those after it
+		// arrive, and only for files still listed
+		formats := make(map[string]string, len(msg.formats))
+		for path, name := range m.FileFormats {
+			if _, ok := m.FileGroups[path]; ok {
+				formats[path] = name
+			}
+		}
+		for path, name := range msg.formats {
+			if _, ok := m.FileGroups[path]; ok {
+				formats[path] = name
+			}
+		}
+		m.FileFormats = formats
+		return m, nil
 
 	case tea.KeyMsg:
 		// Global quit
@@ -109,36 +138,97 @@ func Update(msg tea.Msg, m models.Model) (models.Model, tea.Cmd) {
 			return handleLogViewInput(msg, m)
 		case models.SaveInputView:
 			return handleSaveInputViewInput(msg, m)
+		case models.SearchView:
+			return handleSearchViewInput(msg, m)
 		}
 
-	case []logparser.LogEntry:
-		m.LogEntries = msg
-		m.LogCursor = 0
-		m.Err = nil
-		return m, periodicScanCmd()
+	case indexProgressMsg:
+		if msg.index != m.LogIndex {
+			return m, nil // A file that is no longer being viewed
+		}
+		m.Events = msg.index.Events()
+		done, err := msg.index.Done()
+		if err != nil {
+			m.Err = err
+		}
+		var followCmd tea.Cmd
+		if done && m.Following {
+			m.LogCursor = Max(logEntryCount(m)-1, 0)
+			// Pick up anything written since the log was read, as changes aren't
+			// picked up while it is being indexed
+			m, followCmd = scheduleFollow(m)
+		}
+		var findCmd tea.Cmd
+		if done {
+			m, findCmd = startFindScan(m)
+		}
+		m, cmd = ensureLogWindow(m)
+		cmd = tea.Batch(cmd, followCmd, findCmd)
+		if !done {
+			cmd = tea.Batch(cmd, pollIndexCmd(msg.index))
+		}
+		return m, cmd
+
+	case logWindowMsg:
+		if msg.index != m.LogIndex {
+			return m, nil
+		}
+		m.LogWindowLoading = false
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		m.LogEntries = msg.entries
+		m.LogWindowStart = msg.start
+		// The cursor may have moved on while the window was loading
+		return ensureLogWindow(m)
+
+	case logRefreshMsg:
+		return handleLogRefresh(msg, m)
 
-	case []coreprotectparser.CoreProtectLogEntry:
-		m.CoreProtectLogEntries = msg
+	case findScanMsg:
+		return handleFindScan(msg, m)
+
+	case watchEventMsg:
+		return handleWatchEvent(msg, m)
+
+	case searchProgressMsg:
+		return handleSearchProgress(msg, m)
+
+	case *coreprotectparser.ParsedLog:
+		m.CoreProtectLogEntries = msg.Entries
+		m.CoreProtectSessions = msg.Sessions
+		m.Summaries = coreprotectparser.Summarize(msg.Entries)
+		m.SummaryCursor = Min(m.SummaryCursor, Max(len(m.Summaries)-1, 0))
+		m.CoreProtectLoading = false
 		m.LogCursor = 0
+		m.LogOffset = 0
 		m.Err = nil
-		return m, periodicScanCmd()
+		return m, nil
 
 	case models.SaveSuccessMsg:
-		m.SaveMessage = fmt.Sprintf("Logs saved to output/%s", msg.Filename)
+		m.SaveMessage = fmt.Sprintf("Logs saved to %s", msg.Filename)
 		m.State = m.PreviousState
 		m.FocusedPane = models.LogFilePane
 		m.InputActive = false
 		m.SaveFilenameInput = ""
-		return m, periodicScanCmd()
+		return m, nil
 
 	case models.SaveErrorMsg:
+		if errors.Is(msg.Err, fs.ErrExist) && m.State == models.SaveInputView {
+			// Ask before replacing it, keeping the dialog open
+			m.SaveConfirmOverwrite = true
+			m.SaveMessage = fmt.Sprintf("%v. Overwrite? (y/n)", msg.Err)
+			return m, nil
+		}
 		m.SaveMessage = fmt.Sprintf("Error saving: %v", msg.Err)
 		m.State = m.PreviousState
-		return m, periodicScanCmd()
+		return m, nil
 
 	case error:
 		m.Err = msg
-		return m, periodicScanCmd()
+		m.CoreProtectLoading = false
+		return m, nil
 	}
 
 	return m, cmd
@@ -159,9 +249,19 @@ func handleMenuViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 				m.MenuCursor++
 			}
 		case "tab":
+			m.FocusedPane = models.FilterPane
+			m.InputActive = true
+		case "s":
 			if !m.CoreProtectMode {
-				m.FocusedPane = models.FilterPane
-				m.InputActive = true
+				return startGlobalSearch(m)
+			}
+		case " ":
+			m = toggleSelected(m)
+		case "r":
+			m = selectRange(m)
+		case "m":
+			if !m.CoreProtectMode {
+				return openTimeline(m)
 			}
 		case "enter":
 			selectedChoice := m.MenuChoices[m.MenuCursor]
@@ -169,14 +269,10 @@ func handleMenuViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 				return m, tea.Quit
 			} else if strings.HasPrefix(selectedChoice, CoreProtectToggleBaseText) {
 				m.CoreProtectMode = !m.CoreProtectMode
+				m.CoreProtectForced = false
 				return m, nil
 			} else {
-				m.State = models.LogView
-				m.LogEntries = []logparser.LogEntry{}
-				m.CoreProtectLogEntries = []coreprotectparser.CoreProtectLogEntry{}
-				m.LogCursor = 0
-				m.Err = nil
-				return m, loadLogFileCmd(selectedChoice, m.Filters, m.CoreProtectMode)
+				return openLogFile(m, selectedChoice)
 			}
 		}
 	} else if m.FocusedPane == models.FilterPane {
@@ -194,12 +290,24 @@ func handleMenuViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 			m.InputActive = false
 		case "enter":
 			if m.FilterInput != "" {
-				m.Filters = append(m.Filters, m.FilterInput)
+				q, err := query.Parse(m.FilterInput)
+				if err != nil {
+					m.FilterErr = err
+					return m, nil
+				}
+				m.Filters = append(m.Filters, q)
 				m.FilterInput = ""
-				if m.State == models.LogView && !m.CoreProtectMode {
+				m.FilterErr = nil
+				if m.State == models.SearchView {
+					return startGlobalSearch(m)
+				}
+				if timeline, ok := currentTimeline(m); ok && m.State == models.LogView {
+					return startTimeline(m, timeline.Paths)
+				}
+				if m.State == models.LogView {
 					currentLogFile := m.MenuChoices[m.MenuCursor]
 					if !strings.HasPrefix(currentLogFile, CoreProtectToggleBaseText) && currentLogFile != ExitText {
-						return m, loadLogFileCmd(currentLogFile, m.Filters, m.CoreProtectMode)
+						return loadLogFile(currentLogFile, m)
 					}
 				}
 			}
@@ -207,9 +315,12 @@ func handleMenuViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 			if len(m.FilterInput) > 0 {
 				m.FilterInput = m.FilterInput[:len(m.FilterInput)-1]
 			}
+			m.FilterErr = nil
 		default:
-			if msg.Type == tea.KeyRunes && len(msg.Runes) > 0 {
+			// Spaces arrive as their own key type and are needed for phrases and operators
+			if (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && len(msg.Runes) > 0 {
 				m.FilterInput += string(msg.Runes)
+				m.FilterErr = nil
 			}
 		}
 	}
@@ -218,6 +329,33 @@ func handleMenuViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 
 // handleLogViewInput handles input when in log view
 func handleLogViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	// Typing into the filter input works the same as in the menu view
+	if m.FocusedPane == models.FilterPane {
+		return handleMenuViewInput(msg, m)
+	}
+	if m.JumpActive {
+		return handleJumpInput(msg, m)
+	}
+	if m.FindActive {
+		return handleFindInput(msg, m)
+	}
+
+	if m.EventMode && !m.CoreProtectMode {
+		if handled, newM := handleEventViewInput(msg, m); handled {
+			return ensureLogWindow(newM)
+		}
+	} else if m.SummaryMode && m.CoreProtectMode {
+		if handled, newM := handleSummaryViewInput(msg, m); handled {
+			return newM, nil
+		}
+	} else if handled, newM := handleViewportInput(msg, m); handled {
+		return ensureLogWindow(newM)
+	} else if handled, newM := handleFindKeys(msg, m); handled {
+		return ensureLogWindow(scrollLogView(newM))
+	} else if handled, newM := handleDetailInput(msg, m); handled {
+		return ensureLogWindow(scrollLogView(newM))
+	}
+
 	switch msg.String() {
 	case "q":
 		return m, tea.Quit
@@ -226,125 +364,352 @@ func handleLogViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd)
 			m.LogCursor--
 		}
 	case "down", "j":
-		currentLogListSize := 0
-		if m.CoreProtectMode {
-			currentLogListSize = len(m.CoreProtectLogEntries)
-		} else {
-			currentLogListSize = len(m.LogEntries)
-		}
-
-		if m.FocusedPane != models.FilterPane && m.LogCursor < currentLogListSize-1 {
+		if m.FocusedPane != models.FilterPane && m.LogCursor < logEntryCount(m)-1 {
 			m.LogCursor++
 		}
+	case "enter":
+		// Expand or collapse the stack trace attached to the selected entry
+		if entry, ok := logEntryAt(m, m.LogCursor); ok && m.FocusedPane != models.FilterPane && !m.CoreProtectMode && entry.HasContinuation() {
+			if m.ExpandedEntries == nil {
+				m.ExpandedEntries = map[int]bool{}
+			}
+			m.ExpandedEntries[m.LogCursor] = !m.ExpandedEntries[m.LogCursor]
+		}
+	case "v":
+		if m.FocusedPane != models.FilterPane && !m.CoreProtectMode {
+			m.EventMode = !m.EventMode
+		} else if m.FocusedPane != models.FilterPane {
+			m.SummaryMode = !m.SummaryMode
+		}
+	case "f":
+		if !m.CoreProtectMode {
+			return toggleFollow(m)
+		}
+	case "s":
+		if !m.CoreProtectMode {
+			return startGlobalSearch(m)
+		}
 	case "e":
-		if (m.CoreProtectMode && len(m.CoreProtectLogEntries) > 0) || (!m.CoreProtectMode && len(m.LogEntries) > 0) {
+		if logEntryCount(m) > 0 {
 			m.PreviousState = m.State
 			m.State = models.SaveInputView
 			m.SaveFilenameInput = ""
+			m.SaveFormat = fileops.FormatText
+			m.SaveConfirmOverwrite = false
+			m.SaveCompletions = nil
 			m.SaveMessage = ""
 		}
 	case "esc":
+		if m.FindPattern != "" && !m.EventMode && !m.SummaryMode {
+			// Clear the search highlighting before leaving the log
+			m.FindPattern = ""
+			return stopFindScan(m), nil
+		}
+		if m.CoreProtectMode && m.PlayerFilter != "" {
+			// Back to the player summary the entries were opened from
+			m.PlayerFilter = ""
+			m.SummaryMode = true
+			m.LogCursor = 0
+			return m, nil
+		}
+		if m.FromSearch && m.Search != nil {
+			// Back to the search results the log was opened from
+			m.FromSearch = false
+			m.State = models.SearchView
+			return m, nil
+		}
 		m.State = models.MenuView
 		m.FocusedPane = models.LogFilePane
 		m.InputActive = false
 		m.SaveMessage = ""
+		m = restoreCoreProtectMode(m)
 	case "tab":
-		if !m.CoreProtectMode {
-			if m.FocusedPane == models.LogFilePane {
-				m.FocusedPane = models.FilterPane
-				m.InputActive = true
-			} else {
-				m.FocusedPane = models.LogFilePane
-				m.InputActive = false
-			}
+		if m.FocusedPane == models.LogFilePane {
+			m.FocusedPane = models.FilterPane
+			m.InputActive = true
 		} else {
 			m.FocusedPane = models.LogFilePane
 			m.InputActive = false
 		}
 	}
-	return m, nil
+	return ensureLogWindow(scrollLogView(m))
 }
 
 // handleSaveInputViewInput handles input when in save input view
 func handleSaveInputViewInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	if m.SaveConfirmOverwrite {
+		return handleOverwriteInput(msg, m)
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		return m, tea.Quit
 	case "esc":
 		m.State = m.PreviousState
 		m.SaveFilenameInput = ""
+		m.SaveCompletions = nil
 		m.SaveMessage = ""
 		m.FocusedPane = models.LogFilePane
 		m.InputActive = false
+		return m, nil
 	case "enter":
-		if m.SaveFilenameInput != "" {
-			return m, saveFileCmd(m)
-		} else {
-			m.SaveMessage = "Filename cannot be empty."
+		path, err := exportPath(m)
+		if err != nil {
+			m.SaveMessage = fmt.Sprintf("Error: %v", err)
+			return m, nil
 		}
+		return m, saveFileCmd(m, path, false)
+	case "tab":
+		completed, matches, _ := fileops.CompleteDirectory(m.OutputDir, m.SaveFilenameInput)
+		m.SaveFilenameInput = completed
+		m.SaveCompletions = nil
+		if len(matches) > 1 {
+			m.SaveCompletions = matches
+		}
+		m.SaveMessage = ""
+		return m, nil
+	case "right":
+		m = cycleSaveFormat(m, 1)
+	case "left":
+		m = cycleSaveFormat(m, -1)
+	case "ctrl+g":
+		m = toggleSaveGzip(m)
 	case "backspace":
 		if len(m.SaveFilenameInput) > 0 {
 			m.SaveFilenameInput = m.SaveFilenameInput[:len(m.SaveFilenameInput)-1]
 		}
+		if format, ok := fileops.FormatForFilename(m.SaveFilenameInput); ok {
+			m.SaveFormat = format
+		}
 	default:
 		if msg.Type == tea.KeyRunes && len(msg.Runes) > 0 {
 			m.SaveFilenameInput += string(msg.Runes)
+			if format, ok := fileops.FormatForFilename(m.SaveFilenameInput); ok {
+				m.SaveFormat = format
+			}
 		}
 	}
+	m.SaveCompletions = nil
+	m.SaveMessage = ""
 	return m, nil
 }
 
-// saveFileCmd creates a command to save the log entries
-func saveFileCmd(m models.Model) tea.Cmd {
-	return func() tea.Msg {
-		if m.SaveFilenameInput == "" {
-			return models.SaveErrorMsg{Err: fmt.Errorf("filename cannot be empty")}
+// handleOverwriteInput handles the answer to whether an existing export should be replaced
+func handleOverwriteInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "y", "Y":
+		m.SaveConfirmOverwrite = false
+		path, err := exportPath(m)
+		if err != nil {
+			m.SaveMessage = fmt.Sprintf("Error: %v", err)
+			return m, nil
+		}
+		return m, saveFileCmd(m, path, true)
+	case "n", "N", "esc":
+		// Back to editing the filename
+		m.SaveConfirmOverwrite = false
+		m.SaveMessage = ""
+	}
+	return m, nil
+}
+
+// exportPath returns the path the export is saved to, after expanding the
+// filename template and checking it stays within the output directory
+func exportPath(m models.Model) (string, error) {
+	filters := query.Sources(m.Filters)
+	if m.PlayerFilter != "" {
+		filters = append(filters, m.PlayerFilter)
+	}
+	var file string
+	if _, ok := currentTimeline(m); ok {
+		file = "timeline"
+	} else if m.MenuCursor < len(m.MenuChoices) {
+		file = m.MenuChoices[m.MenuCursor]
+	}
+	filename := fileops.ExpandFilenameTemplate(m.SaveFilenameInput, fileops.FilenameVars{
+		File:    file,
+		Filters: filters,
+		Now:     time.Now(),
+	})
+	return fileops.ResolveOutputPath(m.OutputDir, filename)
+}
+
+// cycleSaveFormat switches to the next export format in the given direction,
+// changing the extension of the filename to match if it has one for another
+// format or none at all
+func cycleSaveFormat(m models.Model, step int) models.Model {
+	next := 0
+	count := len(fileops.ExportFormats)
+	for i, format := range fileops.ExportFormats {
+		if format == m.SaveFormat {
+			next = ((i+step)%count + count) % count
 		}
+	}
+	m.SaveFormat = fileops.ExportFormats[next]
+
+	if m.SaveFilenameInput == "" {
+		return m
+	}
+	name, gzipped := strings.CutSuffix(m.SaveFilenameInput, fileops.GzipExtension)
+	ext := filepath.Ext(name)
+	if _, ok := fileops.FormatForFilename(name); ok || ext == "" {
+		name = strings.TrimSuffix(name, ext) + m.SaveFormat.Extension()
+	}
+	if gzipped {
+		name += fileops.GzipExtension
+	}
+	m.SaveFilenameInput = name
+	return m
+}
+
+// toggleSaveGzip adds or removes the gzip extension that compresses the export
+func toggleSaveGzip(m models.Model) models.Model {
+	if name, ok := strings.CutSuffix(m.SaveFilenameInput, fileops.GzipExtension); ok {
+		m.SaveFilenameInput = name
+	} else if m.SaveFilenameInput != "" {
+		m.SaveFilenameInput += fileops.GzipExtension
+	}
+	return m
+}
+
+// saveFileCmd creates a command to save the log entries to path
+func saveFileCmd(m models.Model, path string, overwrite bool) tea.Cmd {
+	opts := fileops.ExportOptions{
+		OutputDir: m.OutputDir,
+		Path:      path,
+		Format:    m.SaveFormat,
+		Overwrite: overwrite,
+	}
+	return func() tea.Msg {
 		var err error
 		if m.CoreProtectMode {
-			err = fileops.SaveCoreProtectLogsToFile(m.CoreProtectLogEntries, m.SaveFilenameInput)
+			err = fileops.SaveCoreProtectLogsToFile(coreProtectEntries(m), opts)
 		} else {
-			err = fileops.SaveStandardLogsToFile(m.LogEntries, m.SaveFilenameInput)
+			err = fileops.SaveIndexedLogsToFile(m.LogIndex, opts)
 		}
 		if err != nil {
 			return models.SaveErrorMsg{Err: err}
 		}
-		return models.SaveSuccessMsg{Filename: m.SaveFilenameInput}
+		return models.SaveSuccessMsg{Filename: path}
+	}
+}
+
+// queryFilter returns a log entry filter matching any of the queries
+func queryFilter(queries []*query.Query) logparser.Filter {
+	if len(queries) == 0 {
+		return nil
+	}
+	return func(entry logparser.LogEntry) bool {
+		return query.MatchAny(queries, entry)
+	}
+}
+
+// queryTerms returns the terms one of which entries matching any of the queries
+// must contain, letting cached indexes skip entries without them
+func queryTerms(queries []*query.Query) [][]string {
+	return query.AnyRequiredTerms(queries, logparser.TextFields...)
+}
+
+// openLogFile switches to the log view and starts loading a log file, selecting it in the menu if listed
+func openLogFile(m models.Model, filePath string) (models.Model, tea.Cmd) {
+	for i, choice := range m.MenuChoices {
+		if choice == filePath {
+			m.MenuCursor = i
+		}
+	}
+	return loadLogFile(filePath, resetLogView(m))
+}
+
+// resetLogView switches to the log view, clearing the state of the log viewed before
+func resetLogView(m models.Model) models.Model {
+	m.State = models.LogView
+	m.CoreProtectLogEntries = []coreprotectparser.CoreProtectLogEntry{}
+	m.CoreProtectSessions = nil
+	m.Summaries = nil
+	m.SummaryMode = false
+	m.SummaryCursor = 0
+	m.PlayerFilter = ""
+	m.LogCursor = 0
+	m.LogOffset = 0
+	m.LogColumn = 0
+	m.JumpActive = false
+	m.FindActive = false
+	m.Following = false
+	m = unfollow(m)
+	m.FromSearch = false
+	m.Err = nil
+	return m
+}
+
+// loadLogFile starts loading a log file. Standard logs are indexed in the
+// background and read a window at a time, while CoreProtect logs are parsed whole.
+func loadLogFile(filePath string, m models.Model) (models.Model, tea.Cmd) {
+	if fileops.IsCoreProtectDatabase(filePath) {
+		// Databases only hold CoreProtect actions
+		if !m.CoreProtectMode {
+			m.CoreProtectMode = true
+			m.CoreProtectForced = true
+		}
+		m.CoreProtectLoading = true
+		return m, loadCoreProtectDatabaseCmd(filePath, m.Filters)
+	}
+	m = restoreCoreProtectMode(m)
+	if m.CoreProtectMode {
+		m.CoreProtectLoading = true
+		return m, loadCoreProtectFileCmd(filePath, m.Filters, m.Location)
+	}
+	return startLogIndex(m, filePath)
+}
+
+// restoreCoreProtectMode turns CoreProtect mode back off if it was only turned
+// on to open a CoreProtect database
+func restoreCoreProtectMode(m models.Model) models.Model {
+	if m.CoreProtectForced {
+		m.CoreProtectMode = false
+		m.CoreProtectForced = false
 	}
+	return m
 }
 
-// loadLogFileCmd is a command that sends the loaded entries back as a message
-func loadLogFileCmd(filePath string, filters []string, coreProtectMode bool) tea.Cmd {
+// coreProtectQueryFilter returns a CoreProtect entry filter matching any of the queries
+func coreProtectQueryFilter(queries []*query.Query) coreprotectparser.Filter {
+	if len(queries) == 0 {
+		return nil
+	}
+	return func(entry coreprotectparser.CoreProtectLogEntry) bool {
+		return query.MatchAny(queries, entry)
+	}
+}
+
+// loadCoreProtectFileCmd is a command that sends the parsed CoreProtect log back as a message
+func loadCoreProtectFileCmd(filePath string, filters []*query.Query, loc *time.Location) tea.Cmd {
 	return func() tea.Msg {
-		if coreProtectMode {
-			// For CoreProtect, we read the whole file content then parse
-			content, err := fileops.ReadFileContent(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to read CoreProtect log file %s: %w", filePath, err)
-			}
-			cpLog, err := coreprotectparser.ParseLogContent(content)
-			if err != nil {
-				return fmt.Errorf("failed to parse CoreProtect log file %s: %w", filePath, err)
-			}
-			return cpLog.Entries
-		} else {
-			// Standard log parsing
-			parser, err := logparser.NewParser()
-			if err != nil {
-				return err
-			}
+		cpLog, err := fileops.ReadCoreProtectLog(filePath, coreProtectQueryFilter(filters), loc)
+		if err != nil {
+			return err
+		}
+		return cpLog
+	}
+}
 
-			// Use our gzip-aware reader for all files
-			content, err := fileops.ReadFileContent(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to read log file %s: %w", filePath, err)
-			}
+// loadCoreProtectDatabaseCmd is a command that sends the newest actions in a
+// CoreProtect database matching the filters back as a message
+func loadCoreProtectDatabaseCmd(filePath string, filters []*query.Query) tea.Cmd {
+	return func() tea.Msg {
+		database, err := coreprotectparser.OpenDatabase(filePath)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
 
-			entries, err := parser.ParseContent(content, filters)
-			if err != nil {
-				return fmt.Errorf("failed to parse log content from %s: %w", filePath, err)
-			}
-			return entries
+		q := coreprotectparser.NewDatabaseQuery(filters, time.Now())
+		q.Limit = coreprotectparser.DefaultDatabaseLimit
+		cpLog, err := database.Lookup(q, coreProtectQueryFilter(filters))
+		if err != nil {
+			return fmt.Errorf("failed to read CoreProtect database %s: %w", filePath, err)
 		}
+		return cpLog
 	}
 }
@@ -0,0 +1,315 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"goparselogs/internal/models"
+	"goparselogs/pkg/coreprotectparser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// logViewLayout describes the space the entries of the log view are drawn in
+type logViewLayout struct {
+	width       int                                     // Columns available for an entry's text, after the cursor marker
+	lines       int                                     // Lines available for entries
+	detailLines int                                     // Lines available for the details of the selected entry, if shown
+	gutter      func(source string) string              // Names the source of each timeline entry, nil for single logs
+	cpEntries   []coreprotectparser.CoreProtectLogEntry // CoreProtect entries being listed
+	find        *regexp.Regexp                          // Matches the text being found, nil if there is none
+}
+
+// newLogViewLayout works out the space the log view has for entries
+func newLogViewLayout(m models.Model) logViewLayout {
+	headerFooterAndPaddingHeight := m.RightPaneStyle.GetVerticalPadding() + 2 + 1 + 1 + 1 + 1
+	layout := logViewLayout{
+		width: Max(calcRightPaneWidth(m)-m.RightPaneStyle.GetHorizontalPadding()-2, 5),
+		lines: Max(m.TermHeight-headerFooterAndPaddingHeight, 1),
+		find:  findRegexp(findPattern(m)),
+	}
+	if m.ShowDetails {
+		// The detail pane takes half the space, plus a line above and below it
+		layout.detailLines = Max(layout.lines/2, 1)
+		layout.lines = Max(layout.lines-layout.detailLines-2, 1)
+	}
+	if m.CoreProtectMode {
+		layout.cpEntries = coreProtectEntries(m)
+	} else if timeline, ok := currentTimeline(m); ok {
+		layout.gutter = timelineGutter(m, timeline)
+	}
+	return layout
+}
+
+// logRow is a line of the log view taken up by an entry
+type logRow struct {
+	text    string
+	subtle  bool     // Drawn in the subtle style, as stack trace lines and entries still loading are
+	matches [][2]int // Byte ranges of the text matching the text being found
+}
+
+// logEntryText returns the text of entry i as shown in the log view: the
+// gutter naming its source, its own line and any stack trace lines shown
+// below it. It returns false for entries that haven't been loaded yet.
+func logEntryText(m models.Model, layout logViewLayout, i int) (gutter, line string, continuation []string, ok bool) {
+	if m.CoreProtectMode {
+		return "", formatCoreProtectLine(layout.cpEntries[i]), nil, true
+	}
+	entry, ok := logEntryAt(m, i)
+	if !ok {
+		return "", "", nil, false
+	}
+	line = formatLogEntryLine(entry)
+	if entry.HasContinuation() && !m.ExpandedEntries[i] {
+		line = fmt.Sprintf("%s [+%d]", line, len(entry.Continuation))
+	}
+	if layout.gutter != nil {
+		gutter = layout.gutter(entry.Source)
+	}
+	if m.ExpandedEntries[i] {
+		for _, contLine := range entry.Continuation {
+			continuation = append(continuation, "    "+strings.ReplaceAll(contLine, "\t", "    "))
+		}
+	}
+	return gutter, line, continuation, true
+}
+
+// logEntryRows returns the lines entry i takes up in the log view, wrapped or
+// cut off to fit. An entry never takes up more lines than the view has, so
+// the selected entry always fits on screen.
+func logEntryRows(m models.Model, layout logViewLayout, i int) []logRow {
+	gutter, line, continuation, ok := logEntryText(m, layout, i)
+	if !ok {
+		// Outside the loaded window until it catches up with the cursor
+		return []logRow{{text: "...", subtle: true}}
+	}
+
+	rows := fitLineRows(gutter, line, layout.width, m.WrapLines, m.LogColumn, layout.find)
+	for _, contLine := range continuation {
+		for _, row := range fitLineRows("", contLine, layout.width, m.WrapLines, m.LogColumn, layout.find) {
+			row.subtle = true
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) > layout.lines {
+		rows = rows[:layout.lines]
+	}
+	return rows
+}
+
+// logEntryHeight returns the number of lines entry i takes up in the log view
+func logEntryHeight(m models.Model, layout logViewLayout) func(int) int {
+	return func(i int) int {
+		return len(logEntryRows(m, layout, i))
+	}
+}
+
+// fitLine fits a line of text into width columns, either wrapping it onto as
+// many lines as it needs or cutting it off after scrolling column columns to
+// the right. The prefix, such as a timeline's gutter, is never scrolled and
+// the wrapped lines are indented to match it.
+func fitLine(prefix, text string, width int, wrap bool, column int) []string {
+	var lines []string
+	for _, row := range fitLineRows(prefix, text, width, wrap, column, nil) {
+		lines = append(lines, row.text)
+	}
+	return lines
+}
+
+// fitLineRows fits a line of text into rows as fitLine does, marking where re
+// matches the text in each of them. Matches are found in the whole text, so
+// that one split across rows is marked on both.
+func fitLineRows(prefix, text string, width int, wrap bool, column int, re *regexp.Regexp) []logRow {
+	prefixWidth := len([]rune(prefix))
+	room := Max(width-prefixWidth, 5)
+	runes := []rune(text)
+
+	var matches [][2]int // Rune ranges of the text
+	if re != nil {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			matches = append(matches, [2]int{utf8.RuneCountInString(text[:loc[0]]), utf8.RuneCountInString(text[:loc[1]])})
+		}
+	}
+	// row shows runes [from, to) of the text between the prefix and suffix
+	row := func(prefix string, from, to int, suffix string) logRow {
+		r := logRow{text: prefix + string(runes[from:to]) + suffix}
+		for _, match := range matches {
+			if start, end := Max(match[0], from), Min(match[1], to); start < end {
+				offset := len(prefix) + len(string(runes[from:start]))
+				r.matches = append(r.matches, [2]int{offset, offset + len(string(runes[start:end]))})
+			}
+		}
+		return r
+	}
+
+	if !wrap {
+		from := Min(column, len(runes))
+		if len(runes)-from > room {
+			return []logRow{row(prefix, from, from+room-3, "...")}
+		}
+		return []logRow{row(prefix, from, len(runes), "")}
+	}
+
+	var rows []logRow
+	indent := strings.Repeat(" ", prefixWidth)
+	from := 0
+	for len(runes)-from > room {
+		// Break after the last space, unless that leaves most of the line empty
+		cut := room
+		for j := room; j > room/2; j-- {
+			if unicode.IsSpace(runes[from+j-1]) {
+				cut = j
+				break
+			}
+		}
+		rows = append(rows, row(prefix, from, from+cut, ""))
+		from += cut
+		prefix = indent
+	}
+	return append(rows, row(prefix, from, len(runes), ""))
+}
+
+// logViewport returns the [start, end) range of entries shown in the log view,
+// scrolled as little as possible from LogOffset to bring the cursor into view.
+func logViewport(m models.Model, layout logViewLayout) (int, int) {
+	count := logEntryCount(m)
+	if count == 0 {
+		return 0, 0
+	}
+	height := logEntryHeight(m, layout)
+	cursor := Max(Min(m.LogCursor, count-1), 0)
+
+	fill := func(start int) (int, int) {
+		end, used := start, 0
+		for end < count && (end == start || used+height(end) <= layout.lines) {
+			used += height(end)
+			end++
+		}
+		return end, used
+	}
+
+	start := Max(Min(m.LogOffset, cursor), 0)
+	end, used := fill(start)
+	if cursor >= end {
+		// Scroll down until the cursor is on the last line
+		start, used = cursor, height(cursor)
+		for start > 0 && used+height(start-1) <= layout.lines {
+			start--
+			used += height(start)
+		}
+		end, used = fill(start)
+	}
+	if end == count {
+		// Don't leave lines empty at the bottom while there are entries above
+		for start > 0 && used+height(start-1) <= layout.lines {
+			start--
+			used += height(start)
+		}
+	}
+	return start, end
+}
+
+// scrollLogView scrolls the log view as little as possible to bring the cursor into view
+func scrollLogView(m models.Model) models.Model {
+	m.LogOffset, _ = logViewport(m, newLogViewLayout(m))
+	return m
+}
+
+// centreLogView scrolls the log view so that the cursor is roughly in the middle, as after a jump
+func centreLogView(m models.Model) models.Model {
+	layout := newLogViewLayout(m)
+	m.LogOffset, _ = visibleEntryRange(m.LogCursor, logEntryCount(m), layout.lines, logEntryHeight(m, layout))
+	return m
+}
+
+// maxLogColumn returns how far the log view can be scrolled to the right for
+// the end of the longest of the entries shown to come into view
+func maxLogColumn(m models.Model, layout logViewLayout, start, end int) int {
+	column := 0
+	for i := start; i < end; i++ {
+		gutter, line, continuation, ok := logEntryText(m, layout, i)
+		if !ok {
+			continue
+		}
+		column = Max(column, len([]rune(line))-(layout.width-len([]rune(gutter))))
+		for _, contLine := range continuation {
+			column = Max(column, len([]rune(contLine))-layout.width)
+		}
+	}
+	return column
+}
+
+// handleViewportInput handles the keys that scroll the entries of the log
+// view. It returns false for keys that should fall through to the regular
+// log view handling.
+func handleViewportInput(msg tea.KeyMsg, m models.Model) (bool, models.Model) {
+	count := logEntryCount(m)
+	// The cursor may have been moved without scrolling, such as when following the log
+	m = scrollLogView(m)
+	layout := newLogViewLayout(m)
+	start, end := logViewport(m, layout)
+
+	switch msg.String() {
+	case "pgdown":
+		page := Max(end-start, 1)
+		m.LogCursor = Min(m.LogCursor+page, Max(count-1, 0))
+		m.LogOffset = start + page
+	case "pgup":
+		page := Max(end-start, 1)
+		m.LogCursor = Max(m.LogCursor-page, 0)
+		m.LogOffset = Max(start-page, 0)
+	case "home", "g":
+		m.LogCursor = 0
+		m.LogOffset = 0
+	case "end", "G":
+		m.LogCursor = Max(count-1, 0)
+		m.LogOffset = m.LogCursor
+	case ":":
+		m.JumpActive = true
+		m.JumpInput = ""
+		return true, m
+	case "w":
+		m.WrapLines = !m.WrapLines
+		m.LogColumn = 0
+	case "left", "h":
+		if !m.WrapLines {
+			m.LogColumn = Max(m.LogColumn-layout.width/2, 0)
+		}
+		return true, m
+	case "right", "l":
+		if !m.WrapLines {
+			m.LogColumn = Max(Min(m.LogColumn+layout.width/2, maxLogColumn(m, layout, start, end)), m.LogColumn)
+		}
+		return true, m
+	default:
+		return false, m
+	}
+	return true, scrollLogView(m)
+}
+
+// handleJumpInput handles typing the number of the entry to jump to
+func handleJumpInput(msg tea.KeyMsg, m models.Model) (models.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.JumpActive = false
+	case "enter":
+		m.JumpActive = false
+		if n, err := strconv.Atoi(m.JumpInput); err == nil && logEntryCount(m) > 0 {
+			m.LogCursor = Max(Min(n, logEntryCount(m)), 1) - 1
+			m = centreLogView(m)
+		}
+	case "backspace":
+		if len(m.JumpInput) > 0 {
+			m.JumpInput = m.JumpInput[:len(m.JumpInput)-1]
+		}
+	default:
+		if msg.Type == tea.KeyRunes && len(msg.Runes) > 0 && unicode.IsDigit(msg.Runes[0]) {
+			m.JumpInput += string(msg.Runes)
+		}
+	}
+	return ensureLogWindow(m)
+}
@@ -0,0 +1,278 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Terminal size of the test models, leaving the log view testLogWidth columns and testLogLines lines for entries
+const (
+	testTermWidth  = 100
+	testTermHeight = 18
+	testLogWidth   = 73
+	testLogLines   = 10
+)
+
+// testLogModel returns a model viewing a log with the given lines, all of them
+// loaded, once its format has been detected
+func testLogModel(t *testing.T, log string) models.Model {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "latest.log")
+	require.NoError(t, os.WriteFile(path, []byte(log), 0o644))
+
+	ix := fileops.NewLogIndex(path, nil, time.UTC)
+	ix.Build()
+	t.Cleanup(func() { ix.Close() })
+	entries, err := ix.ReadEntries(0, ix.Len())
+	require.NoError(t, err)
+
+	m := createInitialState(Options{Sources: fileops.LogSources{Dirs: []string{dir}}, Location: time.UTC})
+	m, _ = Update(detectFormatsCmd(menuFiles(m))(), m)
+	m.State = models.LogView
+	m.TermWidth = testTermWidth
+	m.TermHeight = testTermHeight
+	m.LeftPaneWidth = 20
+	m.LogIndex = ix
+	m.LogEntries = entries
+	return m
+}
+
+// numberedLog returns a log of count one-line entries, numbered from 1
+func numberedLog(count int) string {
+	var log strings.Builder
+	for i := 1; i <= count; i++ {
+		fmt.Fprintf(&log, "[12:00:00] [Server thread/INFO]: Entry %d\n", i)
+	}
+	return log.String()
+}
+
+func keyMsg(key string) tea.KeyMsg {
+	switch key {
+	case "pgdown":
+		return tea.KeyMsg{Type: tea.KeyPgDown}
+	case "pgup":
+		return tea.KeyMsg{Type: tea.KeyPgUp}
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "backspace":
+		return tea.KeyMsg{Type: tea.KeyBackspace}
+	case "left":
+		return tea.KeyMsg{Type: tea.KeyLeft}
+	case "right":
+		return tea.KeyMsg{Type: tea.KeyRight}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+func TestNewLogViewLayout(t *testing.T) {
+	m := testLogModel(t, numberedLog(1))
+	layout := newLogViewLayout(m)
+	assert.Equal(t, testLogWidth, layout.width)
+	assert.Equal(t, testLogLines, layout.lines)
+	assert.Zero(t, layout.detailLines)
+
+	// The detail pane takes half the lines, and one more above and below it
+	m.ShowDetails = true
+	layout = newLogViewLayout(m)
+	assert.Equal(t, testLogLines/2, layout.detailLines)
+	assert.Equal(t, testLogLines-testLogLines/2-2, layout.lines)
+}
+
+func TestFitLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		text   string
+		width  int
+		wrap   bool
+		column int
+		want   []string
+	}{
+		{name: "fits", text: "short line", width: 20, want: []string{"short line"}},
+		{name: "cut off", text: "a line that is too long", width: 10, want: []string{"a line ..."}},
+		{name: "scrolled", text: "a line that is too long", width: 10, column: 7, want: []string{"that is..."}},
+		{name: "scrolled to the end", text: "a line that is too long", width: 10, column: 15, want: []string{"too long"}},
+		{name: "scrolled past the end", text: "short", width: 10, column: 20, want: []string{""}},
+		{name: "prefix isn't scrolled", prefix: "a | ", text: "a line that is too long", width: 14, column: 7, want: []string{"a | that is..."}},
+		{name: "wrapped at spaces", text: "a line that is too long", width: 10, wrap: true, want: []string{"a line ", "that is ", "too long"}},
+		{name: "wrapped mid-word", text: "abcdefghijklmnop", width: 10, wrap: true, want: []string{"abcdefghij", "klmnop"}},
+		{name: "wrapped under prefix", prefix: "a | ", text: "a line that is too long", width: 14, wrap: true, want: []string{"a | a line ", "    that is ", "    too long"}},
+		{name: "wrapping ignores column", text: "a line", width: 10, wrap: true, column: 3, want: []string{"a line"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fitLine(tt.prefix, tt.text, tt.width, tt.wrap, tt.column))
+		})
+	}
+}
+
+func TestLogEntryRows_WrapHeight(t *testing.T) {
+	long := strings.Repeat("word ", 40)
+	m := testLogModel(t, "[12:00:00] [Server thread/INFO]: "+long+"\n"+
+		"[12:00:01] [Server thread/WARN]: Can't keep up!\n"+
+		"\tat Example.run(Example.java:1)\n"+
+		"\tat Example.main(Example.java:2)\n")
+	layout := newLogViewLayout(m)
+
+	// Cut off to one line unless wrapped
+	assert.Len(t, logEntryRows(m, layout, 0), 1)
+	m.WrapLines = true
+	rows := logEntryRows(m, layout, 0)
+	assert.Len(t, rows, 4)
+	for _, row := range rows {
+		assert.LessOrEqual(t, len(row.text), testLogWidth)
+		assert.False(t, row.subtle)
+	}
+
+	// A collapsed stack trace is counted on the entry's line, an expanded one is shown below it
+	rows = logEntryRows(m, layout, 1)
+	require.Len(t, rows, 1)
+	assert.True(t, strings.HasSuffix(rows[0].text, "[+2]"))
+	m.ExpandedEntries = map[int]bool{1: true}
+	rows = logEntryRows(m, layout, 1)
+	require.Len(t, rows, 3)
+	assert.Equal(t, logRow{text: "        at Example.run(Example.java:1)", subtle: true}, rows[1])
+
+	// No entry is taller than the view
+	layout.lines = 2
+	assert.Len(t, logEntryRows(m, layout, 0), 2)
+
+	// Entries outside the loaded window are placeholders
+	assert.Equal(t, []logRow{{text: "...", subtle: true}}, logEntryRows(m, layout, 5))
+}
+
+func TestLogViewport(t *testing.T) {
+	m := testLogModel(t, numberedLog(100))
+	layout := newLogViewLayout(m)
+
+	start, end := logViewport(m, layout)
+	assert.Equal(t, []int{0, testLogLines}, []int{start, end})
+
+	// Scrolled as little as possible to bring the cursor into view
+	m.LogCursor = 15
+	start, end = logViewport(m, layout)
+	assert.Equal(t, []int{6, 16}, []int{start, end})
+	m.LogOffset = start
+	m.LogCursor = 3
+	start, end = logViewport(m, layout)
+	assert.Equal(t, []int{3, 13}, []int{start, end})
+
+	// No empty lines are left at the bottom
+	m.LogOffset = 95
+	m.LogCursor = 99
+	start, end = logViewport(m, layout)
+	assert.Equal(t, []int{90, 100}, []int{start, end})
+
+	// Taller entries leave room for fewer
+	m = testLogModel(t, "[12:00:00] [Server thread/INFO]: "+strings.Repeat("word ", 40)+"\n"+numberedLog(20))
+	m.WrapLines = true
+	start, end = logViewport(m, newLogViewLayout(m))
+	assert.Equal(t, []int{0, testLogLines - 3}, []int{start, end})
+}
+
+func TestHandleViewportInput_Paging(t *testing.T) {
+	m := testLogModel(t, numberedLog(100))
+	press := func(key string) {
+		t.Helper()
+		handled, updated := handleViewportInput(keyMsg(key), m)
+		assert.True(t, handled, key)
+		m = updated
+	}
+
+	press("pgdown")
+	assert.Equal(t, testLogLines, m.LogCursor)
+	assert.Equal(t, testLogLines, m.LogOffset)
+	press("pgdown")
+	assert.Equal(t, 2*testLogLines, m.LogCursor)
+	press("pgup")
+	assert.Equal(t, testLogLines, m.LogCursor)
+	assert.Equal(t, testLogLines, m.LogOffset)
+
+	press("G")
+	assert.Equal(t, 99, m.LogCursor)
+	assert.Equal(t, 90, m.LogOffset)
+	press("pgdown")
+	assert.Equal(t, 99, m.LogCursor, "stays on the last entry")
+
+	press("g")
+	assert.Equal(t, 0, m.LogCursor)
+	assert.Equal(t, 0, m.LogOffset)
+	press("pgup")
+	assert.Equal(t, 0, m.LogCursor, "stays on the first entry")
+
+	handled, _ := handleViewportInput(keyMsg("x"), m)
+	assert.False(t, handled)
+}
+
+func TestHandleViewportInput_HorizontalScroll(t *testing.T) {
+	m := testLogModel(t, "[12:00:00] [Server thread/INFO]: "+strings.Repeat("x", 100)+"\n"+numberedLog(3))
+	layout := newLogViewLayout(m)
+	longest := len("[12:00:00] [Server thread/INFO]: ") + 100
+	assert.Equal(t, longest-testLogWidth, maxLogColumn(m, layout, 0, 4))
+
+	_, m = handleViewportInput(keyMsg("right"), m)
+	assert.Equal(t, testLogWidth/2, m.LogColumn)
+	_, m = handleViewportInput(keyMsg("l"), m)
+	assert.Equal(t, longest-testLogWidth, m.LogColumn, "stops once the end of the longest entry is in view")
+	_, m = handleViewportInput(keyMsg("right"), m)
+	assert.Equal(t, longest-testLogWidth, m.LogColumn)
+
+	_, m = handleViewportInput(keyMsg("h"), m)
+	assert.Equal(t, longest-testLogWidth-testLogWidth/2, m.LogColumn)
+	_, m = handleViewportInput(keyMsg("left"), m)
+	assert.Equal(t, 0, m.LogCursor)
+	assert.Equal(t, 0, m.LogColumn)
+
+	// Wrapped lines don't scroll, and wrapping scrolls back to the start
+	_, m = handleViewportInput(keyMsg("right"), m)
+	_, m = handleViewportInput(keyMsg("w"), m)
+	assert.True(t, m.WrapLines)
+	assert.Equal(t, 0, m.LogColumn)
+	_, m = handleViewportInput(keyMsg("right"), m)
+	assert.Equal(t, 0, m.LogColumn)
+}
+
+func TestHandleJumpInput(t *testing.T) {
+	m := testLogModel(t, numberedLog(100))
+	jump := func(keys ...string) {
+		t.Helper()
+		_, m = handleViewportInput(keyMsg(":"), m)
+		assert.True(t, m.JumpActive)
+		for _, key := range keys {
+			m, _ = handleJumpInput(keyMsg(key), m)
+		}
+		assert.False(t, m.JumpActive)
+	}
+
+	// The entry jumped to is centred
+	jump("5", "x", "0", "enter")
+	assert.Equal(t, 49, m.LogCursor)
+	assert.Equal(t, 45, m.LogOffset)
+
+	jump("1", "2", "3", "backspace", "enter")
+	assert.Equal(t, 11, m.LogCursor)
+
+	jump("1", "0", "0", "0", "enter")
+	assert.Equal(t, 99, m.LogCursor, "clamped to the last entry")
+	jump("0", "enter")
+	assert.Equal(t, 0, m.LogCursor, "clamped to the first entry")
+
+	jump("5", "esc")
+	assert.Equal(t, 0, m.LogCursor, "cancelled")
+	jump("enter")
+	assert.Equal(t, 0, m.LogCursor, "nothing typed")
+}
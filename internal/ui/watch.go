@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchEventMsg is sent when the watcher reports changes to the log files
+type watchEventMsg struct {
+	event fileops.WatchEvent
+}
+
+// waitForChangesCmd waits for the next batch of changes from the watcher.
+// Only one is ever waiting, as each batch's handler issues the next.
+func waitForChangesCmd(watcher *fileops.Watcher) tea.Cmd {
+	if watcher == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		select {
+		case event := <-watcher.Events():
+			return watchEventMsg{event: event}
+		case <-watcher.Done():
+			return nil
+		}
+	}
+}
+
+// handleWatchEvent rescans the menu when log files are added or removed, and
+// picks up entries appended to the log being followed
+func handleWatchEvent(msg watchEventMsg, m models.Model) (models.Model, tea.Cmd) {
+	cmds := []tea.Cmd{waitForChangesCmd(m.Watcher)}
+	if msg.event.Err != nil {
+		m.Err = msg.event.Err
+	}
+	if msg.event.Structural() {
+		cmds = append(cmds, scanLogsDirCmd(m.LogSources))
+	}
+
+	if index, ok := m.LogIndex.(*fileops.LogIndex); ok && m.Following {
+		for _, change := range msg.event.Changes {
+			if change.Path == index.Path && change.Op != fileops.FileRemoved {
+				var cmd tea.Cmd
+				m, cmd = scheduleFollow(m)
+				cmds = append(cmds, cmd)
+				break
+			}
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
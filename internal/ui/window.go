@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"time"
+
+	"goparselogs/internal/fileops"
+	"goparselogs/internal/models"
+	"goparselogs/pkg/logparser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	logWindowSize   = 1000 // Number of standard log entries held in memory around the cursor
+	logWindowMargin = 200  // Reload the window when the cursor gets this close to its edge
+
+	indexPollInterval = 150 * time.Millisecond
+)
+
+// indexProgressMsg is sent periodically while a log index is being built
+type indexProgressMsg struct {
+	index fileops.EntryIndex
+}
+
+// logWindowMsg carries the entries read from the index for the window starting at start
+type logWindowMsg struct {
+	index   fileops.EntryIndex
+	start   int
+	entries []logparser.LogEntry
+	err     error
+}
+
+// pollIndexCmd waits briefly and then reports on the progress of the index
+func pollIndexCmd(index fileops.EntryIndex) tea.Cmd {
+	return tea.Tick(indexPollInterval, func(time.Time) tea.Msg {
+		return indexProgressMsg{index: index}
+	})
+}
+
+// loadWindowCmd reads the entries in [start, end) from the index
+func loadWindowCmd(index fileops.EntryIndex, start, end int) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := index.ReadEntries(start, end)
+		return logWindowMsg{index: index, start: start, entries: entries, err: err}
+	}
+}
+
+// startLogIndex replaces the current log index with a new one for filePath and starts building it
+func startLogIndex(m models.Model, filePath string) (models.Model, tea.Cmd) {
+	if m.LogIndex != nil {
+		m.LogIndex.Close()
+	}
+	index := fileops.NewLogIndex(filePath, queryFilter(m.Filters), m.Location)
+	index.SetCache(m.IndexCache, queryTerms(m.Filters))
+	index.Start()
+	return useLogIndex(m, index)
+}
+
+// startTimeline replaces the current log index with a timeline merging the
+// logs at paths and starts building it
+func startTimeline(m models.Model, paths []string) (models.Model, tea.Cmd) {
+	if m.LogIndex != nil {
+		m.LogIndex.Close()
+	}
+	timeline := fileops.NewTimeline(paths, queryFilter(m.Filters), m.Location)
+	timeline.SetCache(m.IndexCache, queryTerms(m.Filters))
+	timeline.Start()
+	return useLogIndex(m, timeline)
+}
+
+// useLogIndex resets the log view to show the entries of a new index
+func useLogIndex(m models.Model, index fileops.EntryIndex) (models.Model, tea.Cmd) {
+	m.LogIndex = index
+	m.LogEntries = []logparser.LogEntry{}
+	m.LogWindowStart = 0
+	m.LogWindowLoading = false
+	m.LogCursor = 0
+	m.LogOffset = 0
+	m.LogColumn = 0
+	m.ExpandedEntries = map[int]bool{}
+	m.Events = nil
+	m.EventCursor = 0
+	m = stopFindScan(m)
+	return m, pollIndexCmd(index)
+}
+
+// logEntryCount returns the number of entries in the current log, whether loaded or not
+func logEntryCount(m models.Model) int {
+	if m.CoreProtectMode {
+		return len(coreProtectEntries(m))
+	}
+	if m.LogIndex == nil {
+		return 0
+	}
+	return m.LogIndex.Len()
+}
+
+// logIndexDone reports whether the current log has been fully indexed
+func logIndexDone(m models.Model) bool {
+	if m.LogIndex == nil {
+		return true
+	}
+	done, _ := m.LogIndex.Done()
+	return done
+}
+
+// logEntryAt returns the standard log entry at index i if it is in the loaded window
+func logEntryAt(m models.Model, i int) (logparser.LogEntry, bool) {
+	i -= m.LogWindowStart
+	if i < 0 || i >= len(m.LogEntries) {
+		return logparser.LogEntry{}, false
+	}
+	return m.LogEntries[i], true
+}
+
+// ensureLogWindow starts loading a new window of entries if the cursor is
+// getting close to the edge of the loaded one
+func ensureLogWindow(m models.Model) (models.Model, tea.Cmd) {
+	if m.CoreProtectMode || m.LogIndex == nil || m.LogWindowLoading {
+		return m, nil
+	}
+
+	total := m.LogIndex.Len()
+	if total == 0 {
+		return m, nil
+	}
+	wanted := Max(m.LogCursor-logWindowMargin, 0)
+	wantedEnd := Min(m.LogCursor+logWindowMargin, total)
+	loadedEnd := m.LogWindowStart + len(m.LogEntries)
+	if wanted >= m.LogWindowStart && wantedEnd <= loadedEnd {
+		return m, nil
+	}
+
+	start := Max(Min(m.LogCursor-logWindowSize/2, total-logWindowSize), 0)
+	end := Min(start+logWindowSize, total)
+	m.LogWindowLoading = true
+	return m, loadWindowCmd(m.LogIndex, start, end)
+}
@@ -0,0 +1,323 @@
+package coreprotectparser
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// DatabasePath is where CoreProtect keeps its SQLite database, relative to the server directory.
+const DatabasePath = "plugins/CoreProtect/database.db"
+
+// DefaultDatabaseLimit is the number of the newest actions read from a
+// database unless another limit is asked for, since a busy server's database
+// holds far more than can be shown.
+const DefaultDatabaseLimit = 10000
+
+// DatabaseQuery selects the rows read from a CoreProtect database. Zero
+// values select everything.
+type DatabaseQuery struct {
+	Users        []string  // Only actions by these users, case-insensitively
+	UserContains string    // Only actions by users whose names contain this, case-insensitively
+	Actions      []Action  // Only these actions
+	Since        time.Time // Only actions at or after this time
+	Until        time.Time // Only actions before this time
+	Center       *Location // With Radius, only actions within Radius blocks of Center in its world
+	Radius       int
+	Limit        int       // Keep only the newest Limit actions
+	Now          time.Time // Time the times ago are worked out from, defaults to the current time
+}
+
+// Database is a CoreProtect SQLite database opened read-only.
+type Database struct {
+	db *sql.DB
+}
+
+// databaseTable describes how the rows of one CoreProtect table become entries
+type databaseTable struct {
+	name    string
+	columns string           // Action code, target, amount and message, in that order
+	joins   string           // Joins needed by columns
+	actions map[int64]Action // Actions by the table's action column, nil if the table has none
+	action  Action           // The action of every row if the table has no action column
+}
+
+var databaseTables = []databaseTable{
+	{
+		name:    "co_block",
+		columns: "t.action, CASE WHEN t.action = 3 THEN e.entity ELSE m.material END, 0, ''",
+		joins: "LEFT JOIN co_material_map m ON m.id = t.type " +
+			"LEFT JOIN co_entity_map e ON e.id = t.type",
+		actions: map[int64]Action{0: ActionBreak, 1: ActionPlace, 2: ActionClick, 3: ActionKill},
+	},
+	{
+		name:    "co_container",
+		columns: "t.action, m.material, t.amount, ''",
+		joins:   "LEFT JOIN co_material_map m ON m.id = t.type",
+		actions: map[int64]Action{0: ActionRemove, 1: ActionAdd},
+	},
+	{
+		name:    "co_session",
+		columns: "t.action, '', 0, ''",
+		actions: map[int64]Action{0: ActionLogout, 1: ActionLogin},
+	},
+	{name: "co_chat", columns: "0, '', 0, t.message", action: ActionChat},
+	{name: "co_command", columns: "0, '', 0, t.message", action: ActionCommand},
+}
+
+// OpenDatabase opens the CoreProtect SQLite database at path without write access.
+func OpenDatabase(path string) (*Database, error) {
+	dsn, err := databaseURI(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CoreProtect database: %w", err)
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CoreProtect database: %w", err)
+	}
+	// A server writing to the database keeps it busy, so fail early if it is not CoreProtect's
+	if _, err := db.Exec("SELECT 1 FROM co_user LIMIT 1"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s is not a CoreProtect database: %w", path, err)
+	}
+	return &Database{db: db}, nil
+}
+
+// databaseURI returns the URI SQLite opens the database at path with, read
+// only. The path is escaped so that characters such as ?, # and % in it
+// aren't taken for the start of the options or an escape.
+func databaseURI(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	abs = filepath.ToSlash(abs)
+	if !strings.HasPrefix(abs, "/") {
+		// Windows paths start with a drive letter rather than a slash
+		abs = "/" + abs
+	}
+	uri := url.URL{Scheme: "file", Path: abs, RawQuery: "mode=ro&_pragma=busy_timeout(5000)"}
+	return uri.String(), nil
+}
+
+// Close closes the database.
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// Lookup reads the actions selected by q, keeping those accepted by filter if
+// it is not nil. Entries are sorted like ParseReader's, oldest first, and
+// have exact event times. The limit counts only entries the filter accepts.
+func (d *Database) Lookup(q DatabaseQuery, filter Filter) (*ParsedLog, error) {
+	now := q.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var entries []CoreProtectLogEntry
+	for _, table := range databaseTables {
+		tableEntries, err := d.lookupTable(table, q, filter, now)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, tableEntries...)
+	}
+
+	// Newest first so the limit keeps the most recent actions, as CoreProtect's own lookups do
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].EventTime.After(entries[j].EventTime)
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+
+	parsedLog := &ParsedLog{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		parsedLog.Entries = append(parsedLog.Entries, entries[i])
+	}
+	// Match the order of lookups read from logs, where newer results come first
+	for i := range parsedLog.Entries {
+		parsedLog.Entries[i].OriginalAcceptedIndex = len(parsedLog.Entries) - 1 - i
+	}
+	return parsedLog, nil
+}
+
+// lookupTable reads the rows of one table selected by q and accepted by filter, newest first
+func (d *Database) lookupTable(table databaseTable, q DatabaseQuery, filter Filter, now time.Time) ([]CoreProtectLogEntry, error) {
+	var where []string
+	var args []any
+
+	if len(q.Actions) > 0 {
+		wanted := map[Action]bool{}
+		for _, action := range q.Actions {
+			wanted[action] = true
+		}
+		if table.actions == nil {
+			if !wanted[table.action] {
+				return nil, nil
+			}
+		} else {
+			var codes []string
+			for code, action := range table.actions {
+				if wanted[action] {
+					codes = append(codes, fmt.Sprint(code))
+				}
+			}
+			if len(codes) == 0 {
+				return nil, nil
+			}
+			sort.Strings(codes)
+			where = append(where, "t.action IN ("+strings.Join(codes, ", ")+")")
+		}
+	}
+	if len(q.Users) > 0 {
+		placeholders := make([]string, len(q.Users))
+		for i, user := range q.Users {
+			placeholders[i] = "?"
+			args = append(args, strings.ToLower(user))
+		}
+		where = append(where, "LOWER(u.user) IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if q.UserContains != "" {
+		where = append(where, "INSTR(LOWER(u.user), ?) > 0")
+		args = append(args, strings.ToLower(q.UserContains))
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "t.time >= ?")
+		args = append(args, q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "t.time < ?")
+		args = append(args, q.Until.Unix())
+	}
+	if q.Center != nil && q.Radius > 0 {
+		// CoreProtect's radius is a square around the centre, across all heights
+		where = append(where, "w.world = ?", "t.x BETWEEN ? AND ?", "t.z BETWEEN ? AND ?")
+		args = append(args, q.Center.World,
+			q.Center.X-q.Radius, q.Center.X+q.Radius,
+			q.Center.Z-q.Radius, q.Center.Z+q.Radius)
+	}
+
+	statement := fmt.Sprintf("SELECT t.time, u.user, COALESCE(w.world, ''), t.x, t.y, t.z, %s "+
+		"FROM %s t JOIN co_user u ON u.id = t.user LEFT JOIN co_world w ON w.id = t.wid %s",
+		table.columns, table.name, table.joins)
+	if len(where) > 0 {
+		statement += " WHERE " + strings.Join(where, " AND ")
+	}
+	statement += " ORDER BY t.time DESC, t.rowid DESC"
+	if q.Limit > 0 && filter == nil {
+		statement += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := d.db.Query(statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	defer rows.Close()
+
+	var entries []CoreProtectLogEntry
+	for rows.Next() {
+		var (
+			unix            int64
+			location        Location
+			code            int64
+			target, message sql.NullString
+			amount          int
+			entry           CoreProtectLogEntry
+		)
+		if err := rows.Scan(&unix, &entry.Username, &location.World, &location.X, &location.Y, &location.Z,
+			&code, &target, &amount, &message); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+
+		entry.Action = table.action
+		if table.actions != nil {
+			action, ok := table.actions[code]
+			if !ok {
+				continue
+			}
+			entry.Action = action
+		}
+		entry.Target = normalizeTarget(target.String)
+		entry.Amount = amount
+		entry.Location = &location
+		entry.setEventTime(time.Unix(unix, 0), now)
+		entry.setDatabaseMessage(message.String)
+		if filter != nil && !filter(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if q.Limit > 0 && len(entries) >= q.Limit {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+	}
+	return entries, nil
+}
+
+// setEventTime sets the exact time of an entry read from the database and
+// the time ago CoreProtect would show for it at now
+func (e *CoreProtectLogEntry) setEventTime(eventTime, now time.Time) {
+	e.EventTime = eventTime
+	e.Time = eventTime
+	ago := now.Sub(eventTime)
+	e.HoursAgo = ago.Hours()
+
+	switch {
+	case ago < time.Minute:
+		e.AgoUnit = "s"
+	case ago < time.Hour:
+		e.AgoUnit = "m"
+	case ago < 24*time.Hour:
+		e.AgoUnit = "h"
+	default:
+		e.AgoUnit = "d"
+	}
+	e.Ago = math.Round(float64(ago)/float64(agoUnits[e.AgoUnit])*100) / 100
+	if e.AgoUnit == "d" {
+		e.IsInDays = true
+		e.DaysAgo = e.Ago
+	}
+}
+
+// setDatabaseMessage fills in the message and raw line of an entry read from
+// the database the way they read in a lookup result
+func (e *CoreProtectLogEntry) setDatabaseMessage(text string) {
+	var result string
+	switch e.Action {
+	case ActionChat, ActionCommand:
+		e.Message = text
+		result = fmt.Sprintf("%s: %s", e.Username, text)
+	case ActionLogin:
+		e.Message = "logged in"
+	case ActionLogout:
+		e.Message = "logged out"
+	default:
+		for verb, action := range actionVerbs {
+			if action != e.Action {
+				continue
+			}
+			if e.Action == ActionAdd || e.Action == ActionRemove {
+				e.Message = fmt.Sprintf("%s x%d %s", verb, e.Amount, e.Target)
+			} else {
+				e.Message = verb + " " + e.Target
+			}
+		}
+	}
+	if result == "" {
+		result = fmt.Sprintf("%s %s.", e.Username, e.Message)
+	}
+	e.RawLine = fmt.Sprintf("%s ago - %s", e.AgoString(), result)
+	if e.Location != nil {
+		e.LocationLine = fmt.Sprintf("^ (%s)", e.Location)
+	}
+}
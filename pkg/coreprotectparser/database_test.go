@@ -0,0 +1,260 @@
+package coreprotectparser
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/query"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The parts of CoreProtect's schema read by Database
+const testDatabaseSchema = `
+CREATE TABLE co_user (id INTEGER PRIMARY KEY, time INTEGER, user TEXT, uuid TEXT);
+CREATE TABLE co_world (id INTEGER PRIMARY KEY, world TEXT);
+CREATE TABLE co_material_map (id INTEGER PRIMARY KEY, material TEXT);
+CREATE TABLE co_entity_map (id INTEGER PRIMARY KEY, entity TEXT);
+CREATE TABLE co_block (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, type INTEGER, data INTEGER, meta BLOB, blockdata BLOB, action INTEGER, rolled_back INTEGER);
+CREATE TABLE co_container (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, type INTEGER, data INTEGER, amount INTEGER, metadata BLOB, action INTEGER, rolled_back INTEGER);
+CREATE TABLE co_chat (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, message TEXT);
+CREATE TABLE co_command (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, message TEXT);
+CREATE TABLE co_session (time INTEGER, user INTEGER, wid INTEGER, x INTEGER, y INTEGER, z INTEGER, action INTEGER);
+
+INSERT INTO co_user VALUES (1, 0, 'Th4tGuy1', ''), (2, 0, 'queercookie', ''), (3, 0, '#creeper', '');
+INSERT INTO co_world VALUES (1, 'world'), (2, 'world_nether');
+INSERT INTO co_material_map VALUES (1, 'minecraft:stone'), (2, 'minecraft:diamond');
+INSERT INTO co_entity_map VALUES (1, 'minecraft:zombie');
+
+INSERT INTO co_session VALUES (1714590000, 2, 1, 0, 64, 0, 1);
+INSERT INTO co_chat VALUES (1714590060, 2, 1, 0, 64, 0, 'can I see?');
+INSERT INTO co_block VALUES (1714590120, 1, 1, 10, 64, -5, 1, 0, NULL, NULL, 0, 0);
+INSERT INTO co_block VALUES (1714590180, 1, 2, 500, 30, 500, 1, 0, NULL, NULL, 1, 0);
+INSERT INTO co_block VALUES (1714590240, 1, 1, 12, 64, -4, 1, 0, NULL, NULL, 3, 0);
+INSERT INTO co_container VALUES (1714590300, 2, 1, 11, 63, -5, 2, 0, 64, NULL, 0, 0);
+INSERT INTO co_command VALUES (1714590360, 2, 1, 0, 64, 0, '/home');
+INSERT INTO co_session VALUES (1714590420, 2, 1, 0, 64, 0, 0);
+`
+
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	return openTestDatabaseAt(t, filepath.Join(t.TempDir(), "database.db"))
+}
+
+// openTestDatabaseAt writes the test database to path and opens it. It is
+// created elsewhere so that path needn't be one SQLite can be handed as is.
+func openTestDatabaseAt(t *testing.T, path string) *Database {
+	t.Helper()
+	created := filepath.Join(t.TempDir(), "database.db")
+	db, err := sql.Open("sqlite", created)
+	require.NoError(t, err)
+	_, err = db.Exec(testDatabaseSchema)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.Rename(created, path))
+
+	database, err := OpenDatabase(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestDatabaseLookup(t *testing.T) {
+	database := openTestDatabase(t)
+	now := time.Unix(1714590420, 0).Add(2 * time.Hour)
+
+	parsedLog, err := database.Lookup(DatabaseQuery{Now: now}, nil)
+	require.NoError(t, err)
+	require.Len(t, parsedLog.Entries, 8)
+
+	var actions []Action
+	for _, entry := range parsedLog.Entries {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []Action{ActionLogin, ActionChat, ActionBreak, ActionPlace, ActionKill, ActionRemove, ActionCommand, ActionLogout}, actions)
+
+	login := parsedLog.Entries[0]
+	assert.Equal(t, "queercookie", login.Username)
+	assert.Equal(t, "logged in", login.Message)
+	assert.Equal(t, time.Unix(1714590000, 0), login.EventTime)
+	assert.Equal(t, time.Duration(0), login.EventTimeError)
+	assert.Equal(t, "h", login.AgoUnit)
+	assert.InDelta(t, 2.12, login.Ago, 0.001)
+	assert.Equal(t, "2.12/h ago - queercookie logged in.", login.RawLine)
+
+	assert.Equal(t, "can I see?", parsedLog.Entries[1].Message)
+
+	broke := parsedLog.Entries[2]
+	assert.Equal(t, "stone", broke.Target)
+	assert.Equal(t, "broke stone", broke.Message)
+	assert.Equal(t, &Location{X: 10, Y: 64, Z: -5, World: "world"}, broke.Location)
+
+	assert.Equal(t, "killed zombie", parsedLog.Entries[4].Message)
+
+	removed := parsedLog.Entries[5]
+	assert.Equal(t, 64, removed.Amount)
+	assert.Equal(t, "removed x64 diamond", removed.Message)
+
+	// Newer entries were accepted first, as in a lookup read from a log
+	assert.Greater(t, parsedLog.Entries[0].OriginalAcceptedIndex, parsedLog.Entries[7].OriginalAcceptedIndex)
+}
+
+func TestDatabaseLookup_Query(t *testing.T) {
+	database := openTestDatabase(t)
+	now := time.Unix(1714590420, 0)
+
+	tests := []struct {
+		name     string
+		query    DatabaseQuery
+		expected []string
+	}{
+		{"user", DatabaseQuery{Users: []string{"TH4TGUY1"}}, []string{"broke stone", "placed stone", "killed zombie"}},
+		{"user contains", DatabaseQuery{UserContains: "COOKIE"}, []string{"logged in", "can I see?", "removed x64 diamond", "/home", "logged out"}},
+		{"action", DatabaseQuery{Actions: []Action{ActionLogin, ActionCommand}}, []string{"logged in", "/home"}},
+		{"since and until", DatabaseQuery{Since: time.Unix(1714590120, 0), Until: time.Unix(1714590240, 0)}, []string{"broke stone", "placed stone"}},
+		{"radius", DatabaseQuery{Center: &Location{X: 10, Z: -5, World: "world"}, Radius: 2}, []string{"broke stone", "killed zombie", "removed x64 diamond"}},
+		{"limit keeps the newest", DatabaseQuery{Limit: 2}, []string{"/home", "logged out"}},
+		{"unsupported action", DatabaseQuery{Actions: []Action{ActionSign}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Now = now
+			parsedLog, err := database.Lookup(tt.query, nil)
+			require.NoError(t, err)
+
+			var messages []string
+			for _, entry := range parsedLog.Entries {
+				messages = append(messages, entry.Message)
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+func TestDatabaseLookup_Filter(t *testing.T) {
+	database := openTestDatabase(t)
+
+	parsedLog, err := database.Lookup(DatabaseQuery{}, func(entry CoreProtectLogEntry) bool {
+		return entry.Username == "queercookie"
+	})
+	require.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 5)
+
+	// The limit keeps the newest entries the filter accepts
+	parsedLog, err = database.Lookup(DatabaseQuery{Limit: 2}, func(entry CoreProtectLogEntry) bool {
+		return entry.Username == "Th4tGuy1"
+	})
+	require.NoError(t, err)
+	if assert.Len(t, parsedLog.Entries, 2) {
+		assert.Equal(t, "placed stone", parsedLog.Entries[0].Message)
+		assert.Equal(t, "killed zombie", parsedLog.Entries[1].Message)
+	}
+}
+
+func TestDatabaseLookup_TargetsMatchLog(t *testing.T) {
+	database := openTestDatabase(t)
+	fromDatabase, err := database.Lookup(DatabaseQuery{Users: []string{"Th4tGuy1"}, Actions: []Action{ActionBreak}}, nil)
+	require.NoError(t, err)
+	require.Len(t, fromDatabase.Entries, 1)
+
+	fromLog, err := ParseLogContent(`[14:37:37] [Render thread/INFO]: [System] [CHAT] 9.00/h ago §f- §3Th4tGuy1 §fbroke §3minecraft:stone§f.`)
+	require.NoError(t, err)
+	require.Len(t, fromLog.Entries, 1)
+
+	// Both name the block the same way, so the same target: filter finds them
+	assert.Equal(t, "stone", fromLog.Entries[0].Target)
+	assert.Equal(t, fromDatabase.Entries[0].Target, fromLog.Entries[0].Target)
+	assert.Equal(t, fromDatabase.Entries[0].Message, fromLog.Entries[0].Message)
+	q := query.MustParse("target:=stone")
+	assert.True(t, q.Match(fromDatabase.Entries[0]))
+	assert.True(t, q.Match(fromLog.Entries[0]))
+}
+
+func TestNewDatabaseQuery(t *testing.T) {
+	now := time.Unix(1714590420, 0)
+	parse := func(sources ...string) []*query.Query {
+		var queries []*query.Query
+		for _, source := range sources {
+			queries = append(queries, query.MustParse(source))
+		}
+		return queries
+	}
+
+	q := NewDatabaseQuery(parse("user:=Th4tGuy1 action:log ago:<2h"), now)
+	assert.Equal(t, []string{"Th4tGuy1"}, q.Users)
+	assert.Equal(t, []Action{ActionLogin, ActionLogout}, q.Actions)
+	assert.Equal(t, now.Add(-2*time.Hour-time.Second), q.Since)
+	assert.True(t, q.Until.IsZero())
+	assert.Equal(t, now, q.Now)
+
+	q = NewDatabaseQuery(parse("user:cookie ago:>1h action:=break"), now)
+	assert.Equal(t, "cookie", q.UserContains)
+	assert.Equal(t, []Action{ActionBreak}, q.Actions)
+	assert.Equal(t, now.Add(-time.Hour+time.Second), q.Until)
+
+	// Terms that needn't all match narrow nothing down
+	for _, queries := range [][]*query.Query{
+		parse("user:Th4tGuy1 OR action:break"),
+		parse("NOT user:Th4tGuy1"),
+		parse("user:Th4tGuy1", "action:break"),
+		parse("action:nothing"),
+	} {
+		assert.Equal(t, DatabaseQuery{Now: now}, NewDatabaseQuery(queries, now))
+	}
+}
+
+func TestDatabaseLookup_NarrowedByFilters(t *testing.T) {
+	database := openTestDatabase(t)
+	now := time.Unix(1714590420, 0)
+
+	// Narrowing the lookup down finds the same actions as filtering them all
+	for _, source := range []string{
+		"user:th4t",
+		"user:=queercookie action:log",
+		"action:place OR action:chat",
+		"ago:<=3m",
+		"ago:>5m ago:<6m",
+		"action:nothing",
+	} {
+		t.Run(source, func(t *testing.T) {
+			queries := []*query.Query{query.MustParse(source)}
+			filter := func(entry CoreProtectLogEntry) bool { return query.MatchAny(queries, entry) }
+
+			all, err := database.Lookup(DatabaseQuery{Now: now}, filter)
+			require.NoError(t, err)
+			narrowed, err := database.Lookup(NewDatabaseQuery(queries, now), filter)
+			require.NoError(t, err)
+			assert.Equal(t, all.Entries, narrowed.Entries)
+		})
+	}
+}
+
+func TestOpenDatabase_NotCoreProtect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE other (id INTEGER)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = OpenDatabase(path)
+	assert.Error(t, err)
+}
+
+func TestOpenDatabase_PathNeedingEscapes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "50% off #1?", "database?mode=rw.db")
+	database := openTestDatabaseAt(t, path)
+	parsedLog, err := database.Lookup(DatabaseQuery{}, nil)
+	require.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 8)
+
+	// Still read only
+	_, err = database.db.Exec("DELETE FROM co_chat")
+	assert.Error(t, err)
+}
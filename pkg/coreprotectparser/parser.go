@@ -1,126 +1,483 @@
 package coreprotectparser
 
 import (
+	"fmt"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"goparselogs/pkg/logparser"
+)
+
+// Action is the kind of event a CoreProtect lookup result describes.
+type Action int
+
+const (
+	ActionChat    Action = iota // user: message
+	ActionCommand               // user: /command
+	ActionSign                  // user: Line 1: sign text, labelled line by line
+	ActionPlace                 // user placed block
+	ActionBreak                 // user broke block
+	ActionClick                 // user clicked block
+	ActionKill                  // user killed entity
+	ActionAdd                   // user added xN item (to a container)
+	ActionRemove                // user removed xN item (from a container)
+	ActionDrop                  // user dropped xN item
+	ActionPickup                // user picked up xN item
+	ActionLogin                 // user logged in
+	ActionLogout                // user logged out
 )
 
+var actionNames = map[Action]string{
+	ActionChat:    "chat",
+	ActionCommand: "command",
+	ActionSign:    "sign",
+	ActionPlace:   "place",
+	ActionBreak:   "break",
+	ActionClick:   "click",
+	ActionKill:    "kill",
+	ActionAdd:     "add",
+	ActionRemove:  "remove",
+	ActionDrop:    "drop",
+	ActionPickup:  "pickup",
+	ActionLogin:   "login",
+	ActionLogout:  "logout",
+}
+
+// String returns the action's short name, as used by action: filters.
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// actionVerbs maps the verbs used in lookup results to their actions
+var actionVerbs = map[string]Action{
+	"placed":    ActionPlace,
+	"broke":     ActionBreak,
+	"clicked":   ActionClick,
+	"killed":    ActionKill,
+	"added":     ActionAdd,
+	"removed":   ActionRemove,
+	"dropped":   ActionDrop,
+	"picked up": ActionPickup,
+}
+
+// normalizeTarget returns the name of a block, entity or item without the
+// minecraft: namespace, which lookups only sometimes show and the database
+// always stores, so that a target reads the same from either.
+func normalizeTarget(name string) string {
+	return strings.TrimPrefix(name, "minecraft:")
+}
+
+// Location is the block position shown beneath a lookup result.
+type Location struct {
+	X, Y, Z int
+	World   string
+}
+
+// String formats the location the way CoreProtect displays it.
+func (l Location) String() string {
+	return fmt.Sprintf("x%d/y%d/z%d/%s", l.X, l.Y, l.Z, l.World)
+}
+
 // CoreProtectLogEntry represents a parsed CoreProtect log entry.
 type CoreProtectLogEntry struct {
 	HoursAgo              float64 // Total time ago in hours (converted from days if needed)
 	DaysAgo               float64 // Original days value if present, 0 otherwise
 	IsInDays              bool    // Whether the original time was in days
+	Ago                   float64 // Time ago as displayed by CoreProtect, in AgoUnit
+	AgoUnit               string  // Unit of Ago: "s", "m", "h" or "d"
 	Username              string
-	Message               string
+	Action                Action
+	Message               string    // Chat, command or sign text, or the action as written (e.g. "removed x64 diamond")
+	Target                string    // Block, entity or item acted on, empty for chat, commands, signs and sessions
+	Amount                int       // Number of items added, removed, dropped or picked up, 0 otherwise
+	Location              *Location // Nil if the result had no location line
 	RawLine               string
-	Time                  time.Time // Parsed from the initial log timestamp
-	OriginalAcceptedIndex int       // The order in which this entry was accepted during parsing
+	LocationLine          string        // Raw log line holding the location, if any
+	Time                  time.Time     // Parsed from the initial log timestamp
+	LoggedAt              time.Time     // Full date and time of the log line, zero if the log's date is unknown
+	EventTime             time.Time     // When the action happened (LoggedAt minus the time ago), zero if unknown
+	EventTimeError        time.Duration // Maximum error of EventTime from CoreProtect rounding the time ago to two decimals
+	OriginalAcceptedIndex int           // The order in which this entry was accepted during parsing
+	Session               int           // Index into ParsedLog.Sessions of the lookup the entry came from
+	Page                  int           // Page of the lookup the entry was on, 0 if unknown
 }
 
 // ParsedLog represents the overall parsed log with CoreProtect entries.
 type ParsedLog struct {
-	Entries []CoreProtectLogEntry
+	Entries  []CoreProtectLogEntry
+	Sessions []Session // Lookups found in the log, in order
 }
 
 var (
-	// Regex to capture the hours ago, username, and message from CoreProtect chat lines
-	// Example: 14.20/h ago §f- queercookie: §fcan I see?
-	coreProtectHoursChatRegex = regexp.MustCompile(`\[(\d{2}:\d{2}:\d{2})\] \[Render thread/INFO\]: \[System\] \[CHAT\] (\d+\.\d+)/h ago §f- ([^:]+): §f(.*)`)
+	// Regex to capture the log timestamp and the chat message holding a CoreProtect lookup result
+	// Example: [14:37:37] [Render thread/INFO]: [System] [CHAT] 14.20/h ago §f- queercookie: §fcan I see?
+	coreProtectLineRegex = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[Render thread/INFO\]: \[System\] \[CHAT\] (.*)$`)
+
+	// Regexes to identify the lines around lookup results, after formatting codes are removed
+	// Examples: ----- CoreProtect | Lookup Results -----, CoreProtect - Lookup searching. Please wait...,
+	// ◀ Page 101/6378 ▶ (1 ... 99 | 100 | 101 | 102 | 103 ... 6378)
+	resultsHeaderRegex = regexp.MustCompile(`^-----\s*CoreProtect\s*\|\s*Lookup Results\s*-----`)
+	searchingRegex     = regexp.MustCompile(`^CoreProtect - Lookup searching`)
+	pageFooterRegex    = regexp.MustCompile(`^◀?\s*Page (\d+)/(\d+)`)
 
-	// Regex to capture the days ago, username, and message from CoreProtect chat lines
-	// Example: 1023.18/d ago §f- xSaVage_: §f0.05
-	coreProtectDaysChatRegex = regexp.MustCompile(`\[(\d{2}:\d{2}:\d{2})\] \[Render thread/INFO\]: \[System\] \[CHAT\] (\d+\.\d+)/d ago §f- ([^:]+): §f(.*)`)
+	// Regex to strip Minecraft formatting codes such as §f
+	formattingCodeRegex = regexp.MustCompile(`§[0-9a-fk-orA-FK-OR]`)
 
-	// Regex to identify lines that are part of CoreProtect lookup but not actual messages
-	coreProtectMetaRegex = regexp.MustCompile(`\[\d{2}:\d{2}:\d{2}\] \[Render thread/INFO\]: \[System\] \[CHAT\] (----- CoreProtect \| Lookup Results -----|CoreProtect - Lookup searching\. Please wait\.\.\.|§f◀ Page §f\d+/\d+ ▶)`)
+	// Regex to capture the time ago and the rest of a lookup result
+	// Example: 14.20/h ago - queercookie: can I see?
+	resultRegex = regexp.MustCompile(`^(\d+\.\d+)/([smhd]) ago - (.+)$`)
+
+	// Regexes for the different shapes of the rest of a lookup result
+	messageResultRegex     = regexp.MustCompile(`^([^\s:]+): (.*)$`)
+	signTextRegex          = regexp.MustCompile(`^Line \d+: `)
+	loginResultRegex       = regexp.MustCompile(`^(\S+) logged (in|out)\.?$`)
+	transactionResultRegex = regexp.MustCompile(`^(\S+) (added|removed|dropped|picked up) x(\d+) (.+?)\.?$`)
+	blockResultRegex       = regexp.MustCompile(`^(\S+) (placed|broke|clicked|killed) (.+?)\.?$`)
+
+	// Regex to capture the location line beneath a lookup result
+	// Example: ^ (x123/y64/z-45/world)
+	locationRegex = regexp.MustCompile(`^\s*\^?\s*\(x:?(-?\d+)/y:?(-?\d+)/z:?(-?\d+)/([^)]+)\)`)
 )
 
-// ParseLogContent parses the raw log content string and extracts CoreProtect entries.
-func ParseLogContent(logContent string) (*ParsedLog, error) {
-	lines := strings.Split(logContent, "\n")
-	parsedLog := &ParsedLog{}
-	acceptedCounter := 0
+// Scanner reads CoreProtect entries one at a time from a stream of log lines,
+// in the order they appear in the log. Entries are grouped into lookup
+// sessions: a new session starts whenever CoreProtect starts searching, or
+// when the results show page 1 again or a different number of pages, as they
+// do when a lookup is run again without its searching line being pasted.
+// Other pages of results read more than once in a session are dropped.
+type Scanner struct {
+	lines           *logparser.EntryScanner
+	page            []CoreProtectLogEntry // Entries of the page being read, kept until its footer
+	ready           []CoreProtectLogEntry // Entries waiting to be returned by Scan
+	sessions        []Session
+	newLookup       bool // CoreProtect started searching since the last page
+	current         CoreProtectLogEntry
+	acceptedCounter int
+}
 
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
+// NewScanner returns a scanner reading CoreProtect entries from r. The parser's
+// date and location settings are used to work out when each action happened
+// (see logparser.Parser.SetStartDate); if parser is nil, entries have no
+// absolute times.
+func NewScanner(r io.Reader, parser *logparser.Parser) *Scanner {
+	if parser == nil {
+		parser, _ = logparser.NewParserWithFormat(logparser.VanillaFormat)
+	}
+	return &Scanner{lines: parser.NewEntryScanner(r)}
+}
+
+// Scan advances to the next CoreProtect entry, which is then available through
+// Entry. It returns false when the input ends or an error occurs.
+func (s *Scanner) Scan() bool {
+	for len(s.ready) == 0 {
+		if !s.lines.Scan() {
+			if s.lines.Err() != nil {
+				return false
+			}
+			// Results after the last page footer, or from a lookup with a single page
+			s.endPage(0, 0)
+			if len(s.ready) == 0 {
+				return false
+			}
+			break
 		}
+		s.readLine(s.lines.Entry())
+	}
+
+	s.current = s.ready[0]
+	s.ready = s.ready[1:]
+	return true
+}
+
+// readLine handles one line of the log
+func (s *Scanner) readLine(logEntry logparser.LogEntry) {
+	line := logEntry.RawLine
+	_, text, ok := chatText(line)
+	if !ok {
+		return
+	}
+
+	switch {
+	case resultsHeaderRegex.MatchString(text):
+		s.endPage(0, 0)
+		return
+	case searchingRegex.MatchString(text):
+		s.endPage(0, 0)
+		s.newLookup = true
+		return
+	}
+	if match := pageFooterRegex.FindStringSubmatch(text); match != nil {
+		page, _ := strconv.Atoi(match[1])
+		total, _ := strconv.Atoi(match[2])
+		s.endPage(page, total)
+		return
+	}
 
-		// Skip lines that are CoreProtect metadata but not chat messages
-		if coreProtectMetaRegex.MatchString(line) {
-			continue
+	if location, ok := parseLocationLine(line); ok {
+		if last := len(s.page) - 1; last >= 0 && s.page[last].Location == nil {
+			s.page[last].Location = &location
+			s.page[last].LocationLine = line
 		}
+		return
+	}
 
-		// Try to match hours format first
-		match := coreProtectHoursChatRegex.FindStringSubmatch(line)
-		if len(match) == 5 {
-			timestampStr := match[1]
-			hoursAgoStr := match[2]
-			username := strings.TrimSpace(match[3])
-			message := strings.TrimSpace(match[4])
-
-			hoursAgo, err := strconv.ParseFloat(hoursAgoStr, 64)
-			if err != nil {
-				// Skip lines where hours ago cannot be parsed
-				continue
-			}
+	if entry, ok := parseLine(line); ok {
+		entry.setLoggedAt(logEntry.Time)
+		s.page = append(s.page, entry)
+	}
+}
 
-			logTime, err := time.Parse("15:04:05", timestampStr)
-			if err != nil {
-				logTime = time.Time{}
-			}
+// endPage assigns the entries read since the last page to a lookup session and
+// queues them to be returned, unless the page was already read in that session.
+// page and total are 0 if the page had no footer.
+func (s *Scanner) endPage(page, total int) {
+	if len(s.page) == 0 && page == 0 {
+		return
+	}
+
+	last := len(s.sessions) - 1
+	if last < 0 || s.newLookup || s.sessions[last].restartedBy(page, total) {
+		s.sessions = append(s.sessions, Session{Index: len(s.sessions)})
+		last++
+	}
+	s.newLookup = false
+	session := &s.sessions[last]
 
-			parsedLog.Entries = append(parsedLog.Entries, CoreProtectLogEntry{
-				HoursAgo:              hoursAgo,
-				DaysAgo:               0,
-				IsInDays:              false,
-				Username:              username,
-				Message:               message,
-				RawLine:               line,
-				Time:                  logTime,
-				OriginalAcceptedIndex: acceptedCounter,
-			})
-			acceptedCounter++
-			continue
+	entries := s.page
+	s.page = nil
+	if page > 0 {
+		if total > 0 {
+			session.TotalPages = total
+		}
+		if !session.addPage(page) {
+			session.DuplicatePages++
+			return
 		}
+	}
 
-		// If not hours, try to match days format
-		match = coreProtectDaysChatRegex.FindStringSubmatch(line)
-		if len(match) == 5 {
-			timestampStr := match[1]
-			daysAgoStr := match[2]
-			username := strings.TrimSpace(match[3])
-			message := strings.TrimSpace(match[4])
-
-			daysAgo, err := strconv.ParseFloat(daysAgoStr, 64)
-			if err != nil {
-				// Skip lines where days ago cannot be parsed
-				continue
-			}
+	for _, entry := range entries {
+		entry.Session = session.Index
+		entry.Page = page
+		entry.OriginalAcceptedIndex = s.acceptedCounter
+		s.acceptedCounter++
+		session.Entries++
+		s.ready = append(s.ready, entry)
+	}
+}
 
-			// Convert days to hours for consistent sorting
-			hoursAgo := daysAgo * 24
+// Entry returns the entry read by the last call to Scan.
+func (s *Scanner) Entry() CoreProtectLogEntry {
+	return s.current
+}
 
-			logTime, err := time.Parse("15:04:05", timestampStr)
-			if err != nil {
-				logTime = time.Time{}
-			}
+// Err returns the first non-EOF error encountered while reading.
+func (s *Scanner) Err() error {
+	return s.lines.Err()
+}
 
-			parsedLog.Entries = append(parsedLog.Entries, CoreProtectLogEntry{
-				HoursAgo:              hoursAgo,
-				DaysAgo:               daysAgo,
-				IsInDays:              true,
-				Username:              username,
-				Message:               message,
-				RawLine:               line,
-				Time:                  logTime,
-				OriginalAcceptedIndex: acceptedCounter,
-			})
-			acceptedCounter++
+// Sessions returns the lookup sessions found so far.
+func (s *Scanner) Sessions() []Session {
+	sessions := make([]Session, len(s.sessions))
+	for i, session := range s.sessions {
+		sessions[i] = session
+		sessions[i].Pages = append([]int(nil), session.Pages...)
+	}
+	return sessions
+}
+
+// RolloverCorrection returns the number of days that must be subtracted from
+// the times of the entries scanned so far, as for logparser.EntryScanner.
+func (s *Scanner) RolloverCorrection() int {
+	return s.lines.RolloverCorrection()
+}
+
+// chatText returns the CoreProtect chat text of a log line with formatting codes removed
+func chatText(line string) (timestamp, text string, ok bool) {
+	match := coreProtectLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], strings.TrimSpace(formattingCodeRegex.ReplaceAllString(match[2], "")), true
+}
+
+// parseLocationLine parses the location line shown beneath a lookup result
+func parseLocationLine(line string) (Location, bool) {
+	_, text, ok := chatText(line)
+	if !ok {
+		return Location{}, false
+	}
+	match := locationRegex.FindStringSubmatch(text)
+	if match == nil {
+		return Location{}, false
+	}
+	x, _ := strconv.Atoi(match[1])
+	y, _ := strconv.Atoi(match[2])
+	z, _ := strconv.Atoi(match[3])
+	return Location{X: x, Y: y, Z: z, World: match[4]}, true
+}
+
+// parseLine parses a single log line, returning false if it is not a CoreProtect entry.
+func parseLine(line string) (CoreProtectLogEntry, bool) {
+	if strings.TrimSpace(line) == "" {
+		return CoreProtectLogEntry{}, false
+	}
+
+	timestampStr, text, ok := chatText(line)
+	if !ok {
+		return CoreProtectLogEntry{}, false
+	}
+	match := resultRegex.FindStringSubmatch(text)
+	if match == nil {
+		return CoreProtectLogEntry{}, false
+	}
+
+	ago, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		// Skip lines where the time ago cannot be parsed
+		return CoreProtectLogEntry{}, false
+	}
+
+	entry := CoreProtectLogEntry{RawLine: line, Ago: ago, AgoUnit: match[2]}
+	switch match[2] {
+	case "s":
+		entry.HoursAgo = ago / 3600
+	case "m":
+		entry.HoursAgo = ago / 60
+	case "h":
+		entry.HoursAgo = ago
+	case "d":
+		// Convert days to hours for consistent sorting
+		entry.HoursAgo = ago * 24
+		entry.DaysAgo = ago
+		entry.IsInDays = true
+	}
+
+	if !parseResult(match[3], &entry) {
+		return CoreProtectLogEntry{}, false
+	}
+
+	logTime, err := time.Parse("15:04:05", timestampStr)
+	if err != nil {
+		logTime = time.Time{}
+	}
+	entry.Time = logTime
+
+	return entry, true
+}
+
+// agoUnits is the length of each unit CoreProtect displays times ago in
+var agoUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// setLoggedAt records the full time of the log line and works out from it when the action happened
+func (e *CoreProtectLogEntry) setLoggedAt(loggedAt time.Time) {
+	e.LoggedAt = loggedAt
+	if loggedAt.IsZero() {
+		e.EventTime = time.Time{}
+		e.EventTimeError = 0
+		return
+	}
+	unit := agoUnits[e.AgoUnit]
+	e.EventTime = loggedAt.Add(-time.Duration(e.Ago * float64(unit))).Round(time.Second)
+	// Times ago are shown to two decimal places
+	e.EventTimeError = unit / 200
+}
+
+// parseResult fills in the user, action and target of an entry from the part
+// of a lookup result after the time, returning false if it is not recognised
+func parseResult(result string, entry *CoreProtectLogEntry) bool {
+	if match := messageResultRegex.FindStringSubmatch(result); match != nil {
+		entry.Username = match[1]
+		entry.Message = strings.TrimSpace(match[2])
+		switch {
+		case strings.HasPrefix(entry.Message, "/"):
+			entry.Action = ActionCommand
+		case signTextRegex.MatchString(entry.Message):
+			// Sign text is written out line by line, e.g. Line 1: Welcome Line 2: to spawn
+			entry.Action = ActionSign
+		default:
+			entry.Action = ActionChat
 		}
+		return true
+	}
+
+	if match := loginResultRegex.FindStringSubmatch(result); match != nil {
+		entry.Username = match[1]
+		entry.Message = "logged " + match[2]
+		entry.Action = ActionLogin
+		if match[2] == "out" {
+			entry.Action = ActionLogout
+		}
+		return true
+	}
+
+	if match := transactionResultRegex.FindStringSubmatch(result); match != nil {
+		entry.Username = match[1]
+		entry.Action = actionVerbs[match[2]]
+		entry.Amount, _ = strconv.Atoi(match[3])
+		entry.Target = normalizeTarget(match[4])
+		entry.Message = fmt.Sprintf("%s x%s %s", match[2], match[3], entry.Target)
+		return true
+	}
+
+	if match := blockResultRegex.FindStringSubmatch(result); match != nil {
+		entry.Username = match[1]
+		entry.Action = actionVerbs[match[2]]
+		entry.Target = normalizeTarget(match[3])
+		entry.Message = match[2] + " " + entry.Target
+		return true
+	}
+
+	return false
+}
+
+// ParseLogContent parses the raw log content string and extracts CoreProtect entries.
+func ParseLogContent(logContent string) (*ParsedLog, error) {
+	return ParseReader(strings.NewReader(logContent), nil, nil)
+}
+
+// ParseReader reads log lines from r and extracts the CoreProtect entries
+// accepted by the filter (all entries if it is nil), sorted oldest first.
+// The parser dates the entries as for NewScanner and may be nil. The filter
+// sees the entries once their dates are final, after any midnight rollovers
+// found at the end of the log have been corrected.
+func ParseReader(r io.Reader, parser *logparser.Parser, filter Filter) (*ParsedLog, error) {
+	parsedLog := &ParsedLog{}
+	scanner := NewScanner(r, parser)
+	for scanner.Scan() {
+		parsedLog.Entries = append(parsedLog.Entries, scanner.Entry())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	parsedLog.Sessions = scanner.Sessions()
+
+	// Midnight rollovers are only known at the end when dating back from the file's mtime
+	if days := scanner.RolloverCorrection(); days > 0 {
+		for i := range parsedLog.Entries {
+			entry := &parsedLog.Entries[i]
+			entry.setLoggedAt(logparser.ShiftDays(entry.LoggedAt, -days))
+		}
+	}
+	if filter != nil {
+		kept := parsedLog.Entries[:0]
+		for _, entry := range parsedLog.Entries {
+			if filter(entry) {
+				kept = append(kept, entry)
+			}
+		}
+		parsedLog.Entries = kept
 	}
 
 	// Sort entries:
@@ -138,4 +495,4 @@ func ParseLogContent(logContent string) (*ParsedLog, error) {
 	})
 
 	return parsedLog, nil
-}
\ No newline at end of file
+}
@@ -1,9 +1,12 @@
 package coreprotectparser
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"goparselogs/pkg/logparser"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -109,3 +112,116 @@ func TestParseLogContent_MalformedHours(t *testing.T) {
 	assert.NotNil(t, parsedLog)
 	assert.Empty(t, parsedLog.Entries, "Should not parse entries with malformed hours")
 }
+
+func TestParseLogContent_LookupResultTypes(t *testing.T) {
+	logContent := `[14:37:37] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 9.00/h ago §f- §3Th4tGuy1 §fbroke §3minecraft:diamond_ore§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x123/y12/z-45/world)
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 8.00/h ago §f- §3#tnt §fplaced §3tnt§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 7.00/h ago §f- §3Th4tGuy1 §fremoved §3x64 diamond§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x:10/y:64/z:20/world_nether)
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 6.00/h ago §f- §3Th4tGuy1 §fadded §3x1 diamond_sword§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 5.00/h ago §f- §3Th4tGuy1 §fkilled §3villager§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 4.00/h ago §f- §3Th4tGuy1 §flogged in§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 3.00/h ago §f- §3Th4tGuy1 §flogged out§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- Th4tGuy1: §f/gamemode creative
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- Th4tGuy1: §fLine 1: Welcome Line 2: to spawn
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x0/y70/z0/world)
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 30.00/m ago §f- §3Th4tGuy1 §fclicked §3lever§f.
+`
+	parsedLog, err := ParseLogContent(logContent)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 10)
+
+	entries := parsedLog.Entries
+	assert.Equal(t, ActionBreak, entries[0].Action)
+	assert.Equal(t, "Th4tGuy1", entries[0].Username)
+	assert.Equal(t, "diamond_ore", entries[0].Target)
+	assert.Equal(t, "broke diamond_ore", entries[0].Message)
+	assert.Equal(t, &Location{X: 123, Y: 12, Z: -45, World: "world"}, entries[0].Location)
+
+	assert.Equal(t, ActionPlace, entries[1].Action)
+	assert.Equal(t, "#tnt", entries[1].Username)
+	assert.Nil(t, entries[1].Location)
+
+	assert.Equal(t, ActionRemove, entries[2].Action)
+	assert.Equal(t, 64, entries[2].Amount)
+	assert.Equal(t, "diamond", entries[2].Target)
+	assert.Equal(t, "removed x64 diamond", entries[2].Message)
+	assert.Equal(t, &Location{X: 10, Y: 64, Z: 20, World: "world_nether"}, entries[2].Location)
+
+	assert.Equal(t, ActionAdd, entries[3].Action)
+	assert.Equal(t, ActionKill, entries[4].Action)
+	assert.Equal(t, "villager", entries[4].Target)
+	assert.Equal(t, ActionLogin, entries[5].Action)
+	assert.Equal(t, ActionLogout, entries[6].Action)
+	assert.Equal(t, ActionCommand, entries[7].Action)
+	assert.Equal(t, "/gamemode creative", entries[7].Message)
+	assert.Equal(t, ActionSign, entries[8].Action)
+	assert.Equal(t, "Line 1: Welcome Line 2: to spawn", entries[8].Message)
+	assert.NotEmpty(t, entries[8].LocationLine)
+
+	assert.Equal(t, ActionClick, entries[9].Action)
+	assert.InDelta(t, 0.5, entries[9].HoursAgo, 1e-9)
+}
+
+func TestParseLogContent_ChatWithLocation(t *testing.T) {
+	// Chat lookups show where the player was, which doesn't make the message a sign
+	logContent := `[14:37:37] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- Th4tGuy1: §fmeet me at spawn
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x0/y70/z0/world)
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 0.50/h ago §f- Th4tGuy1: §f/home
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x5/y70/z5/world)
+`
+	parsedLog, err := ParseLogContent(logContent)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 2)
+
+	entries := parsedLog.Entries
+	assert.Equal(t, ActionChat, entries[0].Action)
+	assert.Equal(t, "meet me at spawn", entries[0].Message)
+	assert.Equal(t, &Location{X: 0, Y: 70, Z: 0, World: "world"}, entries[0].Location)
+	assert.Equal(t, ActionCommand, entries[1].Action)
+	assert.Equal(t, &Location{X: 5, Y: 70, Z: 5, World: "world"}, entries[1].Location)
+}
+
+func TestParseReader_AbsoluteTimes(t *testing.T) {
+	logContent := `[23:00:00] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- Th4tGuy1: §fhello
+[23:00:00] [Render thread/INFO]: [System] [CHAT] 1.50/d ago §f- Th4tGuy1: §fold
+[00:30:00] [Render thread/INFO]: [System] [CHAT] 30.00/m ago §f- Th4tGuy1: §fafter midnight
+[00:30:00] [Render thread/INFO]: [System] [CHAT] 15.00/s ago §f- Th4tGuy1: §fjust now
+`
+	parser, err := logparser.NewParserWithFormat(logparser.VanillaFormat)
+	assert.NoError(t, err)
+	parser.SetLocation(time.UTC)
+	parser.SetStartDate(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	parsedLog, err := ParseReader(strings.NewReader(logContent), parser, nil)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 4)
+
+	byMessage := map[string]CoreProtectLogEntry{}
+	for _, entry := range parsedLog.Entries {
+		byMessage[entry.Message] = entry
+	}
+
+	hello := byMessage["hello"]
+	assert.Equal(t, time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC), hello.LoggedAt)
+	assert.Equal(t, time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC), hello.EventTime)
+	assert.Equal(t, 18*time.Second, hello.EventTimeError)
+
+	old := byMessage["old"]
+	assert.Equal(t, time.Date(2024, 4, 30, 11, 0, 0, 0, time.UTC), old.EventTime)
+	assert.Equal(t, 432*time.Second, old.EventTimeError)
+
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), byMessage["after midnight"].EventTime)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 29, 45, 0, time.UTC), byMessage["just now"].EventTime)
+	assert.Equal(t, "15.00/s", byMessage["just now"].AgoString())
+}
+
+func TestParseLogContent_NoDate(t *testing.T) {
+	parsedLog, err := ParseLogContent(`[10:00:00] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- Th4tGuy1: §fhello`)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 1)
+	assert.True(t, parsedLog.Entries[0].EventTime.IsZero())
+}
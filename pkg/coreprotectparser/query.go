@@ -0,0 +1,216 @@
+package coreprotectparser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"goparselogs/pkg/query"
+)
+
+// Filter reports whether a CoreProtect entry should be kept.
+type Filter func(entry CoreProtectLogEntry) bool
+
+// agePartRegex matches one component of an age such as 2h, 30m or 1.5d
+var agePartRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)([smhdw]?)`)
+
+// ageUnitHours is the length of each age unit in hours
+var ageUnitHours = map[string]float64{
+	"s": 1.0 / 3600,
+	"m": 1.0 / 60,
+	"h": 1,
+	"":  1,
+	"d": 24,
+	"w": 24 * 7,
+}
+
+// Field returns the values of the named field, allowing entries to be matched
+// by filter queries such as user:Th4tGuy1, action:break, x:>100 or ago:<2h.
+func (e CoreProtectLogEntry) Field(name string) ([]string, bool) {
+	switch name {
+	case "user", "username", "player":
+		return []string{e.Username}, true
+	case "msg", "message":
+		return []string{e.Message}, true
+	case "action":
+		return []string{e.Action.String()}, true
+	case "target", "material", "block", "item", "entity":
+		return []string{e.Target}, true
+	case "amount":
+		return []string{strconv.Itoa(e.Amount)}, true
+	case "page":
+		return []string{strconv.Itoa(e.Page)}, true
+	case "world":
+		if e.Location == nil {
+			return nil, true
+		}
+		return []string{e.Location.World}, true
+	case "x", "y", "z":
+		if e.Location == nil {
+			return nil, true
+		}
+		coordinates := map[string]int{"x": e.Location.X, "y": e.Location.Y, "z": e.Location.Z}
+		return []string{strconv.Itoa(coordinates[name])}, true
+	case "ago":
+		return []string{e.AgoString()}, true
+	case "time", "timestamp":
+		return []string{e.Time.Format("15:04:05")}, true
+	case "at":
+		if e.EventTime.IsZero() {
+			return nil, true
+		}
+		return []string{e.EventTime.Format("2006-01-02 15:04:05")}, true
+	}
+	return nil, false
+}
+
+// Text returns the values searched by filter terms that don't name a field.
+func (e CoreProtectLogEntry) Text() []string {
+	return []string{e.Username, e.Message}
+}
+
+// CompareField compares the entry's age with a duration such as 2h, 90m,
+// 1.5d or 1d12h for ago:<, ago:<=, ago:> and ago:>= terms. Plain numbers are hours.
+func (e CoreProtectLogEntry) CompareField(name string, op query.Op, value string) (bool, bool) {
+	if name != "ago" {
+		return false, false
+	}
+	hours, err := ParseAge(value)
+	if err != nil {
+		return false, false
+	}
+	switch op {
+	case query.OpLess:
+		return e.HoursAgo < hours, true
+	case query.OpLessEq:
+		return e.HoursAgo <= hours, true
+	case query.OpGreater:
+		return e.HoursAgo > hours, true
+	case query.OpGreaterEq:
+		return e.HoursAgo >= hours, true
+	}
+	return false, false
+}
+
+// AgoString formats the entry's age the way CoreProtect displays it, e.g. 14.20/h.
+func (e CoreProtectLogEntry) AgoString() string {
+	if e.AgoUnit != "" {
+		return fmt.Sprintf("%.2f/%s", e.Ago, e.AgoUnit)
+	}
+	if e.IsInDays {
+		return fmt.Sprintf("%.2f/d", e.DaysAgo)
+	}
+	return fmt.Sprintf("%.2f/h", e.HoursAgo)
+}
+
+// EventTimeString formats when the action happened along with its possible
+// error, e.g. "2024-05-01 21:00:00 ±18s", or returns "" if it is unknown.
+func (e CoreProtectLogEntry) EventTimeString() string {
+	if e.EventTime.IsZero() {
+		return ""
+	}
+	at := e.EventTime.Format("2006-01-02 15:04:05")
+	if e.EventTimeError < time.Second {
+		return at
+	}
+	return fmt.Sprintf("%s ±%s", at, e.EventTimeError.Round(time.Second))
+}
+
+// ParseAge parses an age such as 2h, 90m, 1.5d or 1d12h into hours.
+func ParseAge(value string) (float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	parts := agePartRegex.FindAllStringSubmatchIndex(value, -1)
+	if len(parts) == 0 {
+		return 0, fmt.Errorf("invalid age: %s", value)
+	}
+
+	var hours float64
+	end := 0
+	for _, part := range parts {
+		if part[0] != end {
+			return 0, fmt.Errorf("invalid age: %s", value)
+		}
+		number, err := strconv.ParseFloat(value[part[2]:part[3]], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age: %s", value)
+		}
+		hours += number * ageUnitHours[value[part[4]:part[5]]]
+		end = part[1]
+	}
+	if end != len(value) {
+		return 0, fmt.Errorf("invalid age: %s", value)
+	}
+	return hours, nil
+}
+
+// NewDatabaseQuery returns a database query reading only the actions that
+// could match the queries, going by their user:, action: and ago: terms, so
+// that a database needn't be read whole to be filtered. The queries must
+// still be matched against the actions read. Times ago are worked out from now.
+func NewDatabaseQuery(queries []*query.Query, now time.Time) DatabaseQuery {
+	q := DatabaseQuery{Now: now}
+	if len(queries) == 1 {
+		// Actions matching any one of several queries can't be narrowed down by the terms of one
+		q.narrow(queries[0].Root)
+	}
+	return q
+}
+
+// narrow restricts the query to actions that could match a node of a filter query
+func (q *DatabaseQuery) narrow(node query.Node) {
+	switch n := node.(type) {
+	case *query.AndNode:
+		for _, child := range n.Children {
+			q.narrow(child)
+		}
+	case *query.TermNode:
+		q.narrowTerm(n)
+	}
+	// Either side of an OR may match, and NOT matches actions without the term
+}
+
+// narrowTerm restricts the query to actions that could match a term that every match must satisfy
+func (q *DatabaseQuery) narrowTerm(term *query.TermNode) {
+	switch term.Field {
+	case "user", "username", "player":
+		if term.Op == query.OpEqual && len(q.Users) == 0 {
+			q.Users = []string{term.Value}
+		} else if term.Op == query.OpContains && q.UserContains == "" {
+			q.UserContains = term.Value
+		}
+	case "action":
+		var actions []Action
+		for action := ActionChat; action <= ActionLogout; action++ {
+			name := action.String()
+			matches := (term.Op == query.OpEqual && strings.EqualFold(name, term.Value)) ||
+				(term.Op == query.OpContains && strings.Contains(name, strings.ToLower(term.Value)))
+			if matches && (len(q.Actions) == 0 || slices.Contains(q.Actions, action)) {
+				actions = append(actions, action)
+			}
+		}
+		// No actions would select them all, so leave it to the filter to match none
+		if len(actions) > 0 {
+			q.Actions = actions
+		}
+	case "ago":
+		hours, err := ParseAge(term.Value)
+		if err != nil {
+			return
+		}
+		// Database times are whole seconds, so allow a second either side
+		at := q.Now.Add(-time.Duration(hours * float64(time.Hour)))
+		switch term.Op {
+		case query.OpLess, query.OpLessEq:
+			if since := at.Add(-time.Second); since.After(q.Since) {
+				q.Since = since
+			}
+		case query.OpGreater, query.OpGreaterEq:
+			if until := at.Add(time.Second); q.Until.IsZero() || until.Before(q.Until) {
+				q.Until = until
+			}
+		}
+	}
+}
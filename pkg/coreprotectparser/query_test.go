@@ -0,0 +1,137 @@
+package coreprotectparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"goparselogs/pkg/logparser"
+	"goparselogs/pkg/query"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const filterLog = `
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 14.20/h ago §f- queercookie: §fcan I see?
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 1.50/h ago §f- Th4tGuy1: §fi dontr need it all
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 3.00/h ago §f- Th4tGuy1: §fanother one
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 2.00/d ago §f- xSaVage_: §f0.05
+`
+
+func parseFiltered(t *testing.T, source string) []CoreProtectLogEntry {
+	q, err := query.Parse(source)
+	assert.NoError(t, err)
+	parsedLog, err := ParseLogContent(filterLog)
+	assert.NoError(t, err)
+
+	var matched []CoreProtectLogEntry
+	for _, entry := range parsedLog.Entries {
+		if q.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+func TestFilter_UserAndAgo(t *testing.T) {
+	entries := parseFiltered(t, "user:Th4tGuy1 ago:<2h")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "i dontr need it all", entries[0].Message)
+
+	entries = parseFiltered(t, "ago:>=1d")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "xSaVage_", entries[0].Username)
+
+	entries = parseFiltered(t, "ago:>2h AND ago:<=1d")
+	assert.Len(t, entries, 2)
+}
+
+func TestFilter_BareTermsSearchUserAndMessage(t *testing.T) {
+	assert.Len(t, parseFiltered(t, "th4tguy1"), 2)
+	assert.Len(t, parseFiltered(t, "can I see"), 1)
+	assert.Len(t, parseFiltered(t, "msg:/^0\\.\\d+$/"), 1)
+}
+
+func TestParseReader_Filter(t *testing.T) {
+	q := query.MustParse("user:queercookie")
+	parsedLog, err := ParseLogContent(filterLog)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 4)
+
+	filtered, err := ParseReader(strings.NewReader(filterLog), nil, func(entry CoreProtectLogEntry) bool { return q.Match(entry) })
+	assert.NoError(t, err)
+	assert.Len(t, filtered.Entries, 1)
+}
+
+func TestParseReader_FilterAfterMidnightRollover(t *testing.T) {
+	logContent := `[23:00:00] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- Th4tGuy1: §fbefore midnight
+[00:30:00] [Render thread/INFO]: [System] [CHAT] 30.00/m ago §f- Th4tGuy1: §fafter midnight
+`
+	parser, err := logparser.NewParserWithFormat(logparser.VanillaFormat)
+	assert.NoError(t, err)
+	parser.SetLocation(time.UTC)
+	parser.SetEndDate(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC))
+
+	// The first entry is only known to be from the day before once the rollover is found
+	q := query.MustParse("at:>2024-05-02")
+	filtered, err := ParseReader(strings.NewReader(logContent), parser, func(entry CoreProtectLogEntry) bool { return q.Match(entry) })
+	assert.NoError(t, err)
+	if assert.Len(t, filtered.Entries, 1) {
+		assert.Equal(t, "after midnight", filtered.Entries[0].Message)
+		assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), filtered.Entries[0].EventTime)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for input, expected := range map[string]float64{
+		"2h":    2,
+		"90m":   1.5,
+		"1.5d":  36,
+		"1d12h": 36,
+		"1w":    168,
+		"3":     3,
+	} {
+		hours, err := ParseAge(input)
+		assert.NoError(t, err, input)
+		assert.InDelta(t, expected, hours, 1e-9, input)
+	}
+
+	for _, input := range []string{"", "h", "2x", "2h foo"} {
+		_, err := ParseAge(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestFilter_ActionTargetAndLocation(t *testing.T) {
+	parsedLog, err := ParseLogContent(`[14:37:37] [Render thread/INFO]: [System] [CHAT] 9.00/h ago §f- §3Th4tGuy1 §fbroke §3diamond_ore§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT]                  §7^ §o(x123/y12/z-45/world)
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 7.00/h ago §f- §3Th4tGuy1 §fremoved §3x64 diamond§f.
+[14:37:37] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- Th4tGuy1: §fhello
+`)
+	assert.NoError(t, err)
+
+	count := func(source string) int {
+		q := query.MustParse(source)
+		n := 0
+		for _, entry := range parsedLog.Entries {
+			if q.Match(entry) {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 1, count("action:break"))
+	assert.Equal(t, 2, count("target:diamond"))
+	assert.Equal(t, 1, count("amount:>=64"))
+	assert.Equal(t, 1, count("x:>100 AND world:=world"))
+	assert.Equal(t, 0, count("x:<0"))
+}
+
+func TestEventTimeString(t *testing.T) {
+	entry := CoreProtectLogEntry{}
+	assert.Equal(t, "", entry.EventTimeString())
+
+	entry.EventTime = time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC)
+	entry.EventTimeError = 18 * time.Second
+	assert.Equal(t, "2024-05-01 21:00:00 ±18s", entry.EventTimeString())
+}
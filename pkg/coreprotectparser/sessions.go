@@ -0,0 +1,75 @@
+package coreprotectparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Session is a single CoreProtect lookup, possibly read over several pages.
+type Session struct {
+	Index          int   // Position of the lookup in the log, from 0
+	TotalPages     int   // Number of pages in the lookup, 0 if no page footer was seen
+	Pages          []int // Pages read, in ascending order
+	DuplicatePages int   // Number of times a page was read again and dropped
+	Entries        int   // Number of entries kept from the lookup
+}
+
+// addPage records that a page was read, returning false if it already had been.
+func (s *Session) addPage(page int) bool {
+	i := sort.SearchInts(s.Pages, page)
+	if i < len(s.Pages) && s.Pages[i] == page {
+		return false
+	}
+	s.Pages = append(s.Pages, 0)
+	copy(s.Pages[i+1:], s.Pages[i:])
+	s.Pages[i] = page
+	return true
+}
+
+// restartedBy reports whether a page of results must be from a new lookup:
+// page 1 was already read or the lookup had a different number of pages.
+func (s *Session) restartedBy(page, total int) bool {
+	if total > 0 && s.TotalPages > 0 && total != s.TotalPages {
+		return true
+	}
+	return page == 1 && len(s.Pages) > 0 && s.Pages[0] == 1
+}
+
+// MissingPages returns the pages of the lookup that were never read, in
+// ascending order. It is empty if the number of pages is unknown.
+func (s Session) MissingPages() []int {
+	var missing []int
+	next := 0
+	for page := 1; page <= s.TotalPages; page++ {
+		if next < len(s.Pages) && s.Pages[next] == page {
+			next++
+			continue
+		}
+		missing = append(missing, page)
+	}
+	return missing
+}
+
+// Complete reports whether every page of the lookup was read.
+func (s Session) Complete() bool {
+	return len(s.MissingPages()) == 0
+}
+
+// FormatPageRanges formats ascending page numbers compactly, e.g. "1-3, 7, 9-12".
+func FormatPageRanges(pages []int) string {
+	var parts []string
+	for i := 0; i < len(pages); {
+		j := i
+		for j+1 < len(pages) && pages[j+1] == pages[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprintf("%d", pages[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", pages[i], pages[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,103 @@
+package coreprotectparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pagedLookupLog = `[14:00:00] [Render thread/INFO]: [System] [CHAT] CoreProtect - Lookup searching. Please wait...
+[14:00:00] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:00:00] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- §3Th4tGuy1 §fbroke §3stone§f.
+[14:00:00] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- §3Th4tGuy1 §fbroke §3dirt§f.
+[14:00:00] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f1/4 ▶ §7(§f§n1§r §7| §f2 §7| §f3 §7| §f4§7)
+[14:00:05] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:00:05] [Render thread/INFO]: [System] [CHAT] 3.00/h ago §f- §3Th4tGuy1 §fbroke §3sand§f.
+[14:00:05] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f2/4 ▶
+[14:00:09] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:00:09] [Render thread/INFO]: [System] [CHAT] 3.00/h ago §f- §3Th4tGuy1 §fbroke §3sand§f.
+[14:00:09] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f2/4 ▶
+[14:01:00] [Render thread/INFO]: [System] [CHAT] CoreProtect - Lookup searching. Please wait...
+[14:01:00] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:01:00] [Render thread/INFO]: [System] [CHAT] 0.50/h ago §f- §3queercookie §flogged in§f.
+`
+
+func TestParseLogContent_Sessions(t *testing.T) {
+	parsedLog, err := ParseLogContent(pagedLookupLog)
+	assert.NoError(t, err)
+
+	// The repeated second page is dropped
+	assert.Len(t, parsedLog.Entries, 4)
+	assert.Len(t, parsedLog.Sessions, 2)
+
+	first := parsedLog.Sessions[0]
+	assert.Equal(t, 4, first.TotalPages)
+	assert.Equal(t, []int{1, 2}, first.Pages)
+	assert.Equal(t, 1, first.DuplicatePages)
+	assert.Equal(t, 3, first.Entries)
+	assert.Equal(t, []int{3, 4}, first.MissingPages())
+	assert.False(t, first.Complete())
+
+	second := parsedLog.Sessions[1]
+	assert.Equal(t, 0, second.TotalPages)
+	assert.Equal(t, 1, second.Entries)
+	assert.True(t, second.Complete())
+
+	for _, entry := range parsedLog.Entries {
+		if entry.Username == "queercookie" {
+			assert.Equal(t, 1, entry.Session)
+			assert.Equal(t, 0, entry.Page)
+		} else {
+			assert.Equal(t, 0, entry.Session)
+			assert.NotZero(t, entry.Page)
+		}
+	}
+}
+
+func TestParseLogContent_PageTotalChangeStartsSession(t *testing.T) {
+	parsedLog, err := ParseLogContent(`[14:00:00] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- §3Th4tGuy1 §fbroke §3stone§f.
+[14:00:00] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f1/2 ▶
+[14:00:05] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- §3Th4tGuy1 §fplaced §3stone§f.
+[14:00:05] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f1/9 ▶
+`)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 2)
+	assert.Len(t, parsedLog.Sessions, 2)
+}
+
+func TestParseLogContent_FirstPageAgainStartsSession(t *testing.T) {
+	// Lookups pasted without their searching lines
+	parsedLog, err := ParseLogContent(`[14:00:00] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:00:00] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- §3Th4tGuy1 §fbroke §3stone§f.
+[14:00:00] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f1/3 ▶
+[14:00:05] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:00:05] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- §3Th4tGuy1 §fbroke §3dirt§f.
+[14:00:05] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f2/3 ▶
+[14:01:00] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:01:00] [Render thread/INFO]: [System] [CHAT] 1.00/h ago §f- §3queercookie §fplaced §3sand§f.
+[14:01:00] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f1/3 ▶
+[14:01:05] [Render thread/INFO]: [System] [CHAT] ----- CoreProtect | Lookup Results -----
+[14:01:05] [Render thread/INFO]: [System] [CHAT] 2.00/h ago §f- §3queercookie §fplaced §3glass§f.
+[14:01:05] [Render thread/INFO]: [System] [CHAT] §f◀ Page §f2/3 ▶
+`)
+	assert.NoError(t, err)
+	assert.Len(t, parsedLog.Entries, 4)
+	assert.Len(t, parsedLog.Sessions, 2)
+	for _, session := range parsedLog.Sessions {
+		assert.Equal(t, []int{1, 2}, session.Pages)
+		assert.Zero(t, session.DuplicatePages)
+		assert.Equal(t, []int{3}, session.MissingPages())
+	}
+	for _, entry := range parsedLog.Entries {
+		if entry.Username == "queercookie" {
+			assert.Equal(t, 1, entry.Session)
+		} else {
+			assert.Equal(t, 0, entry.Session)
+		}
+	}
+}
+
+func TestFormatPageRanges(t *testing.T) {
+	assert.Equal(t, "", FormatPageRanges(nil))
+	assert.Equal(t, "1-3, 7, 9-12", FormatPageRanges([]int{1, 2, 3, 7, 9, 10, 11, 12}))
+}
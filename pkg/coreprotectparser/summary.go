@@ -0,0 +1,118 @@
+package coreprotectparser
+
+import (
+	"sort"
+	"time"
+)
+
+// PlayerSummary is the activity of one player across a set of CoreProtect entries.
+type PlayerSummary struct {
+	Username    string
+	Entries     int            // Number of entries by the player
+	Actions     map[Action]int // Number of entries per action
+	Materials   map[string]int // Number of entries per block, item or entity acted on
+	Hours       [24]int        // Number of entries per hour of the day, for entries with a known event time
+	FirstSeen   time.Time      // Event time of the player's oldest entry, zero if unknown
+	LastSeen    time.Time      // Event time of the player's newest entry, zero if unknown
+	MaxHoursAgo float64        // Time ago of the player's oldest entry
+	MinHoursAgo float64        // Time ago of the player's newest entry
+}
+
+// Count is a name with the number of times it was seen.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Summarize groups the entries by username, returning the players with the
+// most entries first.
+func Summarize(entries []CoreProtectLogEntry) []PlayerSummary {
+	byUser := map[string]*PlayerSummary{}
+	var order []string
+	for _, entry := range entries {
+		summary, ok := byUser[entry.Username]
+		if !ok {
+			summary = &PlayerSummary{
+				Username:    entry.Username,
+				Actions:     map[Action]int{},
+				Materials:   map[string]int{},
+				MaxHoursAgo: entry.HoursAgo,
+				MinHoursAgo: entry.HoursAgo,
+			}
+			byUser[entry.Username] = summary
+			order = append(order, entry.Username)
+		}
+		summary.add(entry)
+	}
+
+	summaries := make([]PlayerSummary, 0, len(order))
+	for _, username := range order {
+		summaries = append(summaries, *byUser[username])
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Entries != summaries[j].Entries {
+			return summaries[i].Entries > summaries[j].Entries
+		}
+		return summaries[i].Username < summaries[j].Username
+	})
+	return summaries
+}
+
+// add counts an entry towards the summary
+func (s *PlayerSummary) add(entry CoreProtectLogEntry) {
+	s.Entries++
+	s.Actions[entry.Action]++
+	if entry.Target != "" {
+		s.Materials[entry.Target]++
+	}
+	s.MaxHoursAgo = max(s.MaxHoursAgo, entry.HoursAgo)
+	s.MinHoursAgo = min(s.MinHoursAgo, entry.HoursAgo)
+
+	if entry.EventTime.IsZero() {
+		return
+	}
+	s.Hours[entry.EventTime.Hour()]++
+	if s.FirstSeen.IsZero() || entry.EventTime.Before(s.FirstSeen) {
+		s.FirstSeen = entry.EventTime
+	}
+	if s.LastSeen.IsZero() || entry.EventTime.After(s.LastSeen) {
+		s.LastSeen = entry.EventTime
+	}
+}
+
+// TopMaterials returns up to n of the blocks, items and entities the player
+// acted on most, most frequent first.
+func (s PlayerSummary) TopMaterials(n int) []Count {
+	counts := make([]Count, 0, len(s.Materials))
+	for name, count := range s.Materials {
+		counts = append(counts, Count{Name: name, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// BusiestHours returns up to n hours of the day in which the player was
+// active, busiest first. It is empty if no event times are known.
+func (s PlayerSummary) BusiestHours(n int) []int {
+	var hours []int
+	for hour, count := range s.Hours {
+		if count > 0 {
+			hours = append(hours, hour)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool {
+		return s.Hours[hours[i]] > s.Hours[hours[j]]
+	})
+	if len(hours) > n {
+		hours = hours[:n]
+	}
+	return hours
+}
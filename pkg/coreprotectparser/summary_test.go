@@ -0,0 +1,48 @@
+package coreprotectparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC)
+	}
+	entries := []CoreProtectLogEntry{
+		{Username: "Th4tGuy1", Action: ActionBreak, Target: "stone", HoursAgo: 5, EventTime: at(9, 0)},
+		{Username: "Th4tGuy1", Action: ActionBreak, Target: "dirt", HoursAgo: 4, EventTime: at(10, 0)},
+		{Username: "queercookie", Action: ActionChat, HoursAgo: 3.5, EventTime: at(10, 30)},
+		{Username: "Th4tGuy1", Action: ActionRemove, Target: "diamond", Amount: 64, HoursAgo: 3, EventTime: at(11, 0)},
+		{Username: "Th4tGuy1", Action: ActionBreak, Target: "stone", HoursAgo: 2.5, EventTime: at(11, 30)},
+		{Username: "queercookie", Action: ActionLogout, HoursAgo: 1},
+	}
+
+	summaries := Summarize(entries)
+	if !assert.Len(t, summaries, 2) {
+		return
+	}
+
+	player := summaries[0]
+	assert.Equal(t, "Th4tGuy1", player.Username)
+	assert.Equal(t, 4, player.Entries)
+	assert.Equal(t, map[Action]int{ActionBreak: 3, ActionRemove: 1}, player.Actions)
+	assert.Equal(t, at(9, 0), player.FirstSeen)
+	assert.Equal(t, at(11, 30), player.LastSeen)
+	assert.Equal(t, 5.0, player.MaxHoursAgo)
+	assert.Equal(t, 2.5, player.MinHoursAgo)
+	assert.Equal(t, []Count{{"stone", 2}, {"diamond", 1}}, player.TopMaterials(2))
+	assert.Equal(t, []int{11, 9, 10}, player.BusiestHours(3))
+
+	// Entries without an event time still count, but not towards hours or first/last seen
+	other := summaries[1]
+	assert.Equal(t, "queercookie", other.Username)
+	assert.Equal(t, 2, other.Entries)
+	assert.Equal(t, at(10, 30), other.FirstSeen)
+	assert.Equal(t, at(10, 30), other.LastSeen)
+	assert.Equal(t, 1.0, other.MinHoursAgo)
+	assert.Empty(t, other.TopMaterials(5))
+	assert.Equal(t, []int{10}, other.BusiestHours(3))
+}
@@ -0,0 +1,237 @@
+package events
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	"goparselogs/pkg/logparser"
+)
+
+// Type identifies the kind of a Minecraft event.
+type Type int
+
+const (
+	Join Type = iota
+	Leave
+	Chat
+	Command
+	Death
+	Advancement
+	Kick
+	Ban
+	ServerStart
+	ServerStop
+)
+
+// AllTypes lists every event type in display order.
+var AllTypes = []Type{Join, Leave, Chat, Command, Death, Advancement, Kick, Ban, ServerStart, ServerStop}
+
+// String returns the display name of the event type.
+func (t Type) String() string {
+	switch t {
+	case Join:
+		return "Join"
+	case Leave:
+		return "Leave"
+	case Chat:
+		return "Chat"
+	case Command:
+		return "Command"
+	case Death:
+		return "Death"
+	case Advancement:
+		return "Advancement"
+	case Kick:
+		return "Kick"
+	case Ban:
+		return "Ban"
+	case ServerStart:
+		return "Server Start"
+	case ServerStop:
+		return "Server Stop"
+	}
+	return "Unknown"
+}
+
+// Event is a typed Minecraft event extracted from a log entry.
+type Event struct {
+	Type       Type
+	Player     string // Player the event concerns, if any
+	UUID       string // Player UUID, from the authenticator line preceding a join
+	IP         string // Player address, from the login line preceding a join
+	Detail     string // Chat message, command, death message, advancement, kick/ban reason or server version
+	Actor      string // Player or console that issued a kick or ban
+	Entry      logparser.LogEntry
+	EntryIndex int // Index of the source entry in the slice passed to Extract
+}
+
+// playerPattern matches Java player names and Bedrock (Geyser/Floodgate) prefixed names.
+const playerPattern = `[.*]?\w{1,16}`
+
+var (
+	// Prefix added to chat lines in client logs
+	// Example: [System] [CHAT] <Steve> hello
+	clientChatPrefixRegex = regexp.MustCompile(`^(?:\[System\] )?\[CHAT\] `)
+
+	// Example: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5
+	uuidRegex = regexp.MustCompile(`^UUID of player (` + playerPattern + `) is ([0-9a-fA-F-]{32,36})$`)
+
+	// Example: Steve[/127.0.0.1:54321] logged in with entity id 123 at (1.5, 64.0, -3.5)
+	loginRegex = regexp.MustCompile(`^(` + playerPattern + `)\[/?([^\]]+)\] logged in with entity id`)
+
+	// Example: Steve joined the game
+	joinRegex = regexp.MustCompile(`^(` + playerPattern + `) joined the game$`)
+
+	// Example: Steve left the game
+	leaveRegex = regexp.MustCompile(`^(` + playerPattern + `) left the game$`)
+
+	// Example: <Steve> hello / [Not Secure] <Steve> hello
+	chatRegex = regexp.MustCompile(`^(?:\[Not Secure\] )?<(` + playerPattern + `)> (.*)$`)
+
+	// Example: Steve issued server command: /gamemode creative
+	commandRegex = regexp.MustCompile(`^(` + playerPattern + `) issued server command: (.*)$`)
+
+	// Example: Steve has made the advancement [Stone Age]
+	advancementRegex = regexp.MustCompile(`^(` + playerPattern + `) has (?:made the advancement|completed the challenge|reached the goal) \[(.+)\]$`)
+
+	// Example: Kicked Steve: Flying is not enabled / [Admin: Kicked Steve: Flying]
+	kickRegex = regexp.MustCompile(`^\[?(?:(` + playerPattern + `|Server|Rcon): )?Kicked (` + playerPattern + `)(?:: (.*?))?\]?$`)
+
+	// Example: Banned Steve: Griefing / [Admin: Banned Steve: Griefing]
+	banRegex = regexp.MustCompile(`^\[?(?:(` + playerPattern + `|Server|Rcon): )?Banned (` + playerPattern + `)(?:: (.*?))?\]?$`)
+
+	// Example: Starting minecraft server version 1.20.4
+	serverStartRegex = regexp.MustCompile(`^Starting minecraft server version (.+)$`)
+
+	// Example: Stopping server
+	serverStopRegex = regexp.MustCompile(`^Stopping (?:the )?server$`)
+
+	// Death messages all start with the player name followed by one of these phrases.
+	// Example: Steve was slain by Zombie
+	deathRegex = regexp.MustCompile(`^(` + playerPattern + `) (` + strings.Join([]string{
+		`was (?:shot|slain|killed|pummeled|fireballed|blown up|squashed|squished|impaled|skewered|poked|stung|obliterated|struck by lightning|pricked|doomed|frozen|roasted|burnt|smashed)`,
+		`was (?:too soft|knocked into the void)`,
+		`fell (?:from|off|out of|while|too far)`,
+		`hit the ground too hard`,
+		`drowned`, `died`, `blew up`, `burned to death`, `went up in flames`, `went off with a bang`,
+		`walked into (?:fire|danger|the danger zone|a cactus)`, `tried to swim in lava`,
+		`suffocated in a wall`, `was squished too much`, `starved to death`, `froze to death`,
+		`withered away`, `experienced kinetic energy`, `discovered the floor was lava`,
+		`didn't want to live`, `left the confines of this world`, `fell out of the world`,
+	}, "|") + `)(.*)$`)
+)
+
+// Classify inspects a single log entry and returns the event it describes.
+// The second return value is false if the entry is not a recognised event.
+// UUID and IP are not set, as they come from earlier lines; use Extract to
+// correlate them.
+func Classify(entry logparser.LogEntry) (Event, bool) {
+	message := clientChatPrefixRegex.ReplaceAllString(entry.Message, "")
+	event := Event{Entry: entry}
+
+	if match := joinRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player = Join, match[1]
+	} else if match := leaveRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player = Leave, match[1]
+	} else if match := chatRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player, event.Detail = Chat, match[1], match[2]
+	} else if match := commandRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player, event.Detail = Command, match[1], match[2]
+	} else if match := advancementRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player, event.Detail = Advancement, match[1], match[2]
+	} else if match := kickRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Actor, event.Player, event.Detail = Kick, match[1], match[2], match[3]
+	} else if match := banRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Actor, event.Player, event.Detail = Ban, match[1], match[2], match[3]
+	} else if match := serverStartRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Detail = ServerStart, match[1]
+	} else if serverStopRegex.MatchString(message) {
+		event.Type = ServerStop
+	} else if match := deathRegex.FindStringSubmatch(message); match != nil {
+		event.Type, event.Player, event.Detail = Death, match[1], match[2]+match[3]
+	} else {
+		return Event{}, false
+	}
+
+	return event, true
+}
+
+// Extractor classifies entries one at a time, remembering the UUIDs and IPs
+// from authenticator and login lines so they can be attached to later events.
+type Extractor struct {
+	uuids map[string]string
+	ips   map[string]string
+}
+
+// NewExtractor returns an Extractor with no remembered players.
+func NewExtractor() *Extractor {
+	return &Extractor{uuids: map[string]string{}, ips: map[string]string{}}
+}
+
+// Add classifies the entry at the given index, returning false if it is not
+// a recognised event.
+func (x *Extractor) Add(entry logparser.LogEntry, index int) (Event, bool) {
+	message := entry.Message
+	if match := uuidRegex.FindStringSubmatch(message); match != nil {
+		x.uuids[match[1]] = match[2]
+		return Event{}, false
+	}
+	if match := loginRegex.FindStringSubmatch(message); match != nil {
+		x.ips[match[1]] = stripPort(match[2])
+		return Event{}, false
+	}
+
+	event, ok := Classify(entry)
+	if !ok {
+		return Event{}, false
+	}
+	event.EntryIndex = index
+	if event.Player != "" {
+		event.UUID = x.uuids[event.Player]
+		event.IP = x.ips[event.Player]
+	}
+	return event, true
+}
+
+// Extract classifies every entry and returns the recognised events in order.
+// UUIDs and IPs from the authenticator and login lines are attached to the
+// following join event and remembered for the player's later events.
+func Extract(entries []logparser.LogEntry) []Event {
+	var events []Event
+	extractor := NewExtractor()
+	for i, entry := range entries {
+		if event, ok := extractor.Add(entry, i); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// FilterByType returns the events of the given type.
+func FilterByType(events []Event, t Type) []Event {
+	var filtered []Event
+	for _, event := range events {
+		if event.Type == t {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+// CountByType returns the number of events of each type.
+func CountByType(events []Event) map[Type]int {
+	counts := map[Type]int{}
+	for _, event := range events {
+		counts[event.Type]++
+	}
+	return counts
+}
+
+// stripPort removes the port from an address such as 127.0.0.1:54321 or [::1]:54321.
+func stripPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"testing"
+
+	"goparselogs/pkg/logparser"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const serverLog = `[12:00:00] [Server thread/INFO]: Starting minecraft server version 1.20.4
+[12:00:05] [User Authenticator #1/INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5
+[12:00:05] [Server thread/INFO]: Steve[/127.0.0.1:54321] logged in with entity id 123 at (1.5, 64.0, -3.5)
+[12:00:05] [Server thread/INFO]: Steve joined the game
+[12:00:10] [Server thread/INFO]: <Steve> hello world
+[12:00:11] [Server thread/INFO]: [Not Secure] <Alex> hi
+[12:00:12] [Server thread/INFO]: Steve issued server command: /home
+[12:00:13] [Server thread/INFO]: Steve has made the advancement [Stone Age]
+[12:00:14] [Server thread/INFO]: Steve was slain by Zombie
+[12:00:15] [Server thread/INFO]: [Admin: Kicked Alex: Flying]
+[12:00:16] [Server thread/INFO]: Banned Griefer: Griefing
+[12:00:17] [Server thread/INFO]: Steve left the game
+[12:00:18] [Server thread/INFO]: Preparing spawn area: 83%
+[12:00:20] [Server thread/INFO]: Stopping server
+`
+
+func parseEntries(t *testing.T, content string) []logparser.LogEntry {
+	parser, err := logparser.NewParser()
+	assert.NoError(t, err)
+	entries, err := parser.ParseContent(content, nil)
+	assert.NoError(t, err)
+	return entries
+}
+
+func TestExtract(t *testing.T) {
+	events := Extract(parseEntries(t, serverLog))
+
+	expected := []struct {
+		Type   Type
+		Player string
+		Detail string
+	}{
+		{ServerStart, "", "1.20.4"},
+		{Join, "Steve", ""},
+		{Chat, "Steve", "hello world"},
+		{Chat, "Alex", "hi"},
+		{Command, "Steve", "/home"},
+		{Advancement, "Steve", "Stone Age"},
+		{Death, "Steve", "was slain by Zombie"},
+		{Kick, "Alex", "Flying"},
+		{Ban, "Griefer", "Griefing"},
+		{Leave, "Steve", ""},
+		{ServerStop, "", ""},
+	}
+
+	assert.Len(t, events, len(expected))
+	for i, exp := range expected {
+		assert.Equal(t, exp.Type, events[i].Type, "type mismatch for event %d", i)
+		assert.Equal(t, exp.Player, events[i].Player, "player mismatch for event %d", i)
+		assert.Equal(t, exp.Detail, events[i].Detail, "detail mismatch for event %d", i)
+	}
+
+	join := events[1]
+	assert.Equal(t, "069a79f4-44e9-4726-a5be-fca90e38aaf5", join.UUID)
+	assert.Equal(t, "127.0.0.1", join.IP)
+	assert.Equal(t, 3, join.EntryIndex)
+	assert.Equal(t, "Admin", events[7].Actor)
+}
+
+func TestClassify_ClientChat(t *testing.T) {
+	event, ok := Classify(logparser.LogEntry{Message: "[System] [CHAT] <Steve> hi there"})
+	assert.True(t, ok)
+	assert.Equal(t, Chat, event.Type)
+	assert.Equal(t, "Steve", event.Player)
+	assert.Equal(t, "hi there", event.Detail)
+}
+
+func TestCountByType(t *testing.T) {
+	events := Extract(parseEntries(t, serverLog))
+	counts := CountByType(events)
+	assert.Equal(t, 2, counts[Chat])
+	assert.Equal(t, 1, counts[Join])
+	assert.Len(t, FilterByType(events, Chat), 2)
+}
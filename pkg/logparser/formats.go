@@ -0,0 +1,125 @@
+package logparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SniffLines is the number of non-empty lines examined when detecting the format of a log.
+const SniffLines = 50
+
+// Format describes a log line layout that the parser can recognise.
+type Format interface {
+	// Name returns the short, human-readable name of the format (e.g. "vanilla").
+	Name() string
+	// ParseLine parses a single header line. The second return value is false
+	// when the line does not match the format.
+	ParseLine(line string) (LogEntry, bool)
+}
+
+// regexFormat is a Format backed by a regular expression.
+type regexFormat struct {
+	name  string
+	regex *regexp.Regexp
+	build func(matches []string) LogEntry
+}
+
+func (f *regexFormat) Name() string {
+	return f.name
+}
+
+func (f *regexFormat) ParseLine(line string) (LogEntry, bool) {
+	matches := f.regex.FindStringSubmatch(line)
+	if matches == nil {
+		return LogEntry{}, false
+	}
+	entry := f.build(matches)
+	entry.RawLine = line
+	return entry, true
+}
+
+// NewRegexFormat creates a Format from a regular expression. The build function
+// receives the submatches of each matching line and returns the populated entry.
+func NewRegexFormat(name string, regex *regexp.Regexp, build func(matches []string) LogEntry) Format {
+	return &regexFormat{name: name, regex: regex, build: build}
+}
+
+var (
+	// VanillaFormat matches vanilla client and server logs.
+	// Example: [12:34:56] [Server thread/INFO]: Done (3.2s)!
+	VanillaFormat = NewRegexFormat("vanilla",
+		regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^/]+)/([^\]]+)\]: (.*)$`),
+		func(m []string) LogEntry {
+			return LogEntry{Timestamp: m[1], Thread: m[2], Level: m[3], Message: m[4]}
+		})
+
+	// PaperFormat matches Paper/Spigot/Bukkit server logs, which omit the thread name.
+	// Example: [12:34:56 INFO]: Done (3.2s)!
+	PaperFormat = NewRegexFormat("paper",
+		regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}) ([A-Z]+)\]: (.*)$`),
+		func(m []string) LogEntry {
+			return LogEntry{Timestamp: m[1], Level: m[2], Message: m[3]}
+		})
+
+	// ForgeFormat matches Forge/NeoForge latest.log and debug.log, which include a
+	// logger name and, in debug.log, a date and milliseconds.
+	// Example: [29Jun2024 12:00:00.123] [main/INFO] [net.minecraft.server/]: Starting
+	ForgeFormat = NewRegexFormat("forge",
+		regexp.MustCompile(`^\[(?:(\d{2}[A-Za-z]{3}\d{4}) )?(\d{2}:\d{2}:\d{2}(?:\.\d+)?)\] \[([^\]]+)/([A-Z]+)\] \[([^\]]*)\]: (.*)$`),
+		func(m []string) LogEntry {
+			return LogEntry{Date: m[1], Timestamp: m[2], Thread: m[3], Level: m[4], Logger: strings.TrimSuffix(m[5], "/"), Message: m[6]}
+		})
+
+	// FabricFormat matches Fabric logs, which put the logger name in parentheses.
+	// Example: [12:34:56] [main/INFO] (FabricLoader/GameProvider) Loading Minecraft 1.20.4
+	FabricFormat = NewRegexFormat("fabric",
+		regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^\]]+)/([A-Z]+)\] \(([^)]*)\) (.*)$`),
+		func(m []string) LogEntry {
+			return LogEntry{Timestamp: m[1], Thread: m[2], Level: m[3], Logger: m[4], Message: m[5]}
+		})
+)
+
+// registry holds the known formats in detection priority order.
+var registry = []Format{VanillaFormat, PaperFormat, ForgeFormat, FabricFormat}
+
+// RegisterFormat adds a format to the registry used for auto-detection.
+// It is not safe for concurrent use and should be called during initialisation.
+func RegisterFormat(f Format) {
+	registry = append(registry, f)
+}
+
+// Formats returns the registered formats in detection priority order.
+func Formats() []Format {
+	formats := make([]Format, len(registry))
+	copy(formats, registry)
+	return formats
+}
+
+// FormatByName returns the registered format with the given name.
+func FormatByName(name string) (Format, bool) {
+	for _, f := range registry {
+		if strings.EqualFold(f.Name(), name) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// DetectFormat returns the registered format matching the most of the given lines.
+// Ties go to the format registered first. If no format matches any line, the
+// vanilla format is returned and ok is false.
+func DetectFormat(lines []string) (format Format, ok bool) {
+	best, bestCount := VanillaFormat, 0
+	for _, f := range registry {
+		count := 0
+		for _, line := range lines {
+			if _, matched := f.ParseLine(line); matched {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = f, count
+		}
+	}
+	return best, bestCount > 0
+}
@@ -0,0 +1,100 @@
+package logparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormats_ParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		line     string
+		expected LogEntry
+	}{
+		{
+			name:     "vanilla",
+			format:   VanillaFormat,
+			line:     "[12:34:56] [Server thread/INFO]: Done (3.2s)!",
+			expected: LogEntry{Timestamp: "12:34:56", Thread: "Server thread", Level: "INFO", Message: "Done (3.2s)!"},
+		},
+		{
+			name:     "paper",
+			format:   PaperFormat,
+			line:     "[12:34:56 WARN]: Can't keep up!",
+			expected: LogEntry{Timestamp: "12:34:56", Level: "WARN", Message: "Can't keep up!"},
+		},
+		{
+			name:     "forge debug",
+			format:   ForgeFormat,
+			line:     "[29Jun2024 12:00:00.123] [main/INFO] [net.minecraft.server/]: Starting",
+			expected: LogEntry{Date: "29Jun2024", Timestamp: "12:00:00.123", Thread: "main", Level: "INFO", Logger: "net.minecraft.server", Message: "Starting"},
+		},
+		{
+			name:     "forge latest",
+			format:   ForgeFormat,
+			line:     "[12:00:00] [main/INFO] [cp.mo.mo.Launcher/MODLAUNCHER]: ModLauncher running",
+			expected: LogEntry{Timestamp: "12:00:00", Thread: "main", Level: "INFO", Logger: "cp.mo.mo.Launcher/MODLAUNCHER", Message: "ModLauncher running"},
+		},
+		{
+			name:     "fabric",
+			format:   FabricFormat,
+			line:     "[12:34:56] [main/INFO] (FabricLoader/GameProvider) Loading Minecraft 1.20.4",
+			expected: LogEntry{Timestamp: "12:34:56", Thread: "main", Level: "INFO", Logger: "FabricLoader/GameProvider", Message: "Loading Minecraft 1.20.4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := tt.format.ParseLine(tt.line)
+			assert.True(t, ok)
+			tt.expected.RawLine = tt.line
+			assert.Equal(t, tt.expected, entry)
+
+			// No other built-in format should claim the line
+			for _, other := range Formats() {
+				if other == tt.format {
+					continue
+				}
+				_, matched := other.ParseLine(tt.line)
+				assert.False(t, matched, "%s format unexpectedly matched %q", other.Name(), tt.line)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	format, ok := DetectFormat([]string{
+		"[12:34:56 INFO]: Starting minecraft server version 1.20.4",
+		"[12:34:56 INFO]: Loading properties",
+		"[12:34:57] [Server thread/INFO]: stray vanilla line",
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "paper", format.Name())
+
+	format, ok = DetectFormat([]string{"not a log line"})
+	assert.False(t, ok)
+	assert.Equal(t, "vanilla", format.Name())
+}
+
+func TestParseContent_DetectsFormat(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	content := "[12:34:56 INFO]: Starting minecraft server\n[12:34:57 ERROR]: Failed\njava.lang.Exception\n"
+	entries, err := parser.ParseContent(content, nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "ERROR", entries[1].Level)
+	assert.Equal(t, []string{"java.lang.Exception"}, entries[1].Continuation)
+}
+
+func TestFormatByName(t *testing.T) {
+	format, ok := FormatByName("Forge")
+	assert.True(t, ok)
+	assert.Equal(t, ForgeFormat, format)
+
+	_, ok = FormatByName("unknown")
+	assert.False(t, ok)
+}
@@ -3,128 +3,203 @@ package logparser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strings"
+	"time"
 )
 
 // LogEntry represents a single parsed log entry.
 type LogEntry struct {
-	Timestamp string
-	Thread    string
-	Level     string
-	Message   string
+	Timestamp    string
+	Thread       string
+	Level        string
+	Message      string
+	Logger       string    // Logger name, for formats that include one (Forge, Fabric)
+	Date         string    // Date as written in the line, for formats that include one (Forge debug.log)
+	Time         time.Time // Full date and time of the entry; zero if the calendar date is unknown
+	RawLine      string    // The original header line as read from the log
+	Offset       int64     // Byte offset of the header line in the (decompressed) log
+	LineNumber   int       // 1-based line number of the header line in the log
+	Continuation []string  // Lines following the header that belong to this entry (e.g. stack traces)
+	Source       string    // Path of the log the entry was read from, when read through an index
 }
 
+// HasContinuation reports whether the entry has attached continuation lines.
+func (e LogEntry) HasContinuation() bool {
+	return len(e.Continuation) > 0
+}
+
+// FullMessage returns the message followed by any continuation lines, separated by newlines.
+func (e LogEntry) FullMessage() string {
+	if len(e.Continuation) == 0 {
+		return e.Message
+	}
+	return e.Message + "\n" + strings.Join(e.Continuation, "\n")
+}
+
+// Filter decides whether a parsed entry is kept. A nil Filter keeps every entry.
+type Filter func(entry LogEntry) bool
+
 // Parser is responsible for parsing log files.
 type Parser struct {
-	logRegex *regexp.Regexp
+	format    Format         // nil means the format is detected from the content
+	location  *time.Location // Time zone used to interpret timestamps
+	date      time.Time      // Calendar date used to reconstruct full entry times
+	dateAtEnd bool           // True if date is the date of the last entry rather than the first
 }
 
-// NewParser creates a new instance of the log parser.
+// NewParser creates a new instance of the log parser that detects the log
+// format from the first lines of the content it parses.
 func NewParser() (*Parser, error) {
-	// Regex to capture timestamp, thread/level, and message
-	regex, err := regexp.Compile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^/]+)/([^\]]+)\]: (.*)$`)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compile regex: %w", err)
+	return &Parser{location: time.Local}, nil
+}
+
+// NewParserWithFormat creates a parser that always uses the given format.
+func NewParserWithFormat(format Format) (*Parser, error) {
+	if format == nil {
+		return nil, fmt.Errorf("format cannot be nil")
 	}
-	return &Parser{logRegex: regex}, nil
+	return &Parser{format: format, location: time.Local}, nil
 }
 
-// ParseLine parses a single line and returns a LogEntry if it matches the log format
-func (p *Parser) ParseLine(line string) (LogEntry, error) {
-	matches := p.logRegex.FindStringSubmatch(line)
-	if len(matches) != 5 {
-		return LogEntry{}, fmt.Errorf("line does not match log format: %s", line)
+// SetLocation sets the time zone used to interpret timestamps. Defaults to the local time zone.
+func (p *Parser) SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+	p.location = loc
+	if !p.date.IsZero() {
+		p.date = midnight(p.date, loc)
 	}
+}
 
-	return LogEntry{
-		Timestamp: matches[1],
-		Thread:    matches[2],
-		Level:     matches[3],
-		Message:   matches[4],
-	}, nil
+// Location returns the time zone used to interpret timestamps.
+func (p *Parser) Location() *time.Location {
+	return p.location
 }
 
-// ParseContent parses log content from a string, applying filters
-func (p *Parser) ParseContent(content string, filters []string) ([]LogEntry, error) {
-	var entries []LogEntry
-	scanner := bufio.NewScanner(strings.NewReader(content))
+// SetStartDate sets the calendar date of the first entry, e.g. from a rotated
+// log filename. Later entries advance the date when they roll over midnight.
+func (p *Parser) SetStartDate(date time.Time) {
+	p.date = midnight(date, p.location)
+	p.dateAtEnd = false
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		entry, err := p.ParseLine(line)
-		if err != nil {
-			continue // Skip lines that don't match the log format
-		}
+// SetEndDate sets the calendar date of the last entry, e.g. from the modification
+// time of latest.log. Earlier entries are dated backwards across midnight rollovers.
+func (p *Parser) SetEndDate(date time.Time) {
+	p.date = midnight(date, p.location)
+	p.dateAtEnd = true
+}
 
-		// Apply filters using OR logic
-		if len(filters) > 0 {
-			matchesAtLeastOneFilter := false
-			for _, filter := range filters {
-				filterLower := strings.ToLower(filter)
-				if strings.Contains(strings.ToLower(entry.Message), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Thread), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Level), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Timestamp), filterLower) {
-					matchesAtLeastOneFilter = true
-					break // Found a match, no need to check other filters for this entry
-				}
-			}
-			if !matchesAtLeastOneFilter {
-				continue // Skip this entry if it doesn't match any of the filters
-			}
+// Format returns the format the parser was created with, or nil if it auto-detects.
+func (p *Parser) Format() Format {
+	return p.format
+}
+
+// ParseLine parses a single line and returns a LogEntry if it matches the log format.
+// An auto-detecting parser tries each registered format in turn.
+func (p *Parser) ParseLine(line string) (LogEntry, error) {
+	formats := registry
+	if p.format != nil {
+		formats = []Format{p.format}
+	}
+	for _, f := range formats {
+		if entry, ok := f.ParseLine(line); ok {
+			return entry, nil
 		}
-		entries = append(entries, entry)
 	}
+	return LogEntry{}, fmt.Errorf("line does not match log format: %s", line)
+}
 
-	if err := scanner.Err(); err != nil {
+// ParseContent parses log content from a string, keeping entries accepted by the filter
+func (p *Parser) ParseContent(content string, filter Filter) ([]LogEntry, error) {
+	entries, err := p.parseReader(strings.NewReader(content), filter)
+	if err != nil {
 		return nil, fmt.Errorf("error reading log content: %w", err)
 	}
-
 	return entries, nil
 }
 
-// ParseLog extracts information from a log file, applying filters.
-func (p *Parser) ParseLog(logFilePath string, filters []string) ([]LogEntry, error) {
+// ParseLog extracts information from a log file, keeping entries accepted by the filter.
+func (p *Parser) ParseLog(logFilePath string, filter Filter) ([]LogEntry, error) {
 	file, err := os.Open(logFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 	defer file.Close()
 
+	entries, err := p.parseReader(file, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log file: %w", err)
+	}
+	return entries, nil
+}
+
+// parseReader reads all entries from r, keeping those accepted by the filter.
+// The filter is applied once an entry is complete so that continuation lines
+// can also be matched.
+func (p *Parser) parseReader(r io.Reader, filter Filter) ([]LogEntry, error) {
 	var entries []LogEntry
-	scanner := bufio.NewScanner(file)
+	scanner := p.NewEntryScanner(r)
 	for scanner.Scan() {
-		line := scanner.Text()
-		entry, err := p.ParseLine(line)
-		if err != nil {
-			continue // Skip lines that don't match the log format
+		entry := scanner.Entry()
+		if filter == nil || filter(entry) {
+			entries = append(entries, entry)
 		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-		// Apply filters using OR logic
-		if len(filters) > 0 {
-			matchesAtLeastOneFilter := false
-			for _, filter := range filters {
-				filterLower := strings.ToLower(filter)
-				if strings.Contains(strings.ToLower(entry.Message), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Thread), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Level), filterLower) ||
-					strings.Contains(strings.ToLower(entry.Timestamp), filterLower) {
-					matchesAtLeastOneFilter = true
-					break // Found a match, no need to check other filters for this entry
-				}
-			}
-			if !matchesAtLeastOneFilter {
-				continue // Skip this entry if it doesn't match any of the filters
-			}
+	// The date was that of the last entry, so shift everything back by the
+	// number of midnight rollovers seen while dating forwards from it
+	if days := scanner.RolloverCorrection(); days > 0 {
+		for i := range entries {
+			entries[i].Time = ShiftDays(entries[i].Time, -days)
 		}
-		entries = append(entries, entry)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading log file: %w", err)
+	return entries, nil
+}
+
+// DetectReaderFormat sniffs the first lines of r and returns the best matching format.
+func DetectReaderFormat(r io.Reader) (Format, bool) {
+	scanner := &EntryScanner{reader: bufio.NewReader(r), line: 1}
+	ok := scanner.detectFormat()
+	return scanner.format, ok
+}
+
+// Field returns the values of the named field, allowing entries to be matched
+// by filter queries such as level:ERROR or thread:"Server thread".
+func (e LogEntry) Field(name string) ([]string, bool) {
+	switch name {
+	case "level":
+		return []string{e.Level}, true
+	case "thread":
+		return []string{e.Thread}, true
+	case "logger":
+		return []string{e.Logger}, true
+	case "msg", "message":
+		return append([]string{e.Message}, e.Continuation...), true
+	case "time", "timestamp":
+		return []string{e.Timestamp}, true
+	case "date":
+		if !e.Time.IsZero() {
+			return []string{e.Time.Format("2006-01-02")}, true
+		}
+		return []string{e.Date}, true
 	}
+	return nil, false
+}
 
-	return entries, nil
+// TextFields are the fields of a LogEntry whose values are all among those
+// returned by Text, so that a term on one of them only matches entries
+// containing its value somewhere in their text.
+var TextFields = []string{"level", "thread", "logger", "msg", "message", "time", "timestamp"}
+
+// Text returns the values searched by filter terms that don't name a field.
+func (e LogEntry) Text() []string {
+	return append([]string{e.Message, e.Thread, e.Level, e.Logger, e.Timestamp}, e.Continuation...)
 }
@@ -0,0 +1,80 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const stackTraceLog = `[12:00:00] [main/INFO]: Starting server
+[12:00:01] [Server thread/ERROR]: Encountered an unexpected exception
+java.lang.NullPointerException: Cannot invoke "Object.toString()"
+	at net.minecraft.server.MinecraftServer.tick(MinecraftServer.java:100)
+Caused by: java.lang.IllegalStateException: boom
+	... 5 more
+[12:00:02] [Server thread/INFO]: Done
+`
+
+func TestParseContent_ContinuationLines(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	entries, err := parser.ParseContent(stackTraceLog, nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	assert.False(t, entries[0].HasContinuation())
+	assert.Equal(t, "Encountered an unexpected exception", entries[1].Message)
+	assert.Equal(t, []string{
+		`java.lang.NullPointerException: Cannot invoke "Object.toString()"`,
+		"\tat net.minecraft.server.MinecraftServer.tick(MinecraftServer.java:100)",
+		"Caused by: java.lang.IllegalStateException: boom",
+		"\t... 5 more",
+	}, entries[1].Continuation)
+	assert.Equal(t, "[12:00:01] [Server thread/ERROR]: Encountered an unexpected exception", entries[1].RawLine)
+	assert.False(t, entries[2].HasContinuation())
+}
+
+func TestParseContent_LeadingUnmatchedLinesDropped(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	entries, err := parser.ParseContent("garbage before\n[12:00:00] [main/INFO]: hello\n", nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Empty(t, entries[0].Continuation)
+}
+
+func TestParseContent_FilterMatchesContinuation(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	entries, err := parser.ParseContent(stackTraceLog, func(entry LogEntry) bool {
+		for _, value := range entry.Text() {
+			if strings.Contains(strings.ToLower(value), "illegalstate") {
+				return true
+			}
+		}
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Server thread", entries[0].Thread)
+	assert.Len(t, entries[0].Continuation, 4)
+}
+
+func TestLogEntry_Field(t *testing.T) {
+	entry := LogEntry{Timestamp: "12:00:01", Thread: "Server thread", Level: "ERROR", Message: "boom", Continuation: []string{"\tat foo"}}
+
+	values, ok := entry.Field("msg")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"boom", "\tat foo"}, values)
+
+	values, ok = entry.Field("level")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"ERROR"}, values)
+
+	_, ok = entry.Field("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,186 @@
+package logparser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// EntryScanner reads log entries one at a time from a stream, attaching
+// continuation lines to the entry they follow. Only the entry being assembled
+// is held in memory, so arbitrarily large logs can be processed.
+type EntryScanner struct {
+	reader  *bufio.Reader
+	format  Format
+	clock   *dateClock
+	endDate bool // The parser's date is that of the last entry
+
+	sniffed []scannedLine // Lines buffered while detecting the format
+	pending *LogEntry     // Entry whose continuation lines are still being read
+	blanks  []string      // Blank lines after pending, kept only if more of its continuation lines follow
+	current LogEntry
+	offset  int64 // Byte offset of the next line to be read
+	line    int   // Line number of the next line to be read
+	err     error
+}
+
+// scannedLine is a line along with its position in the stream
+type scannedLine struct {
+	text   string
+	offset int64
+	number int
+}
+
+// NewEntryScanner returns a scanner reading entries from r using the parser's
+// format, date and location settings. An auto-detecting parser sniffs the
+// first lines of r to pick the format.
+func (p *Parser) NewEntryScanner(r io.Reader) *EntryScanner {
+	return &EntryScanner{
+		reader:  bufio.NewReader(r),
+		format:  p.format,
+		clock:   &dateClock{loc: p.location, day: p.date},
+		endDate: p.dateAtEnd,
+		line:    1,
+	}
+}
+
+// SetPosition sets the byte offset and line number of the first line the
+// scanner reads, for when r has been positioned part way through a log.
+func (s *EntryScanner) SetPosition(offset int64, lineNumber int) {
+	s.offset = offset
+	s.line = lineNumber
+}
+
+// Scan advances to the next entry, which is then available through Entry.
+// It returns false when the input ends or an error occurs.
+func (s *EntryScanner) Scan() bool {
+	if s.format == nil {
+		s.detectFormat()
+	}
+
+	for {
+		line, ok := s.nextLine()
+		if !ok {
+			if s.pending != nil {
+				s.current = *s.pending
+				s.pending = nil
+				return true
+			}
+			return false
+		}
+
+		entry, matched := s.format.ParseLine(line.text)
+		if !matched {
+			// Lines before the first entry have nothing to attach to
+			switch {
+			case s.pending == nil:
+			case strings.TrimSpace(line.text) == "":
+				// Blank lines within an entry are kept as they are, but not those between entries
+				s.blanks = append(s.blanks, line.text)
+			default:
+				s.pending.Continuation = append(s.pending.Continuation, s.blanks...)
+				s.pending.Continuation = append(s.pending.Continuation, line.text)
+				s.blanks = nil
+			}
+			continue
+		}
+		s.blanks = nil
+
+		entry.Offset = line.offset
+		entry.LineNumber = line.number
+		s.clock.stamp(&entry)
+
+		previous := s.pending
+		s.pending = &entry
+		if previous != nil {
+			s.current = *previous
+			return true
+		}
+	}
+}
+
+// Entry returns the entry read by the last call to Scan.
+func (s *EntryScanner) Entry() LogEntry {
+	return s.current
+}
+
+// Err returns the first non-EOF error encountered while reading.
+func (s *EntryScanner) Err() error {
+	return s.err
+}
+
+// Format returns the format used to parse entries. For an auto-detecting
+// parser it is nil until the first call to Scan.
+func (s *EntryScanner) Format() Format {
+	return s.format
+}
+
+// Offset returns the number of bytes consumed from the input so far.
+func (s *EntryScanner) Offset() int64 {
+	return s.offset
+}
+
+// RolloverCorrection returns the number of days that must be subtracted from
+// the times of the entries scanned so far when the parser's date was set with
+// SetEndDate, since midnight rollovers are only known once the end is reached.
+func (s *EntryScanner) RolloverCorrection() int {
+	if !s.endDate || s.clock.selfDated {
+		return 0
+	}
+	return s.clock.rollovers
+}
+
+// detectFormat buffers the first lines of the input and picks the best matching
+// format, returning false if no format matched any line
+func (s *EntryScanner) detectFormat() bool {
+	var texts []string
+	nonEmpty := 0
+	for nonEmpty < SniffLines {
+		line, ok := s.readLine()
+		if !ok {
+			break
+		}
+		s.sniffed = append(s.sniffed, line)
+		texts = append(texts, line.text)
+		if strings.TrimSpace(line.text) != "" {
+			nonEmpty++
+		}
+	}
+	var ok bool
+	s.format, ok = DetectFormat(texts)
+	return ok
+}
+
+// nextLine returns the next line, draining lines buffered during format detection first
+func (s *EntryScanner) nextLine() (scannedLine, bool) {
+	if len(s.sniffed) > 0 {
+		line := s.sniffed[0]
+		s.sniffed = s.sniffed[1:]
+		return line, true
+	}
+	return s.readLine()
+}
+
+// readLine reads a line from the input without its line ending
+func (s *EntryScanner) readLine() (scannedLine, bool) {
+	if s.err != nil {
+		return scannedLine{}, false
+	}
+	text, err := s.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		s.err = err
+		return scannedLine{}, false
+	}
+	if text == "" && err == io.EOF {
+		return scannedLine{}, false
+	}
+
+	line := scannedLine{
+		text:   strings.TrimRight(text, "\r\n"),
+		offset: s.offset,
+		number: s.line,
+	}
+	s.offset += int64(len(text))
+	s.line++
+	return line, true
+}
@@ -0,0 +1,69 @@
+package logparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryScanner_OffsetsAndLineNumbers(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	scanner := parser.NewEntryScanner(strings.NewReader(stackTraceLog))
+	var entries []LogEntry
+	for scanner.Scan() {
+		entries = append(entries, scanner.Entry())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Len(t, entries, 3)
+
+	assert.Equal(t, int64(0), entries[0].Offset)
+	assert.Equal(t, 1, entries[0].LineNumber)
+	assert.Equal(t, int64(strings.Index(stackTraceLog, "[12:00:01]")), entries[1].Offset)
+	assert.Equal(t, 2, entries[1].LineNumber)
+	assert.Equal(t, int64(strings.Index(stackTraceLog, "[12:00:02]")), entries[2].Offset)
+	assert.Equal(t, 7, entries[2].LineNumber)
+	assert.Equal(t, int64(len(stackTraceLog)), scanner.Offset())
+}
+
+func TestEntryScanner_KeepsBlankLinesWithinEntries(t *testing.T) {
+	log := "[12:00:00] [Server thread/INFO]: Pasted block:\n" +
+		"first paragraph\n" +
+		"\n" +
+		"  \n" +
+		"second paragraph\n" +
+		"\n" +
+		"[12:00:01] [Server thread/INFO]: Next\n" +
+		"\n"
+
+	parser, err := NewParserWithFormat(VanillaFormat)
+	assert.NoError(t, err)
+	scanner := parser.NewEntryScanner(strings.NewReader(log))
+	var entries []LogEntry
+	for scanner.Scan() {
+		entries = append(entries, scanner.Entry())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Len(t, entries, 2)
+
+	// Blank lines inside the block are kept verbatim, trailing ones are not
+	assert.Equal(t, []string{"first paragraph", "", "  ", "second paragraph"}, entries[0].Continuation)
+	assert.Empty(t, entries[1].Continuation)
+}
+
+func TestEntryScanner_SetPosition(t *testing.T) {
+	parser, err := NewParserWithFormat(VanillaFormat)
+	assert.NoError(t, err)
+
+	offset := strings.Index(stackTraceLog, "[12:00:01]")
+	scanner := parser.NewEntryScanner(strings.NewReader(stackTraceLog[offset:]))
+	scanner.SetPosition(int64(offset), 2)
+
+	assert.True(t, scanner.Scan())
+	entry := scanner.Entry()
+	assert.Equal(t, int64(offset), entry.Offset)
+	assert.Equal(t, 2, entry.LineNumber)
+	assert.Len(t, entry.Continuation, 4)
+}
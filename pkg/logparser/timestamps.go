@@ -0,0 +1,147 @@
+package logparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rolloverThreshold is how far the time of day must jump backwards between
+// consecutive entries before it is treated as crossing midnight. Smaller jumps
+// happen when threads log slightly out of order.
+const rolloverThreshold = 12 * time.Hour
+
+// rotatedLogNameRegex matches rotated log filenames such as 2024-05-01-3.log.gz
+var rotatedLogNameRegex = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})-\d+\.log(?:\.(?:gz|bz2|xz|zst))*$`)
+
+// DateFromFilename extracts the calendar date from a rotated log filename
+// (e.g. logs/2024-05-01-3.log.gz) in the given location.
+func DateFromFilename(path string, loc *time.Location) (time.Time, bool) {
+	match := rotatedLogNameRegex.FindStringSubmatch(strings.ReplaceAll(path, "\\", "/"))
+	if match == nil {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation("2006-01-02", match[1], loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// parseTimeOfDay parses HH:MM:SS with optional fractional seconds into an offset from midnight.
+func parseTimeOfDay(timestamp string) (time.Duration, error) {
+	clock, fraction, _ := strings.Cut(timestamp, ".")
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", timestamp)
+	}
+	var values [3]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %s", timestamp)
+		}
+		values[i] = v
+	}
+	offset := time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute + time.Duration(values[2])*time.Second
+	if fraction != "" {
+		nanos, err := strconv.Atoi((fraction + "000000000")[:9])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %s", timestamp)
+		}
+		offset += time.Duration(nanos)
+	}
+	return offset, nil
+}
+
+// midnight returns the start of the calendar day of t in loc.
+func midnight(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// dateClock assigns calendar dates to consecutive entries, advancing the day
+// whenever the time of day rolls over midnight.
+type dateClock struct {
+	loc       *time.Location
+	day       time.Time // midnight of the current day
+	lastTOD   time.Duration
+	started   bool
+	rollovers int
+	selfDated bool // at least one line carried its own date
+}
+
+// stamp computes the full time of an entry and stores it in entry.Time.
+func (c *dateClock) stamp(entry *LogEntry) {
+	tod, err := parseTimeOfDay(entry.Timestamp)
+	if err != nil {
+		return
+	}
+
+	// Lines that carry their own date (e.g. Forge debug.log) re-anchor the clock
+	if entry.Date != "" {
+		if date, err := time.ParseInLocation("02Jan2006", entry.Date, c.loc); err == nil {
+			c.day = date
+			c.lastTOD = tod
+			c.started = true
+			c.selfDated = true
+			entry.Time = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, int(tod), c.loc)
+			return
+		}
+	}
+
+	if c.day.IsZero() {
+		return
+	}
+	if c.started && tod < c.lastTOD-rolloverThreshold {
+		c.day = c.day.AddDate(0, 0, 1)
+		c.rollovers++
+	}
+	c.lastTOD = tod
+	c.started = true
+	// time.Date normalises the offset so DST transitions are handled by the location
+	entry.Time = time.Date(c.day.Year(), c.day.Month(), c.day.Day(), 0, 0, 0, int(tod), c.loc)
+}
+
+// ShiftDays moves t by the given number of calendar days, keeping the wall
+// clock time. Zero times are left unchanged.
+func ShiftDays(t time.Time, days int) time.Time {
+	if t.IsZero() || days == 0 {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()+days, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// SortByTime sorts entries chronologically, keeping the original order for
+// entries with equal times. Entries with an unknown time sort first.
+func SortByTime(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].Time, entries[j].Time
+		if ti.IsZero() || tj.IsZero() {
+			return ti.IsZero() && !tj.IsZero()
+		}
+		return ti.Before(tj)
+	})
+}
+
+// InTimeRange reports whether the entry's time falls within [from, to].
+// A zero from or to leaves that end of the range open. Entries without a
+// known time only match a fully open range.
+func InTimeRange(entry LogEntry, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	if entry.Time.IsZero() {
+		return false
+	}
+	if !from.IsZero() && entry.Time.Before(from) {
+		return false
+	}
+	if !to.IsZero() && entry.Time.After(to) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package logparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const rolloverLog = `[23:59:58] [Server thread/INFO]: before midnight
+[23:59:57] [Async thread/INFO]: slightly out of order
+[00:00:01] [Server thread/INFO]: after midnight
+[12:00:00] [Server thread/INFO]: next day noon
+`
+
+func TestDateFromFilename(t *testing.T) {
+	date, ok := DateFromFilename("logs/2024-05-01-3.log.gz", time.UTC)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), date)
+
+	date, ok = DateFromFilename("backup.zip!/logs/2024-05-02-1.log.xz", time.UTC)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), date)
+
+	_, ok = DateFromFilename("logs/latest.log", time.UTC)
+	assert.False(t, ok)
+}
+
+func TestParseContent_StartDateRollover(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+	parser.SetLocation(time.UTC)
+	parser.SetStartDate(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+
+	entries, err := parser.ParseContent(rolloverLog, nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, time.Date(2024, 5, 1, 23, 59, 58, 0, time.UTC), entries[0].Time)
+	assert.Equal(t, time.Date(2024, 5, 1, 23, 59, 57, 0, time.UTC), entries[1].Time)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 1, 0, time.UTC), entries[2].Time)
+	assert.Equal(t, time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC), entries[3].Time)
+}
+
+func TestParseContent_EndDateRollover(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+	parser.SetLocation(time.UTC)
+	parser.SetEndDate(time.Date(2024, 5, 2, 12, 30, 0, 0, time.UTC))
+
+	entries, err := parser.ParseContent(rolloverLog, nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, time.Date(2024, 5, 1, 23, 59, 58, 0, time.UTC), entries[0].Time)
+	assert.Equal(t, time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC), entries[3].Time)
+}
+
+func TestParseContent_NoDateLeavesTimeZero(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+
+	entries, err := parser.ParseContent(rolloverLog, nil)
+	assert.NoError(t, err)
+	assert.True(t, entries[0].Time.IsZero())
+}
+
+func TestParseContent_ForgeDatedLines(t *testing.T) {
+	parser, err := NewParser()
+	assert.NoError(t, err)
+	parser.SetLocation(time.UTC)
+
+	entries, err := parser.ParseContent("[29Jun2024 12:00:00.123] [main/INFO] [net.minecraft.server/]: Starting\n", nil)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, time.Date(2024, 6, 29, 12, 0, 0, 123000000, time.UTC), entries[0].Time)
+}
+
+func TestSortByTimeAndInTimeRange(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Message: "b", Time: base.Add(2 * time.Hour)},
+		{Message: "unknown"},
+		{Message: "a", Time: base.Add(time.Hour)},
+	}
+	SortByTime(entries)
+	assert.Equal(t, "unknown", entries[0].Message)
+	assert.Equal(t, "a", entries[1].Message)
+	assert.Equal(t, "b", entries[2].Message)
+
+	assert.True(t, InTimeRange(entries[1], base, base.Add(90*time.Minute)))
+	assert.False(t, InTimeRange(entries[2], base, base.Add(90*time.Minute)))
+	assert.False(t, InTimeRange(entries[0], base, time.Time{}))
+	assert.True(t, InTimeRange(entries[0], time.Time{}, time.Time{}))
+}
@@ -0,0 +1,313 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokLParen tokenKind = iota
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokWord // A bare word, merged with neighbouring bare words into a phrase
+	tokTerm // A quoted phrase, regex or field term
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	term *TermNode
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokAnd, tokOr, tokNot:
+		return t.text
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// fieldPrefixRegex matches the start of a field term such as level: or ago:<=
+var fieldPrefixRegex = regexp.MustCompile(`^([A-Za-z_][\w.]*):(<=|>=|<|>|=)?`)
+
+var keywords = map[string]tokenKind{"AND": tokAnd, "OR": tokOr, "NOT": tokNot}
+
+// tokenize splits a query string into tokens.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")"})
+			i++
+		case c == '"':
+			value, n, err := readQuoted(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokTerm, text: s[i : i+n], term: &TermNode{Value: value, Raw: value}})
+			i += n
+		default:
+			start := i
+			var term *TermNode
+			var n int
+			var err error
+			if match := fieldPrefixRegex.FindStringSubmatch(s[i:]); match != nil && i+len(match[0]) < len(s) && !isBoundary(s[i+len(match[0])]) {
+				term, n, err = readValue(s[i+len(match[0]):], match[2] == "")
+				if err != nil {
+					return nil, err
+				}
+				term.Field = strings.ToLower(match[1])
+				if term.Op != OpRegex {
+					term.Op = parseOp(match[2])
+				}
+				n += len(match[0])
+			} else if _, _, isRegex := readRegex(s[i:]); c == '/' && isRegex {
+				term, n, err = readValue(s[i:], true)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if term != nil {
+				term.Raw = s[start : start+n]
+				tokens = append(tokens, token{kind: tokTerm, text: term.Raw, term: term})
+				i += n
+				continue
+			}
+
+			word := readWord(s[i:])
+			if kind, isKeyword := keywords[word]; isKeyword {
+				tokens = append(tokens, token{kind: kind, text: word})
+			} else {
+				tokens = append(tokens, token{kind: tokWord, text: word})
+			}
+			i += len(word)
+		}
+	}
+	return tokens, nil
+}
+
+// readValue reads a quoted, regex or bare value from the start of s. Regex
+// values are only recognised when allowRegex is set and the closing slash is
+// followed by a boundary, so paths such as //example.com stay plain words.
+func readValue(s string, allowRegex bool) (*TermNode, int, error) {
+	switch {
+	case s[0] == '"':
+		value, n, err := readQuoted(s)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &TermNode{Value: value}, n, nil
+	case s[0] == '/' && allowRegex:
+		if pattern, n, ok := readRegex(s); ok {
+			regex, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid regex /%s/: %w", pattern, err)
+			}
+			return &TermNode{Op: OpRegex, Value: pattern, Regex: regex}, n, nil
+		}
+	}
+	word := readWord(s)
+	return &TermNode{Value: word}, len(word), nil
+}
+
+// readQuoted reads a double-quoted string with backslash escapes from the start of s.
+func readQuoted(s string) (string, int, error) {
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+			}
+		case '"':
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quote in %s", s)
+}
+
+// readRegex reads a /regex/ from the start of s. A backslash-escaped slash
+// does not end the regex.
+func readRegex(s string) (string, int, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			if i+1 < len(s) && !isBoundary(s[i+1]) {
+				return "", 0, false
+			}
+			return strings.ReplaceAll(s[1:i], `\/`, "/"), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// readWord reads up to the next whitespace or closing parenthesis.
+func readWord(s string) string {
+	end := strings.IndexAny(s, " \t)")
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+func isBoundary(c byte) bool {
+	return c == ' ' || c == '\t' || c == ')'
+}
+
+func parseOp(op string) Op {
+	switch op {
+	case "=":
+		return OpEqual
+	case "<":
+		return OpLess
+	case "<=":
+		return OpLessEq
+	case ">":
+		return OpGreater
+	case ">=":
+		return OpGreaterEq
+	}
+	return OpContains
+}
+
+// parser is a recursive descent parser over the query tokens. NOT binds
+// tighter than AND, which binds tighter than OR.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokOr {
+			break
+		}
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &OrNode{Children: children}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	first, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	children := []Node{first}
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			break
+		}
+		if tok.kind == tokAnd {
+			p.pos++
+		} else if tok.kind != tokNot && tok.kind != tokLParen && tok.kind != tokTerm && tok.kind != tokWord {
+			break
+		}
+		// Adjacent terms without an operator are implicitly ANDed
+		next, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &AndNode{Children: children}, nil
+}
+
+func (p *parser) parseNot() (Node, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokNot {
+		p.pos++
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &NotNode{Child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+
+	switch tok.kind {
+	case tokLParen:
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case tokTerm:
+		p.pos++
+		return tok.term, nil
+	case tokWord:
+		// Consecutive bare words form a single phrase, as plain filters always have
+		words := []string{tok.text}
+		p.pos++
+		for {
+			next, ok := p.peek()
+			if !ok || next.kind != tokWord {
+				break
+			}
+			words = append(words, next.text)
+			p.pos++
+		}
+		phrase := strings.Join(words, " ")
+		return &TermNode{Value: phrase, Raw: phrase}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", tok)
+}
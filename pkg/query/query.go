@@ -0,0 +1,339 @@
+// Package query implements the filter language used to search log entries.
+//
+// A query is made of terms combined with AND, OR, NOT and parentheses:
+//
+//	level:ERROR AND thread:"Server thread" AND msg:chunk
+//	joined the game OR left the game
+//	NOT (level:INFO OR level:DEBUG)
+//	msg:/lost connection: .*timed out/
+//
+// Bare words match any searchable field as a case-insensitive substring, and
+// consecutive bare words form a single phrase, so a plain filter such as
+// "joined the game" behaves like a simple substring search. Adjacent terms
+// that are not bare words are implicitly ANDed. Keywords must be uppercase.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Record is implemented by values that queries are evaluated against.
+type Record interface {
+	// Field returns the values of the named field, and false if the record has no such field.
+	Field(name string) ([]string, bool)
+	// Text returns the values searched by bare terms.
+	Text() []string
+}
+
+// Comparer can be implemented by records to provide typed comparisons (e.g.
+// durations or times) for the <, <=, > and >= operators. ok is false if the
+// record cannot compare the field, in which case the default comparison is used.
+type Comparer interface {
+	CompareField(name string, op Op, value string) (matched bool, ok bool)
+}
+
+// Op is the comparison operator of a term.
+type Op int
+
+const (
+	OpContains  Op = iota // field:value, case-insensitive substring
+	OpEqual               // field:=value, case-insensitive equality
+	OpLess                // field:<value
+	OpLessEq              // field:<=value
+	OpGreater             // field:>value
+	OpGreaterEq           // field:>=value
+	OpRegex               // field:/regex/ or /regex/
+)
+
+// String returns the operator as written in a query.
+func (o Op) String() string {
+	switch o {
+	case OpEqual:
+		return "="
+	case OpLess:
+		return "<"
+	case OpLessEq:
+		return "<="
+	case OpGreater:
+		return ">"
+	case OpGreaterEq:
+		return ">="
+	}
+	return ""
+}
+
+// Node is a node of a parsed query's syntax tree.
+type Node interface {
+	Eval(r Record) bool
+	String() string
+}
+
+// AndNode matches when all of its children match.
+type AndNode struct{ Children []Node }
+
+// OrNode matches when any of its children match.
+type OrNode struct{ Children []Node }
+
+// NotNode matches when its child does not.
+type NotNode struct{ Child Node }
+
+// TermNode matches a single value, optionally restricted to a field.
+type TermNode struct {
+	Field string // Empty for bare terms, which search Record.Text
+	Op    Op
+	Value string
+	Regex *regexp.Regexp // Set when Op is OpRegex
+	Raw   string         // The term as written, used as a bare phrase if the record lacks the field
+}
+
+func (n *AndNode) Eval(r Record) bool {
+	for _, child := range n.Children {
+		if !child.Eval(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *OrNode) Eval(r Record) bool {
+	for _, child := range n.Children {
+		if child.Eval(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *NotNode) Eval(r Record) bool {
+	return !n.Child.Eval(r)
+}
+
+func (n *TermNode) Eval(r Record) bool {
+	if n.Field == "" {
+		return n.matchAny(r.Text())
+	}
+
+	values, ok := r.Field(n.Field)
+	if !ok {
+		// Not a field of this record (e.g. "http://..."), so search for the term as written
+		return containsFold(r.Text(), n.Raw)
+	}
+
+	if comparer, isComparer := r.(Comparer); isComparer && n.isComparison() {
+		if matched, handled := comparer.CompareField(n.Field, n.Op, n.Value); handled {
+			return matched
+		}
+	}
+	return n.matchAny(values)
+}
+
+func (n *AndNode) String() string { return joinNodes(n.Children, " AND ") }
+func (n *OrNode) String() string  { return joinNodes(n.Children, " OR ") }
+func (n *NotNode) String() string { return "NOT " + n.Child.String() }
+
+func (n *TermNode) String() string {
+	var value string
+	switch {
+	case n.Op == OpRegex:
+		value = "/" + n.Value + "/"
+	case strings.ContainsAny(n.Value, " \t()\"") || n.Value == "":
+		value = strconv.Quote(n.Value)
+	default:
+		value = n.Value
+	}
+	if n.Field == "" {
+		return value
+	}
+	return n.Field + ":" + n.Op.String() + value
+}
+
+func joinNodes(nodes []Node, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = node.String()
+		if _, isTerm := node.(*TermNode); !isTerm {
+			if _, isNot := node.(*NotNode); !isNot {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func (n *TermNode) isComparison() bool {
+	return n.Op == OpLess || n.Op == OpLessEq || n.Op == OpGreater || n.Op == OpGreaterEq
+}
+
+// matchAny reports whether any of the values matches the term.
+func (n *TermNode) matchAny(values []string) bool {
+	for _, value := range values {
+		if n.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *TermNode) match(value string) bool {
+	switch n.Op {
+	case OpRegex:
+		return n.Regex.MatchString(value)
+	case OpEqual:
+		return strings.EqualFold(value, n.Value)
+	case OpLess, OpLessEq, OpGreater, OpGreaterEq:
+		cmp := compareValues(value, n.Value)
+		switch n.Op {
+		case OpLess:
+			return cmp < 0
+		case OpLessEq:
+			return cmp <= 0
+		case OpGreater:
+			return cmp > 0
+		default:
+			return cmp >= 0
+		}
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(n.Value))
+}
+
+// compareValues compares numerically when both values are numbers, and
+// case-insensitively as strings otherwise.
+func compareValues(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func containsFold(values []string, substr string) bool {
+	substr = strings.ToLower(substr)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query is a parsed filter query.
+type Query struct {
+	Source string // The query as entered
+	Root   Node
+}
+
+// Parse parses a query string into a Query.
+func Parse(source string) (*Query, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return &Query{Source: source, Root: root}, nil
+}
+
+// MustParse is like Parse but panics if the query cannot be parsed.
+func MustParse(source string) *Query {
+	q, err := Parse(source)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Match reports whether the record matches the query.
+func (q *Query) Match(r Record) bool {
+	return q.Root.Eval(r)
+}
+
+// String returns the query as entered.
+func (q *Query) String() string {
+	return q.Source
+}
+
+// RequiredTerms returns values that every record matching the query contains,
+// as a case-insensitive substring of one of its Text values. They come from
+// bare terms and from terms on textFields, the fields whose values are all
+// among the record's Text values, that must match whatever the rest of the
+// query says. An index of the words in each record's text can use them to
+// rule records out without evaluating the query. Nil is returned when no
+// term is required.
+func (q *Query) RequiredTerms(textFields ...string) []string {
+	return requiredTerms(q.Root, textFields)
+}
+
+func requiredTerms(node Node, textFields []string) []string {
+	switch n := node.(type) {
+	case *AndNode:
+		var terms []string
+		for _, child := range n.Children {
+			terms = append(terms, requiredTerms(child, textFields)...)
+		}
+		return terms
+	case *TermNode:
+		if n.Op != OpContains && n.Op != OpEqual {
+			return nil
+		}
+		if n.Field == "" || slices.Contains(textFields, n.Field) {
+			return []string{n.Value}
+		}
+	}
+	// Either side of an OR may match, and NOT matches records without the term
+	return nil
+}
+
+// AnyRequiredTerms returns the RequiredTerms of each query, one of which
+// every record matched by MatchAny contains all of. Nil is returned for an
+// empty list of queries, which matches everything.
+func AnyRequiredTerms(queries []*Query, textFields ...string) [][]string {
+	var alternatives [][]string
+	for _, q := range queries {
+		alternatives = append(alternatives, q.RequiredTerms(textFields...))
+	}
+	return alternatives
+}
+
+// MatchAny reports whether the record matches at least one of the queries.
+// An empty list of queries matches everything.
+func MatchAny(queries []*Query, r Record) bool {
+	if len(queries) == 0 {
+		return true
+	}
+	for _, q := range queries {
+		if q.Match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sources returns the source text of each query.
+func Sources(queries []*Query) []string {
+	sources := make([]string, len(queries))
+	for i, q := range queries {
+		sources[i] = q.Source
+	}
+	return sources
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRecord is a simple Record backed by a map of field values
+type testRecord map[string]string
+
+func (r testRecord) Field(name string) ([]string, bool) {
+	value, ok := r[name]
+	if !ok {
+		return nil, false
+	}
+	return []string{value}, true
+}
+
+func (r testRecord) Text() []string {
+	var values []string
+	for _, value := range r {
+		values = append(values, value)
+	}
+	return values
+}
+
+var chunkError = testRecord{"level": "ERROR", "thread": "Server thread", "msg": "Failed to save Chunk [12, -4]"}
+var joinInfo = testRecord{"level": "INFO", "thread": "Server thread", "msg": "Steve joined the game"}
+var workerWarn = testRecord{"level": "WARN", "thread": "Worker-Main-1", "msg": "Chunk took too long"}
+
+func TestQuery_Match(t *testing.T) {
+	tests := []struct {
+		query   string
+		matches []testRecord
+		misses  []testRecord
+	}{
+		{`joined the game`, []testRecord{joinInfo}, []testRecord{chunkError, workerWarn}},
+		{`JOINED THE`, []testRecord{joinInfo}, []testRecord{chunkError}},
+		{`level:ERROR AND thread:"Server thread" AND msg:chunk`, []testRecord{chunkError}, []testRecord{joinInfo, workerWarn}},
+		{`level:error msg:chunk`, []testRecord{chunkError}, []testRecord{workerWarn}},
+		{`msg:chunk AND NOT level:ERROR`, []testRecord{workerWarn}, []testRecord{chunkError, joinInfo}},
+		{`(level:WARN OR level:ERROR) AND thread:server`, []testRecord{chunkError}, []testRecord{workerWarn, joinInfo}},
+		{`level:WARN OR level:ERROR AND thread:server`, []testRecord{chunkError, workerWarn}, []testRecord{joinInfo}},
+		{`msg:/chunk \[\d+, -?\d+\]/`, []testRecord{chunkError}, []testRecord{workerWarn}},
+		{`/^steve/`, []testRecord{joinInfo}, []testRecord{chunkError}},
+		{`level:=warn`, []testRecord{workerWarn}, []testRecord{chunkError}},
+		{`"the game"`, []testRecord{joinInfo}, []testRecord{chunkError}},
+		{`NOT NOT level:INFO`, []testRecord{joinInfo}, []testRecord{chunkError}},
+		{`Worker-Main-1`, []testRecord{workerWarn}, []testRecord{joinInfo}},
+		{`unknownfield:Steve`, nil, []testRecord{joinInfo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			assert.NoError(t, err)
+			for _, r := range tt.matches {
+				assert.True(t, q.Match(r), "expected %q to match %v", tt.query, r)
+			}
+			for _, r := range tt.misses {
+				assert.False(t, q.Match(r), "expected %q not to match %v", tt.query, r)
+			}
+		})
+	}
+}
+
+func TestQuery_UnknownFieldFallsBackToPhrase(t *testing.T) {
+	q, err := Parse("https://example.com")
+	assert.NoError(t, err)
+	assert.True(t, q.Match(testRecord{"msg": "see https://example.com for details"}))
+}
+
+func TestQuery_Comparisons(t *testing.T) {
+	q := MustParse("count:>=10 AND count:<20")
+	assert.True(t, q.Match(testRecord{"count": "10"}))
+	assert.True(t, q.Match(testRecord{"count": "19.5"}))
+	assert.False(t, q.Match(testRecord{"count": "9"}))
+	assert.False(t, q.Match(testRecord{"count": "20"}))
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, source := range []string{"", "   ", "(level:ERROR", "level:ERROR)", `"unterminated`, "msg:/[/", "AND", "NOT"} {
+		_, err := Parse(source)
+		assert.Error(t, err, "expected an error for %q", source)
+	}
+}
+
+func TestNode_String(t *testing.T) {
+	q := MustParse(`level:ERROR AND (thread:"Server thread" OR NOT msg:/chunk/) joined the game`)
+	assert.Equal(t, `level:ERROR AND (thread:"Server thread" OR NOT msg:/chunk/) AND "joined the game"`, q.Root.String())
+}
+
+func TestQuery_RequiredTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		terms []string
+	}{
+		{`joined the game`, []string{"joined the game"}},
+		{`level:ERROR AND msg:chunk`, []string{"ERROR", "chunk"}},
+		{`level:=warn joined`, []string{"warn", "joined"}},
+		{`thread:server`, nil},
+		{`level:WARN OR level:ERROR`, nil},
+		{`chunk AND NOT level:ERROR`, []string{"chunk"}},
+		{`(joined OR left) AND msg:game`, []string{"game"}},
+		{`msg:/chunk \d+/ AND steve`, []string{"steve"}},
+		{`count:>10`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.terms, MustParse(tt.query).RequiredTerms("level", "msg"))
+		})
+	}
+}
+
+func TestAnyRequiredTerms(t *testing.T) {
+	assert.Nil(t, AnyRequiredTerms(nil, "msg"))
+	queries := []*Query{MustParse("level:ERROR chunk"), MustParse("thread:main")}
+	assert.Equal(t, [][]string{{"chunk"}, nil}, AnyRequiredTerms(queries, "msg"))
+}
+
+func TestMatchAny(t *testing.T) {
+	assert.True(t, MatchAny(nil, joinInfo))
+	queries := []*Query{MustParse("level:ERROR"), MustParse("joined")}
+	assert.True(t, MatchAny(queries, joinInfo))
+	assert.False(t, MatchAny(queries, workerWarn))
+	assert.Equal(t, []string{"level:ERROR", "joined"}, Sources(queries))
+}